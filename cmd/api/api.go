@@ -5,17 +5,31 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/constants"
 	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/admin"
 	"github.com/nicolaics/jim-carrier-server/service/auth"
 	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
-	"github.com/nicolaics/jim-carrier-server/service/bank"
+	"github.com/nicolaics/jim-carrier-server/service/blob"
+	"github.com/nicolaics/jim-carrier-server/service/blobgc"
 	"github.com/nicolaics/jim-carrier-server/service/currency"
+	"github.com/nicolaics/jim-carrier-server/service/encryption"
 	"github.com/nicolaics/jim-carrier-server/service/fcm"
+	"github.com/nicolaics/jim-carrier-server/service/image"
 	"github.com/nicolaics/jim-carrier-server/service/listing"
+	"github.com/nicolaics/jim-carrier-server/service/notification"
 	"github.com/nicolaics/jim-carrier-server/service/order"
+	"github.com/nicolaics/jim-carrier-server/service/outbox"
+	"github.com/nicolaics/jim-carrier-server/service/payout"
+	"github.com/nicolaics/jim-carrier-server/service/request"
 	"github.com/nicolaics/jim-carrier-server/service/review"
+	"github.com/nicolaics/jim-carrier-server/service/savedsearch"
+	"github.com/nicolaics/jim-carrier-server/service/scheduler"
+	"github.com/nicolaics/jim-carrier-server/service/session"
 	"github.com/nicolaics/jim-carrier-server/service/user"
 )
 
@@ -39,31 +53,106 @@ func (s *APIServer) Run() error {
 	subrouter := s.router.PathPrefix("/api/v1").Subrouter()
 	subrouterUnprotected := s.router.PathPrefix("/api/v1").Subrouter()
 
+	// runs after the subrouter's JWTMiddleware
+	adminRouter := subrouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(jwt.RequireRoles(constants.ROLE_ADMIN, constants.ROLE_SUPPORT))
+
 	userStore := user.NewStore(s.db)
 	listingStore := listing.NewStore(s.db)
 	orderStore := order.NewStore(s.db)
 	reviewStore := review.NewStore(s.db)
 	currencyStore := currency.NewStore(s.db)
 	fcmStore := fcm.NewStore(s.db)
-	bankDetailStore := bank.NewStore(s.db)
+	sessionStore := session.NewStore(s.db)
+	auditLogStore := admin.NewStore(s.db)
+	requestStore := request.NewStore(s.db)
+	savedSearchStore := savedsearch.NewStore(s.db)
+
+	outboxStore := outbox.NewStore(s.db)
+
+	keyring, err := encryption.NewKeyringFromConfig()
+	if err != nil {
+		return err
+	}
+
+	payoutMethodStore := payout.NewStore(s.db, keyring)
+
+	blobStore, err := blob.NewBlobStoreFromConfig()
+	if err != nil {
+		return err
+	}
+
+	dispatcher, err := notification.NewDispatcherFromConfig(fcmStore, blobStore)
+	if err != nil {
+		return err
+	}
+
+	// handlers only enqueue, the worker does the delivery
+	notifier := outbox.NewOutbox(outboxStore, fcmStore, sessionStore)
+
+	outboxWorker := outbox.NewWorker(outboxStore, dispatcher,
+		time.Duration(config.Envs.OutboxPollIntervalInSeconds)*time.Second,
+		time.Duration(config.Envs.OutboxBaseBackoffInSeconds)*time.Second,
+		int(config.Envs.OutboxMaxAttempts))
+	outboxWorker.Start()
 
-	userHandler := user.NewHandler(userStore)
+	userHandler := user.NewHandler(userStore, sessionStore, notifier, blobStore)
 	userHandler.RegisterRoutes(subrouter)
 	userHandler.RegisterUnprotectedRoutes(subrouterUnprotected)
 
+	orderUnitOfWork := order.NewUnitOfWork(s.db, orderStore, listingStore)
+
+	savedSearchAlerter := savedsearch.NewAlerter(savedSearchStore, listingStore, notifier,
+		time.Duration(config.Envs.SavedSearchAlertIntervalInSeconds)*time.Second)
+
 	listingHandler := listing.NewHandler(listingStore, userStore, currencyStore, reviewStore,
-										bankDetailStore, orderStore, fcmStore)
+										payoutMethodStore, orderStore, notifier, orderUnitOfWork, savedSearchAlerter)
 	listingHandler.RegisterRoutes(subrouter)
 
-	orderHandler := order.NewHandler(orderStore, userStore, listingStore, currencyStore, fcmStore, 
-									bankDetailStore)
+	orderHandler := order.NewHandler(orderStore, userStore, listingStore, currencyStore, notifier, 
+									payoutMethodStore, orderUnitOfWork, blobStore)
 	orderHandler.RegisterRoutes(subrouter)
 
+	requestUnitOfWork := request.NewUnitOfWork(s.db, requestStore, listingStore, orderUnitOfWork)
+
+	requestHandler := request.NewHandler(requestStore, userStore, orderStore, currencyStore,
+										notifier, requestUnitOfWork, blobStore)
+	requestHandler.RegisterRoutes(subrouter)
+
+	savedSearchHandler := savedsearch.NewHandler(savedSearchStore)
+	savedSearchHandler.RegisterRoutes(subrouter)
+	savedSearchHandler.RegisterUnprotectedRoutes(subrouterUnprotected)
+
 	reviewHandler := review.NewHandler(reviewStore, orderStore, listingStore, userStore)
 	reviewHandler.RegisterRoutes(subrouter)
 
-	bankDetailHandler := bank.NewHandler(bankDetailStore, userStore)
-	bankDetailHandler.RegisterRoutes(subrouter)
+	payoutMethodHandler := payout.NewHandler(payoutMethodStore, currencyStore)
+	payoutMethodHandler.RegisterRoutes(subrouter)
+
+	imageHandler := image.NewHandler(orderStore, requestStore, blobStore)
+	imageHandler.RegisterRoutes(subrouter)
+
+	notificationHandler := fcm.NewHandler(fcmStore)
+	notificationHandler.RegisterRoutes(subrouter)
+
+	outboxHandler := outbox.NewHandler(outboxStore, auditLogStore)
+	outboxHandler.RegisterRoutes(adminRouter)
+
+	adminHandler := admin.NewHandler(auditLogStore, userStore, sessionStore, listingStore, orderStore,
+									orderUnitOfWork, notifier, blobStore)
+	adminHandler.RegisterRoutes(adminRouter)
+
+	jobScheduler := scheduler.NewScheduler(scheduler.NewStore(s.db))
+	jobScheduler.Register(scheduler.NewOrderDeadlineJob(time.Duration(config.Envs.OrderDeadlineJobIntervalInSeconds)*time.Second,
+		orderStore, listingStore, userStore, notifier, orderUnitOfWork))
+	jobScheduler.Register(scheduler.NewListingExpiryJob(time.Duration(config.Envs.ListingExpiryJobIntervalInSeconds)*time.Second,
+		listingStore, orderStore, userStore, notifier, orderUnitOfWork))
+	jobScheduler.Register(scheduler.NewOrphanedUploadJob(time.Duration(config.Envs.BlobGCJobIntervalInSeconds)*time.Second,
+		blobgc.NewCollector(blobStore, blobgc.NewStore(s.db), time.Duration(config.Envs.BlobGCGracePeriodInSeconds)*time.Second),
+		config.Envs.BlobGCDryRun))
+	jobScheduler.Register(scheduler.NewPayoutKeyRotationJob(time.Duration(config.Envs.BankKeyRotationIntervalInSeconds)*time.Second,
+		payoutMethodStore))
+	jobScheduler.Start()
 
 	log.Println("Listening on: ", s.addr)
 
@@ -71,7 +160,7 @@ func (s *APIServer) Run() error {
 	s.router.Use(logMiddleware.Func())
 
 	s.router.Use(auth.CorsMiddleware())
-	subrouter.Use(jwt.JWTMiddleware())
+	subrouter.Use(jwt.JWTMiddleware(userStore))
 
 	return http.ListenAndServe(s.addr, s.router)
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/service/blob"
+	"github.com/nicolaics/jim-carrier-server/service/blobgc"
+)
+
+// reports the uploads nothing points to anymore and deletes the ones older than
+// the grace period, e.g.
+// go run cmd/blobgc/main.go -dry-run
+// go run cmd/blobgc/main.go -grace 72h
+func main() {
+	dryRun := flag.Bool("dry-run", config.Envs.BlobGCDryRun, "only report the orphans, delete nothing")
+	gracePeriod := flag.Duration("grace", time.Duration(config.Envs.BlobGCGracePeriodInSeconds)*time.Second,
+		"orphans modified within this period are kept")
+	flag.Parse()
+
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 config.Envs.DBUser,
+		Passwd:               config.Envs.DBPassword,
+		Addr:                 config.Envs.DBAddress,
+		DBName:               config.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Ping()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	blobStore, err := blob.NewBlobStoreFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	collector := blobgc.NewCollector(blobStore, blobgc.NewStore(db), *gracePeriod)
+
+	report, err := collector.Collect(*dryRun)
+	if report == nil {
+		log.Fatal(err)
+	}
+
+	var orphanedBytes int64
+
+	for _, orphan := range report.Orphans {
+		status := "would be deleted"
+		switch {
+		case orphan.Deleted:
+			status = "deleted"
+		case orphan.InGracePeriod:
+			status = "kept, within the grace period"
+		case !report.DryRun:
+			status = "not deleted"
+		}
+
+		orphanedBytes += orphan.Size
+		fmt.Printf("%s\t%d bytes\t%s\t%s\n", orphan.Key, orphan.Size, orphan.ModifiedAt.Format(time.RFC3339), status)
+	}
+
+	log.Printf("%d blob(s) scanned, %d orphan(s) (%d bytes), %d deleted", report.Scanned, len(report.Orphans), orphanedBytes, report.DeletedCount)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
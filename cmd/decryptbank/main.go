@@ -0,0 +1,103 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/service/encryption"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// writes the bank details back in plaintext, only needed to roll the migrations
+// back past 20261017210000_encrypt_bank_detail, make migrate-down stops before
+// that step while a row is still encrypted. the bank_detail table is only there again
+// once the migrations are rolled back to that version, e.g.
+// go run cmd/decryptbank/main.go
+func main() {
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 config.Envs.DBUser,
+		Passwd:               config.Envs.DBPassword,
+		Addr:                 config.Envs.DBAddress,
+		DBName:               config.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Ping()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	keyring, err := encryption.NewKeyringFromConfig()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	decrypted, err := decryptBankDetails(db, keyring)
+	log.Printf("%d bank detail(s) decrypted", decrypted)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func decryptBankDetails(db *sql.DB, sealer types.FieldSealer) (int, error) {
+	rows, err := db.Query(`SELECT id, account_number, account_holder, key_id, wrapped_key 
+							FROM bank_detail WHERE key_id IS NOT NULL`)
+	if err != nil {
+		return 0, err
+	}
+
+	type encryptedRow struct {
+		id     int
+		sealed types.SealedFields
+	}
+
+	encryptedRows := make([]encryptedRow, 0)
+
+	for rows.Next() {
+		var row encryptedRow
+		var accountNumber, accountHolder string
+
+		err := rows.Scan(&row.id, &accountNumber, &accountHolder, &row.sealed.KeyID, &row.sealed.WrappedKey)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		row.sealed.Values = []string{accountNumber, accountHolder}
+		encryptedRows = append(encryptedRows, row)
+	}
+	rows.Close()
+
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+
+	decrypted := 0
+
+	for _, row := range encryptedRows {
+		values, err := sealer.Open(&row.sealed)
+		if err != nil {
+			return decrypted, err
+		}
+
+		_, err = db.Exec(`UPDATE bank_detail SET account_number = ?, account_holder = ?, key_id = NULL, wrapped_key = NULL 
+							WHERE id = ?`, values[0], values[1], row.id)
+		if err != nil {
+			return decrypted, err
+		}
+
+		decrypted++
+	}
+
+	return decrypted, nil
+}
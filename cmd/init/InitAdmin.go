@@ -0,0 +1,113 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/service/auth"
+	"github.com/nicolaics/jim-carrier-server/service/user"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// bootstraps the first admin account, e.g.
+// ADMIN_EMAIL=a@b.com ADMIN_PASSWORD=secret go run cmd/init/InitAdmin.go
+func main() {
+	email := flag.String("email", os.Getenv("ADMIN_EMAIL"), "admin email")
+	password := flag.String("password", os.Getenv("ADMIN_PASSWORD"), "admin password, only used when the account is created")
+	name := flag.String("name", getEnv("ADMIN_NAME", "Admin"), "admin name")
+	phoneNumber := flag.String("phone", os.Getenv("ADMIN_PHONE_NUMBER"), "admin phone number")
+	force := flag.Bool("force", false, "create or promote the account even if an admin already exists")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("admin email is required (-email or ADMIN_EMAIL)")
+	}
+
+	db, err := db.NewMySQLStorage(mysql.Config{
+		User:                 config.Envs.DBUser,
+		Passwd:               config.Envs.DBPassword,
+		Addr:                 config.Envs.DBAddress,
+		DBName:               config.Envs.DBName,
+		Net:                  "tcp",
+		AllowNativePasswords: true,
+		ParseTime:            true,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer db.Close()
+
+	err = db.Ping()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	userStore := user.NewStore(db)
+
+	adminCount, err := userStore.GetUserCountByRole(constants.ROLE_ADMIN)
+	if err != nil {
+		log.Fatalf("error count admins: %v", err)
+	}
+
+	if adminCount > 0 && !*force {
+		log.Printf("%d admin(s) already exist, use -force to add another one", adminCount)
+		return
+	}
+
+	exist, _, err := userStore.CheckProvider(*email)
+	if err != nil {
+		log.Fatalf("error check user: %v", err)
+	}
+
+	// an existing account keeps its password, it is only promoted
+	if exist {
+		admin, err := userStore.GetUserByEmail(*email)
+		if err != nil {
+			log.Fatalf("error get user: %v", err)
+		}
+
+		err = userStore.UpdateUserRole(admin.ID, constants.ROLE_ADMIN)
+		if err != nil {
+			log.Fatalf("error update user role: %v", err)
+		}
+
+		log.Printf("%s promoted to admin", *email)
+		return
+	}
+
+	if *password == "" {
+		log.Fatal("admin password is required to create the account (-password or ADMIN_PASSWORD)")
+	}
+
+	hashedPassword, err := auth.HashPassword(*password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = userStore.CreateUser(types.User{
+		Name:        *name,
+		Email:       *email,
+		Password:    hashedPassword,
+		PhoneNumber: *phoneNumber,
+		Provider:    constants.PROVIDER_EMAIL,
+		Role:        constants.ROLE_ADMIN,
+	})
+	if err != nil {
+		log.Fatalf("error create user: %v", err)
+	}
+
+	log.Printf("admin %s created", *email)
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+
+	return fallback
+}
@@ -1,11 +1,12 @@
 package main
 
 import (
+	"database/sql"
+	"fmt"
 	"os"
 
 	"github.com/golang-migrate/migrate/v4"
 
-	_ "database/sql"
 	"log"
 
 	mySqlConfig "github.com/go-sql-driver/mysql"
@@ -55,9 +56,51 @@ func main() {
 		}
 	}
 
+	// one step at a time, so a step that would lose data is refused before it runs
 	if cmd == "down" {
-		if err := m.Down(); err != nil && err != migrate.ErrNoChange {
-			log.Fatal(err)
+		for {
+			version, dirty, err := m.Version()
+			if err == migrate.ErrNilVersion {
+				break
+			}
+			if err != nil {
+				log.Fatal(err)
+			}
+			if dirty {
+				log.Fatalf("version %d is dirty, fix it and run migrate force first", version)
+			}
+
+			if err := checkDown(db, version); err != nil {
+				log.Fatal(err)
+			}
+
+			if err := m.Steps(-1); err != nil {
+				log.Fatal(err)
+			}
 		}
 	}
 }
+
+const encryptBankDetailVersion = 20261017210000
+
+// the down step of encrypt_bank_detail drops the key columns,
+// the bank details still encrypted with them would be lost for good
+func checkDown(db *sql.DB, version uint) error {
+	if version != encryptBankDetailVersion {
+		return nil
+	}
+
+	var count int
+
+	err := db.QueryRow(`SELECT COUNT(*) FROM bank_detail WHERE key_id IS NOT NULL`).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count > 0 {
+		return fmt.Errorf("stopped at version %d: %d bank detail(s) are still encrypted, "+
+			"run make decrypt-bank-detail and then make migrate-down again", version, count)
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS order_event (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `order_id` INT NOT NULL,
+    `event_type` INT NOT NULL,
+    `actor` INT NOT NULL,
+    `order_status` INT NOT NULL,
+    `payment_status` INT NOT NULL,
+    `package_location` VARCHAR(255),
+    `note` TEXT,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_order_event_order_id` (`order_id`, `created_at`)
+);
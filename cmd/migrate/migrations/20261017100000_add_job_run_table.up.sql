@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS job_run (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `job_name` VARCHAR(100) NOT NULL,
+    `instance` VARCHAR(255) NOT NULL,
+    `status` INT NOT NULL,
+    `processed_count` INT NOT NULL DEFAULT 0,
+    `error` TEXT,
+    `started_at` TIMESTAMP NOT NULL,
+    `finished_at` TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_job_run_job_name` (`job_name`, `started_at`)
+);
@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS notification_outbox (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `channel` VARCHAR(20) NOT NULL,
+    `to_user_id` INT,
+    `recipient` VARCHAR(512) NOT NULL,
+    `title` VARCHAR(512) NOT NULL,
+    `body` TEXT NOT NULL,
+    `attachment_url` VARCHAR(512),
+    `attachment_name` VARCHAR(255),
+    `data` TEXT,
+    `status` INT NOT NULL DEFAULT 0,
+    `attempts` INT NOT NULL DEFAULT 0,
+    `last_error` TEXT,
+    `next_attempt_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `sent_at` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_notification_outbox_due` (`status`, `next_attempt_at`)
+);
@@ -0,0 +1,3 @@
+ALTER TABLE fcm_history
+    DROP COLUMN `to_token`,
+    DROP COLUMN `data`;
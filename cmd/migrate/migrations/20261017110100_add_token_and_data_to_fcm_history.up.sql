@@ -0,0 +1,3 @@
+ALTER TABLE fcm_history
+    ADD COLUMN `to_token` VARCHAR(512),
+    ADD COLUMN `data` TEXT;
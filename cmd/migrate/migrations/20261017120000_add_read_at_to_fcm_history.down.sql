@@ -0,0 +1,3 @@
+ALTER TABLE fcm_history
+    DROP INDEX `idx_fcm_history_inbox`,
+    DROP COLUMN `read_at`;
@@ -0,0 +1,3 @@
+ALTER TABLE fcm_history
+    ADD COLUMN `read_at` TIMESTAMP NULL DEFAULT NULL,
+    ADD INDEX `idx_fcm_history_inbox` (`to_user_id`, `read_at`);
@@ -0,0 +1,2 @@
+ALTER TABLE notification_outbox
+    ADD COLUMN `inbox_id` INT;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS user_session (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `user_id` INT NOT NULL,
+    `device_name` VARCHAR(255) NOT NULL,
+    `fcm_token` VARCHAR(512),
+    `last_used_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `revoked_at` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_user_session_user` (`user_id`, `revoked_at`)
+);
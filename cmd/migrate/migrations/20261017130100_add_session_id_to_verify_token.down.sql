@@ -0,0 +1,3 @@
+ALTER TABLE verify_token
+    DROP INDEX `idx_verify_token_session`,
+    DROP COLUMN `session_id`;
@@ -0,0 +1,3 @@
+ALTER TABLE verify_token
+    ADD COLUMN `session_id` INT,
+    ADD INDEX `idx_verify_token_session` (`session_id`, `token_type`);
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS session_event (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `session_id` INT NOT NULL,
+    `user_id` INT NOT NULL,
+    `event_type` INT NOT NULL,
+    `token_uuid` VARCHAR(64),
+    `ip_address` VARCHAR(64),
+    `user_agent` VARCHAR(512),
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_session_event_user` (`user_id`, `created_at`),
+    INDEX `idx_session_event_token` (`session_id`, `token_uuid`)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user
+    ADD COLUMN `role` INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE user
+    ADD COLUMN `suspended_at` TIMESTAMP NULL DEFAULT NULL;
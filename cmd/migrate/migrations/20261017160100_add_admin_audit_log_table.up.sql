@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `admin_id` INT NOT NULL,
+    `action` INT NOT NULL,
+    `target_type` INT NOT NULL,
+    `target_id` INT NOT NULL,
+    `reason` TEXT,
+    `ip_address` VARCHAR(64),
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_admin_audit_log_target` (`target_type`, `target_id`, `created_at`),
+    INDEX `idx_admin_audit_log_admin` (`admin_id`, `created_at`)
+);
@@ -0,0 +1,6 @@
+ALTER TABLE listing
+    DROP INDEX `idx_listing_route`,
+    DROP COLUMN `origin_city`,
+    DROP COLUMN `origin_country_code`,
+    DROP COLUMN `destination_city`,
+    DROP COLUMN `destination_country_code`;
@@ -0,0 +1,6 @@
+ALTER TABLE listing
+    ADD COLUMN `origin_city` VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN `origin_country_code` CHAR(2) NOT NULL DEFAULT '',
+    ADD COLUMN `destination_city` VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN `destination_country_code` CHAR(2) NOT NULL DEFAULT '',
+    ADD INDEX `idx_listing_route` (`origin_country_code`, `destination_country_code`, `departure_date`);
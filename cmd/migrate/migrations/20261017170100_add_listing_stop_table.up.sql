@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS listing_stop (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `listing_id` INT NOT NULL,
+    `stop_order` INT NOT NULL,
+    `city` VARCHAR(255) NOT NULL,
+    `country_code` CHAR(2) NOT NULL,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_listing_stop_listing` (`listing_id`, `stop_order`),
+    INDEX `idx_listing_stop_city` (`city`)
+);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS delivery_request (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `giver_id` INT NOT NULL,
+    `origin_city` VARCHAR(255) NOT NULL,
+    `origin_country_code` CHAR(2) NOT NULL,
+    `destination_city` VARCHAR(255) NOT NULL,
+    `destination_country_code` CHAR(2) NOT NULL,
+    `weight` DOUBLE NOT NULL,
+    `budget` DOUBLE NOT NULL,
+    `currency_id` INT NOT NULL,
+    `deadline` TIMESTAMP NOT NULL,
+    `package_content` VARCHAR(255) NOT NULL,
+    `package_img_url` VARCHAR(255),
+    `notes` TEXT,
+    `status` INT NOT NULL DEFAULT 0,
+    `order_id` INT,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_modified_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_delivery_request_route` (`status`, `origin_country_code`, `destination_country_code`, `deadline`),
+    INDEX `idx_delivery_request_giver` (`giver_id`)
+);
@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS request_offer (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `request_id` INT NOT NULL,
+    `carrier_id` INT NOT NULL,
+    `price` DOUBLE NOT NULL,
+    `departure_date` TIMESTAMP NOT NULL,
+    `last_received_date` TIMESTAMP NOT NULL,
+    `message` TEXT,
+    `status` INT NOT NULL DEFAULT 0,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_modified_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_request_offer_request` (`request_id`, `status`),
+    INDEX `idx_request_offer_carrier` (`carrier_id`)
+);
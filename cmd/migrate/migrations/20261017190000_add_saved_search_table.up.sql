@@ -0,0 +1,20 @@
+CREATE TABLE IF NOT EXISTS saved_search (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `user_id` INT NOT NULL,
+    `name` VARCHAR(255) NOT NULL,
+    `destination` VARCHAR(255) NOT NULL DEFAULT '',
+    `departure_from` TIMESTAMP NULL DEFAULT NULL,
+    `departure_to` TIMESTAMP NULL DEFAULT NULL,
+    `max_price_per_kg` DOUBLE NOT NULL DEFAULT 0,
+    `currency` VARCHAR(10) NOT NULL DEFAULT '',
+    `min_weight` DOUBLE NOT NULL DEFAULT 0,
+    `alerts_enabled` BOOLEAN NOT NULL DEFAULT TRUE,
+    `unsubscribe_token` VARCHAR(64) NOT NULL,
+    `last_alerted_at` TIMESTAMP NULL DEFAULT NULL,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_saved_search_user` (`user_id`),
+    INDEX `idx_saved_search_alerts` (`alerts_enabled`),
+    UNIQUE INDEX `idx_saved_search_unsubscribe_token` (`unsubscribe_token`)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE user
+    DROP COLUMN `saved_search_alerted_at`;
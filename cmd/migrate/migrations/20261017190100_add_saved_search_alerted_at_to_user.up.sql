@@ -0,0 +1,2 @@
+ALTER TABLE user
+    ADD COLUMN `saved_search_alerted_at` TIMESTAMP NULL DEFAULT NULL;
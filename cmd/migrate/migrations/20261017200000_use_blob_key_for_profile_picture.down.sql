@@ -0,0 +1,3 @@
+UPDATE user
+    SET `profile_picture_url` = CONCAT('./static/img/', `profile_picture_url`)
+    WHERE `profile_picture_url` <> '';
@@ -0,0 +1,3 @@
+UPDATE user
+    SET `profile_picture_url` = SUBSTRING(`profile_picture_url`, CHAR_LENGTH('./static/img/') + 1)
+    WHERE `profile_picture_url` LIKE './static/img/%';
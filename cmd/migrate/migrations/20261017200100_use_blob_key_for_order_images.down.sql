@@ -0,0 +1,3 @@
+UPDATE order_list
+    SET `package_img_url` = IF(`package_img_url` <> '', CONCAT('./static/img/', `package_img_url`), `package_img_url`),
+        `payment_proof_url` = IF(`payment_proof_url` <> '', CONCAT('./static/img/', `payment_proof_url`), `payment_proof_url`);
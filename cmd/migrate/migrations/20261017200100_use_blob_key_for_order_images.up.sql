@@ -0,0 +1,3 @@
+UPDATE order_list
+    SET `package_img_url` = IF(`package_img_url` LIKE './static/img/%', SUBSTRING(`package_img_url`, CHAR_LENGTH('./static/img/') + 1), `package_img_url`),
+        `payment_proof_url` = IF(`payment_proof_url` LIKE './static/img/%', SUBSTRING(`payment_proof_url`, CHAR_LENGTH('./static/img/') + 1), `payment_proof_url`);
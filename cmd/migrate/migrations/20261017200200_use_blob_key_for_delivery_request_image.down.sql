@@ -0,0 +1,3 @@
+UPDATE delivery_request
+    SET `package_img_url` = CONCAT('./static/img/', `package_img_url`)
+    WHERE `package_img_url` <> '';
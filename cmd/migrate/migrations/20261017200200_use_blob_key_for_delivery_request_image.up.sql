@@ -0,0 +1,3 @@
+UPDATE delivery_request
+    SET `package_img_url` = SUBSTRING(`package_img_url`, CHAR_LENGTH('./static/img/') + 1)
+    WHERE `package_img_url` LIKE './static/img/%';
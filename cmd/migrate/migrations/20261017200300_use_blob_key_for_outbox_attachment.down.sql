@@ -0,0 +1,3 @@
+UPDATE notification_outbox
+    SET `attachment_url` = CONCAT('./static/img/', `attachment_url`)
+    WHERE `attachment_url` <> '';
@@ -0,0 +1,3 @@
+UPDATE notification_outbox
+    SET `attachment_url` = SUBSTRING(`attachment_url`, CHAR_LENGTH('./static/img/') + 1)
+    WHERE `attachment_url` LIKE './static/img/%';
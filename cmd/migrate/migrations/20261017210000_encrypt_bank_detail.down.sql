@@ -0,0 +1,3 @@
+ALTER TABLE bank_detail
+    DROP COLUMN `key_id`,
+    DROP COLUMN `wrapped_key`;
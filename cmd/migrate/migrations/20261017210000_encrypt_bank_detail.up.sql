@@ -0,0 +1,5 @@
+ALTER TABLE bank_detail
+    MODIFY COLUMN `account_number` VARCHAR(512) NOT NULL,
+    MODIFY COLUMN `account_holder` VARCHAR(512) NOT NULL,
+    ADD COLUMN `key_id` VARCHAR(32) DEFAULT NULL,
+    ADD COLUMN `wrapped_key` VARCHAR(128) DEFAULT NULL;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS payout_method (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `user_id` INT NOT NULL,
+    `method_type` INT NOT NULL,
+    `currency_id` INT DEFAULT NULL,
+    `provider` VARCHAR(255) NOT NULL,
+    `account_number` VARCHAR(512) NOT NULL,
+    `account_holder` VARCHAR(512) NOT NULL,
+    `key_id` VARCHAR(32) DEFAULT NULL,
+    `wrapped_key` VARCHAR(128) DEFAULT NULL,
+    `is_default` BOOLEAN NOT NULL DEFAULT FALSE,
+    `created_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `last_modified_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `bank_detail_id` INT DEFAULT NULL,
+
+    PRIMARY KEY (`id`),
+    INDEX `idx_payout_method_user_currency` (`user_id`, `currency_id`)
+);
@@ -0,0 +1 @@
+DELETE FROM payout_method WHERE `bank_detail_id` IS NOT NULL;
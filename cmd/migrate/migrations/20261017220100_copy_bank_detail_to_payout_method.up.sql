@@ -0,0 +1,5 @@
+INSERT INTO payout_method (`user_id`, `method_type`, `currency_id`, `provider`, `account_number`, `account_holder`, `key_id`, `wrapped_key`, `is_default`, `last_modified_at`, `bank_detail_id`)
+    SELECT b.user_id, 0,
+        (SELECT l.currency_id FROM listing AS l WHERE l.carrier_id = b.user_id ORDER BY l.id DESC LIMIT 1),
+        b.bank_name, b.account_number, b.account_holder, b.key_id, b.wrapped_key, TRUE, b.last_modified_at, b.id
+    FROM bank_detail AS b;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS bank_detail (
+    `id` INT NOT NULL AUTO_INCREMENT,
+    `user_id` INT NOT NULL,
+    `bank_name` VARCHAR(255) NOT NULL,
+    `account_number` VARCHAR(512) NOT NULL,
+    `account_holder` VARCHAR(512) NOT NULL,
+    `last_modified_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    `key_id` VARCHAR(32) DEFAULT NULL,
+    `wrapped_key` VARCHAR(128) DEFAULT NULL,
+
+    PRIMARY KEY (`id`)
+);
+
+-- bank_detail holds one account per user, the newest default bank account is kept
+INSERT INTO bank_detail (`user_id`, `bank_name`, `account_number`, `account_holder`, `last_modified_at`, `key_id`, `wrapped_key`)
+    SELECT p.user_id, p.provider, p.account_number, p.account_holder, p.last_modified_at, p.key_id, p.wrapped_key
+    FROM payout_method AS p
+    WHERE p.id = (SELECT MAX(d.id) FROM payout_method AS d
+                    WHERE d.user_id = p.user_id
+                    AND d.method_type = 0
+                    AND d.is_default = TRUE);
@@ -24,6 +24,30 @@ type Config struct {
 	GoogleClientID                   string
 	GoogleClientSecret               string
 	GoogleApplicationCredentialsPath string
+	OrderDeadlineJobIntervalInSeconds int64
+	ListingExpiryJobIntervalInSeconds int64
+	ServiceFeePercent                 float64
+	InsuranceFeePercent               float64
+	NotificationMode                  string
+	SMTPHost                          string
+	SMTPPort                          string
+	OutboxPollIntervalInSeconds       int64
+	OutboxBaseBackoffInSeconds        int64
+	OutboxMaxAttempts                 int64
+	SavedSearchAlertIntervalInSeconds int64
+	BlobStorage                       string
+	BlobLocalRoot                     string
+	S3Endpoint                        string
+	S3Region                          string
+	S3Bucket                          string
+	S3AccessKeyID                     string
+	S3SecretAccessKey                 string
+	BlobGCJobIntervalInSeconds        int64
+	BlobGCGracePeriodInSeconds        int64
+	BlobGCDryRun                      bool
+	BankEncryptionKeys                string
+	BankEncryptionKeyID               string
+	BankKeyRotationIntervalInSeconds  int64
 }
 
 var Envs = initConfig()
@@ -48,6 +72,30 @@ func initConfig() Config {
 		GoogleClientID:                   getEnv("GOOGLE_CLIENT_ID", ""),
 		GoogleClientSecret:               getEnv("GOOGLE_CLIENT_SECRET", ""),
 		GoogleApplicationCredentialsPath: getEnv("GOOGLE_APPLICATION_CREDENTIALS_PATH", ""),
+		OrderDeadlineJobIntervalInSeconds: getEnvAsInt("ORDER_DEADLINE_JOB_INTERVAL", (60 * 5)), // every 5 minutes
+		ListingExpiryJobIntervalInSeconds: getEnvAsInt("LISTING_EXPIRY_JOB_INTERVAL", (60 * 5)), // every 5 minutes
+		ServiceFeePercent:                 getEnvAsFloat("SERVICE_FEE_PERCENT", 0),
+		InsuranceFeePercent:               getEnvAsFloat("INSURANCE_FEE_PERCENT", 0),
+		NotificationMode:                  getEnv("NOTIFICATION_MODE", "live"), // "live" or "recorder"
+		SMTPHost:                          getEnv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:                          getEnv("SMTP_PORT", "587"),
+		OutboxPollIntervalInSeconds:       getEnvAsInt("OUTBOX_POLL_INTERVAL", 10),
+		OutboxBaseBackoffInSeconds:        getEnvAsInt("OUTBOX_BASE_BACKOFF", 30),
+		OutboxMaxAttempts:                 getEnvAsInt("OUTBOX_MAX_ATTEMPTS", 5),
+		SavedSearchAlertIntervalInSeconds: getEnvAsInt("SAVED_SEARCH_ALERT_INTERVAL", (60 * 60)), // at most one alert an hour per user
+		BlobStorage:                       getEnv("BLOB_STORAGE", "local"), // "local" or "s3"
+		BlobLocalRoot:                     getEnv("BLOB_LOCAL_ROOT", "./static/img/"),
+		S3Endpoint:                        getEnv("S3_ENDPOINT", "https://s3.amazonaws.com"),
+		S3Region:                          getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:                          getEnv("S3_BUCKET", ""),
+		S3AccessKeyID:                     getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:                 getEnv("S3_SECRET_ACCESS_KEY", ""),
+		BlobGCJobIntervalInSeconds:        getEnvAsInt("BLOB_GC_JOB_INTERVAL", (3600 * 24)), // once a day
+		BlobGCGracePeriodInSeconds:        getEnvAsInt("BLOB_GC_GRACE_PERIOD", (3600 * 24)), // an upload gets a day to be referenced
+		BlobGCDryRun:                      getEnvAsBool("BLOB_GC_DRY_RUN", false),
+		BankEncryptionKeys:                getEnv("BANK_ENCRYPTION_KEYS", ""), // "<key id>:<base64 of 32 bytes>,..."
+		BankEncryptionKeyID:               getEnv("BANK_ENCRYPTION_KEY_ID", ""),
+		BankKeyRotationIntervalInSeconds:  getEnvAsInt("BANK_KEY_ROTATION_JOB_INTERVAL", 3600),
 	}
 }
 
@@ -72,3 +120,31 @@ func getEnvAsInt(key string, fallback int64) int64 {
 
 	return fallback
 }
+
+func getEnvAsFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		f, err := strconv.ParseFloat(value, 64)
+
+		if err != nil {
+			return fallback
+		}
+
+		return f
+	}
+
+	return fallback
+}
+
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		b, err := strconv.ParseBool(value)
+
+		if err != nil {
+			return fallback
+		}
+
+		return b
+	}
+
+	return fallback
+}
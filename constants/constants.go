@@ -2,6 +2,7 @@ package constants
 
 const EXP_STATUS_AVAILABLE = 0
 const EXP_STATUS_EXPIRED = 1
+const EXP_STATUS_RESERVED = 2
 
 const PAYMENT_STATUS_PENDING = 0
 const PAYMENT_STATUS_CANCELLED = 1
@@ -23,6 +24,7 @@ const PENDING_STATUS_STR = "pending"
 const REFUNDED_STATUS_STR = "refunded"
 const EXPIRED_STATUS_STR = "expired"
 const AVAILABLE_STATUS_STR = "available"
+const RESERVED_STATUS_STR = "reserved"
 
 const VERIFY_CODE_WAITING = 0
 const VERIFY_CODE_COMPLETE = 1
@@ -36,13 +38,152 @@ const PROVIDER_GMAIL = "gmail"
 const REVIEW_GIVER_TO_CARRIER = 0
 const REVIEW_CARRIER_TO_GIVER = 1
 
-const PAYMENT_PROOF_DIR_PATH = "./static/img/payment_proof/"
-const PROFILE_IMG_DIR_PATH = "./static/img/profile_img/"
-const PACKAGE_IMG_DIR_PATH = "./static/img/package/"
+const PAYMENT_PROOF_KEY_PREFIX = "payment_proof/"
+const PROFILE_IMG_KEY_PREFIX = "profile_img/"
+const PACKAGE_IMG_KEY_PREFIX = "package/"
+
+const DEFAULT_PROFILE_IMG_KEY = PROFILE_IMG_KEY_PREFIX + "default.png"
+
+const BLOB_STORAGE_LOCAL = "local"
+const BLOB_STORAGE_S3 = "s3"
 
 const PAYMENT_PROOF_MAX_BYTES = 10 << 20 // 10MB in bytes
 const PROFILE_IMG_MAX_BYTES = 5 << 20 // 5MB in bytes
 const PACKAGE_IMG_MAX_BYTES = 10 << 20 // 10MB in bytes
+const UPLOAD_FIELDS_MAX_BYTES = 1 << 20 // 1MB for everything but the file
+
+const IMG_MAX_PIXELS = 50_000_000 // refuse to decode anything bigger, whatever the file size
+const IMG_MAX_DIMENSION = 2048
+const IMG_MEDIUM_DIMENSION = 1024
+const IMG_THUMBNAIL_DIMENSION = 256
+const IMG_JPEG_QUALITY = 85
 
 const ACCESS_TOKEN = 0
 const REFRESH_TOKEN = 1
+
+const ACTOR_GIVER = 0
+const ACTOR_CARRIER = 1
+const ACTOR_SYSTEM = 2
+const ACTOR_ADMIN = 3
+
+const GIVER_ACTOR_STR = "giver"
+const CARRIER_ACTOR_STR = "carrier"
+const SYSTEM_ACTOR_STR = "system"
+const ADMIN_ACTOR_STR = "admin"
+
+const ORDER_EVENT_STATUS_CHANGED = 0
+const ORDER_EVENT_LOCATION_UPDATED = 1
+const ORDER_EVENT_PAYMENT_UPDATED = 2
+const ORDER_EVENT_MODIFIED = 3
+const ORDER_EVENT_CREATED = 4
+
+const STATUS_CHANGED_EVENT_STR = "status-changed"
+const LOCATION_UPDATED_EVENT_STR = "location-updated"
+const PAYMENT_UPDATED_EVENT_STR = "payment-updated"
+const MODIFIED_EVENT_STR = "modified"
+const CREATED_EVENT_STR = "created"
+
+const JOB_RUN_SUCCESS = 0
+const JOB_RUN_FAILED = 1
+
+const SERVICE_FEE_STR = "service"
+const INSURANCE_FEE_STR = "insurance"
+
+const NOTIFICATION_CHANNEL_EMAIL = "email"
+const NOTIFICATION_CHANNEL_PUSH = "push"
+
+const NOTIFICATION_MODE_LIVE = "live"
+const NOTIFICATION_MODE_RECORDER = "recorder"
+
+const OUTBOX_STATUS_PENDING = 0
+const OUTBOX_STATUS_SENT = 1
+const OUTBOX_STATUS_DEAD = 2
+
+const SENT_STATUS_STR = "sent"
+const DEAD_STATUS_STR = "dead"
+
+const SESSION_EVENT_LOGIN = 0
+const SESSION_EVENT_REFRESHED = 1
+const SESSION_EVENT_REUSE_DETECTED = 2
+const SESSION_EVENT_REVOKED = 3
+
+const LOGIN_EVENT_STR = "login"
+const REFRESHED_EVENT_STR = "refreshed"
+const REUSE_DETECTED_EVENT_STR = "reuse-detected"
+const REVOKED_EVENT_STR = "revoked"
+
+const ROLE_USER = 0
+const ROLE_SUPPORT = 1
+const ROLE_ADMIN = 2
+
+const USER_ROLE_STR = "user"
+const SUPPORT_ROLE_STR = "support"
+const ADMIN_ROLE_STR = "admin"
+
+const AUDIT_ACTION_SUSPEND_USER = 0
+const AUDIT_ACTION_REACTIVATE_USER = 1
+const AUDIT_ACTION_EXPIRE_LISTING = 2
+const AUDIT_ACTION_DELETE_LISTING = 3
+const AUDIT_ACTION_FORCE_ORDER_STATUS = 4
+const AUDIT_ACTION_VIEW_PAYMENT_PROOF = 5
+const AUDIT_ACTION_REPLAY_OUTBOX = 6
+
+const SUSPEND_USER_ACTION_STR = "suspend-user"
+const REACTIVATE_USER_ACTION_STR = "reactivate-user"
+const EXPIRE_LISTING_ACTION_STR = "expire-listing"
+const DELETE_LISTING_ACTION_STR = "delete-listing"
+const FORCE_ORDER_STATUS_ACTION_STR = "force-order-status"
+const VIEW_PAYMENT_PROOF_ACTION_STR = "view-payment-proof"
+const REPLAY_OUTBOX_ACTION_STR = "replay-outbox"
+
+const AUDIT_TARGET_USER = 0
+const AUDIT_TARGET_LISTING = 1
+const AUDIT_TARGET_ORDER = 2
+const AUDIT_TARGET_OUTBOX = 3
+
+const USER_TARGET_STR = "user"
+const LISTING_TARGET_STR = "listing"
+const ORDER_TARGET_STR = "order"
+const OUTBOX_TARGET_STR = "outbox"
+
+const LISTING_SORT_DEPARTURE_STR = "departure"
+const LISTING_SORT_DEPARTURE_DESC_STR = "departure-desc"
+const LISTING_SORT_PRICE_STR = "price"
+const LISTING_SORT_PRICE_DESC_STR = "price-desc"
+const LISTING_SORT_WEIGHT_DESC_STR = "weight-desc"
+const LISTING_SORT_RATING_DESC_STR = "rating-desc"
+const LISTING_SORT_NEWEST_STR = "newest"
+
+const REQUEST_STATUS_OPEN = 0
+const REQUEST_STATUS_ACCEPTED = 1
+const REQUEST_STATUS_CANCELLED = 2
+
+const OPEN_STATUS_STR = "open"
+const ACCEPTED_STATUS_STR = "accepted"
+
+const OFFER_STATUS_PENDING = 0
+const OFFER_STATUS_ACCEPTED = 1
+const OFFER_STATUS_REJECTED = 2
+const OFFER_STATUS_WITHDRAWN = 3
+
+const REJECTED_STATUS_STR = "rejected"
+const WITHDRAWN_STATUS_STR = "withdrawn"
+
+const SAVED_SEARCH_MAX_PER_USER = 20
+
+const IMAGE_KIND_PROFILE_STR = "profile"
+const IMAGE_KIND_PACKAGE_STR = "package"
+const IMAGE_KIND_PAYMENT_PROOF_STR = "payment-proof"
+
+const IMAGE_VARIANT_MEDIUM_STR = "medium"
+const IMAGE_VARIANT_THUMBNAIL_STR = "thumb"
+
+const IMAGE_CACHE_CONTROL = "private, max-age=86400" // a day, stored file names are never reused
+
+const PAYOUT_METHOD_BANK_ACCOUNT = 0
+const PAYOUT_METHOD_E_WALLET = 1
+
+const BANK_ACCOUNT_TYPE_STR = "bank-account"
+const E_WALLET_TYPE_STR = "e-wallet"
+
+const PAYOUT_METHOD_MAX_PER_USER = 20
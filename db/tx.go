@@ -0,0 +1,26 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// RunInTx runs fn inside a single transaction.
+// The transaction is committed when fn returns nil, and rolled back otherwise.
+func RunInTx(conn *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+
+	err = fn(tx)
+	if err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("%v (rollback error: %v)", err, rbErr)
+		}
+
+		return err
+	}
+
+	return tx.Commit()
+}
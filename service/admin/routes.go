@@ -0,0 +1,572 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/service/notification"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	auditLogStore   types.AuditLogStore
+	userStore       types.UserStore
+	sessionStore    types.SessionStore
+	listingStore    types.ListingStore
+	orderStore      types.OrderStore
+	orderUnitOfWork types.OrderUnitOfWork
+	notifier        types.Notifier
+	blobStore       types.BlobStore
+}
+
+func NewHandler(auditLogStore types.AuditLogStore, userStore types.UserStore, sessionStore types.SessionStore,
+	listingStore types.ListingStore, orderStore types.OrderStore, orderUnitOfWork types.OrderUnitOfWork,
+	notifier types.Notifier, blobStore types.BlobStore) *Handler {
+	return &Handler{
+		auditLogStore:   auditLogStore,
+		userStore:       userStore,
+		sessionStore:    sessionStore,
+		listingStore:    listingStore,
+		orderStore:      orderStore,
+		orderUnitOfWork: orderUnitOfWork,
+		notifier:        notifier,
+		blobStore:       blobStore,
+	}
+}
+
+// the router must already be restricted to admin and support staff
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/user", h.handleGetUsers).Methods(http.MethodGet)
+	router.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/user/{id}/suspend", h.handleSuspendUser).Methods(http.MethodPost)
+	router.HandleFunc("/user/{id}/suspend", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/user/{id}/reactivate", h.handleReactivateUser).Methods(http.MethodPost)
+	router.HandleFunc("/user/{id}/reactivate", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/listing/{id}/expire", h.handleExpireListing).Methods(http.MethodPost)
+	router.HandleFunc("/listing/{id}/expire", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/listing/{id}", h.handleDeleteListing).Methods(http.MethodDelete)
+	router.HandleFunc("/listing/{id}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/order/{id}/status", h.handleForceOrderStatus).Methods(http.MethodPatch)
+	router.HandleFunc("/order/{id}/status", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/order/{id}/payment-proof", h.handleGetPaymentProof).Methods(http.MethodGet)
+	router.HandleFunc("/order/{id}/payment-proof", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/audit-log", h.handleGetAuditLogs).Methods(http.MethodGet)
+	router.HandleFunc("/audit-log", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+// lists every user, or searches them by name or phone number
+func (h *Handler) handleGetUsers(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	name := strings.TrimSpace(r.URL.Query().Get("name"))
+	phoneNumber := strings.TrimSpace(r.URL.Query().Get("phoneNumber"))
+
+	var users []types.User
+	var total int
+
+	if name != "" || phoneNumber != "" {
+		if name != "" {
+			users, err = h.userStore.GetUserBySearchName(name)
+		} else {
+			users, err = h.userStore.GetUserBySearchPhoneNumber(phoneNumber)
+		}
+		if err != nil {
+			log.Printf("error search users: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error search users: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+
+		// the search is not paginated in the store
+		total = len(users)
+		start := min((page-1)*pageSize, total)
+		users = users[start:min(start+pageSize, total)]
+	} else {
+		total, err = h.userStore.GetUserCount()
+		if err != nil {
+			log.Printf("error count users: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count users: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+
+		users, err = h.userStore.GetUsers(pageSize, (page-1)*pageSize)
+		if err != nil {
+			log.Printf("error get users: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get users: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+	}
+
+	returnUsers := make([]types.AdminUserReturnPayload, 0)
+
+	for _, user := range users {
+		returnUsers = append(returnUsers, types.AdminUserReturnPayload{
+			ID:           user.ID,
+			Name:         user.Name,
+			Email:        user.Email,
+			PhoneNumber:  user.PhoneNumber,
+			Provider:     user.Provider,
+			Role:         utils.RoleIntToString(user.Role),
+			Suspended:    user.SuspendedAt.Valid,
+			SuspendedAt:  user.SuspendedAt.Time,
+			LastLoggedIn: user.LastLoggedIn,
+			CreatedAt:    user.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.AdminUsersReturnPayload{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		Users:    returnUsers,
+	})
+}
+
+func (h *Handler) handleSuspendUser(w http.ResponseWriter, r *http.Request) {
+	admin, id, payload, ok := h.parseReasonRequest(w, r, "user")
+	if !ok {
+		return
+	}
+
+	if id == admin.ID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you cannot suspend yourself"))
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user %d not found", id))
+		return
+	}
+
+	// support staff handle customers, staff accounts are left to admins
+	if user.Role != constants.ROLE_USER && admin.Role != constants.ROLE_ADMIN {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only an admin can suspend a staff account"))
+		return
+	}
+
+	suspended, err := h.userStore.SuspendUser(user.ID)
+	if err != nil {
+		log.Printf("error suspend user: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error suspend user: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !suspended {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("user %d is already suspended", id))
+		return
+	}
+
+	// no session is the current one, so every device is logged out
+	revokedIds, err := h.sessionStore.RevokeOtherSessions(user.ID, 0)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("user %d suspended but error revoke sessions: %v", user.ID, err))
+	}
+
+	// the user's session history shows the forced logout, from the admin's address
+	for _, sessionId := range revokedIds {
+		err = h.sessionStore.CreateSessionEvent(types.SessionEvent{
+			SessionID: sessionId,
+			UserID:    user.ID,
+			EventType: constants.SESSION_EVENT_REVOKED,
+			IPAddress: utils.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error record revoked event of session %d: %v", sessionId, err))
+		}
+	}
+
+	h.recordAuditLog(r, admin, constants.AUDIT_ACTION_SUSPEND_USER, constants.AUDIT_TARGET_USER, user.ID, payload.Reason)
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("user %d suspended", user.ID))
+}
+
+func (h *Handler) handleReactivateUser(w http.ResponseWriter, r *http.Request) {
+	admin, id, payload, ok := h.parseReasonRequest(w, r, "user")
+	if !ok {
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("user %d not found", id))
+		return
+	}
+
+	if user.Role != constants.ROLE_USER && admin.Role != constants.ROLE_ADMIN {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("only an admin can reactivate a staff account"))
+		return
+	}
+
+	reactivated, err := h.userStore.ReactivateUser(user.ID)
+	if err != nil {
+		log.Printf("error reactivate user: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reactivate user: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !reactivated {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("no suspended user with id %d", id))
+		return
+	}
+
+	h.recordAuditLog(r, admin, constants.AUDIT_ACTION_REACTIVATE_USER, constants.AUDIT_TARGET_USER, id, payload.Reason)
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("user %d reactivated", id))
+}
+
+func (h *Handler) handleExpireListing(w http.ResponseWriter, r *http.Request) {
+	admin, id, payload, ok := h.parseReasonRequest(w, r, "listing")
+	if !ok {
+		return
+	}
+
+	listing, err := h.listingStore.GetListingByID(id)
+	if err != nil || listing == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("listing %d not found", id))
+		return
+	}
+
+	expired, err := h.listingStore.ExpireListing(listing.ID)
+	if err != nil {
+		log.Printf("error expire listing: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error expire listing: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !expired {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("listing %d is already expired", id))
+		return
+	}
+
+	h.recordAuditLog(r, admin, constants.AUDIT_ACTION_EXPIRE_LISTING, constants.AUDIT_TARGET_LISTING, listing.ID, payload.Reason)
+
+	h.notifyUser(listing.CarrierID, fmt.Sprintf("Listing to %s Expired", listing.Destination),
+		fmt.Sprintf("Your listing to %s departing on %s was expired by our support team: %s",
+			listing.Destination, listing.DepartureDate.Format("02 Jan 2006"), payload.Reason), 0)
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("listing %d expired", listing.ID))
+}
+
+func (h *Handler) handleDeleteListing(w http.ResponseWriter, r *http.Request) {
+	admin, id, payload, ok := h.parseReasonRequest(w, r, "listing")
+	if !ok {
+		return
+	}
+
+	listing, err := h.listingStore.GetListingByID(id)
+	if err != nil || listing == nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("listing %d not found", id))
+		return
+	}
+
+	// the orders have to be cancelled first, so their weight and givers are taken care of
+	orderCount, err := h.orderStore.GetOrderCountByListingID(listing.ID)
+	if err != nil {
+		log.Printf("error count orders of listing: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count orders of listing: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if orderCount > 0 {
+		utils.WriteError(w, http.StatusConflict, fmt.Errorf("there are active orders, cancel them first"))
+		return
+	}
+
+	err = h.listingStore.DeleteListing(listing.ID)
+	if err != nil {
+		log.Printf("error delete listing: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error delete listing: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	h.recordAuditLog(r, admin, constants.AUDIT_ACTION_DELETE_LISTING, constants.AUDIT_TARGET_LISTING, listing.ID, payload.Reason)
+
+	h.notifyUser(listing.CarrierID, fmt.Sprintf("Listing to %s Removed", listing.Destination),
+		fmt.Sprintf("Your listing to %s departing on %s was removed by our support team: %s",
+			listing.Destination, listing.DepartureDate.Format("02 Jan 2006"), payload.Reason), 0)
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("listing %d deleted", listing.ID))
+}
+
+// moves the order to any other status, bypassing the lifecycle rules of the parties
+func (h *Handler) handleForceOrderStatus(w http.ResponseWriter, r *http.Request) {
+	admin, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid order id"))
+		return
+	}
+
+	var payload types.AdminOrderStatusPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v \n", err)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	orderStatus := utils.OrderStatusStringToInt(payload.OrderStatus)
+	if orderStatus == -1 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown order status"))
+		return
+	}
+
+	order, err := h.orderStore.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order %d not found", id))
+		return
+	}
+
+	listing, err := h.listingStore.GetListingByID(order.ListingID)
+	if err != nil || listing == nil {
+		log.Printf("error get listing of order %d: %v", order.ID, err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get listing of order %d: %v", order.ID, err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	err = h.orderUnitOfWork.TransitionOrder(order.ID, orderStatus, constants.ACTOR_ADMIN, "", payload.Reason)
+	if errors.Is(err, types.ErrIllegalTransition) {
+		utils.WriteError(w, http.StatusConflict, err)
+		return
+	}
+	if errors.Is(err, types.ErrNotEnoughWeight) {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		log.Printf("error force order status: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error force order status: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	reason := fmt.Sprintf("%s -> %s: %s", utils.OrderStatusIntToString(order.OrderStatus), payload.OrderStatus, payload.Reason)
+	h.recordAuditLog(r, admin, constants.AUDIT_ACTION_FORCE_ORDER_STATUS, constants.AUDIT_TARGET_ORDER, order.ID, reason)
+
+	subject := fmt.Sprintf("Order No. %d is %s", order.ID, payload.OrderStatus)
+	body := fmt.Sprintf("Our support team changed order no. %d to %s: %s", order.ID, payload.OrderStatus, payload.Reason)
+	h.notifyUser(order.GiverID, subject, body, order.ID)
+	h.notifyUser(listing.CarrierID, subject, body, order.ID)
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("order %d is now %s", order.ID, payload.OrderStatus))
+}
+
+func (h *Handler) handleGetPaymentProof(w http.ResponseWriter, r *http.Request) {
+	admin, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid order id"))
+		return
+	}
+
+	order, err := h.orderStore.GetOrderByID(id)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order %d not found", id))
+		return
+	}
+
+	if order.PaymentProofURL == "" {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order %d has no payment proof", id))
+		return
+	}
+
+	// looking at someone's payment is an action too
+	h.recordAuditLog(r, admin, constants.AUDIT_ACTION_VIEW_PAYMENT_PROOF, constants.AUDIT_TARGET_ORDER, order.ID,
+		r.URL.Query().Get("reason"))
+
+	err = utils.ServeImage(w, r, h.blobStore, order.PaymentProofURL)
+	if errors.Is(err, types.ErrBlobNotFound) {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("order %d has no payment proof", id))
+		return
+	}
+	if err != nil {
+		log.Printf("error get payment proof: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get payment proof: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+}
+
+func (h *Handler) handleGetAuditLogs(w http.ResponseWriter, r *http.Request) {
+	targetType := -1
+	if targetTypeStr := r.URL.Query().Get("targetType"); targetTypeStr != "" {
+		targetType = utils.AuditTargetStringToInt(targetTypeStr)
+		if targetType == -1 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown target type"))
+			return
+		}
+	}
+
+	targetId := 0
+	if targetIdStr := r.URL.Query().Get("targetId"); targetIdStr != "" {
+		var err error
+		targetId, err = strconv.Atoi(targetIdStr)
+		if err != nil || targetId < 1 || targetType == -1 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid targetId, it needs a targetType"))
+			return
+		}
+	}
+
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	total, err := h.auditLogStore.GetAuditLogCount(targetType, targetId)
+	if err != nil {
+		log.Printf("error count audit logs: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count audit logs: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	auditLogs, err := h.auditLogStore.GetAuditLogs(targetType, targetId, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("error get audit logs: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get audit logs: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnAuditLogs := make([]types.AuditLogReturnPayload, 0)
+
+	for _, auditLog := range auditLogs {
+		returnAuditLogs = append(returnAuditLogs, types.AuditLogReturnPayload{
+			ID:         auditLog.ID,
+			AdminID:    auditLog.AdminID,
+			AdminName:  auditLog.AdminName,
+			Action:     utils.AuditActionIntToString(auditLog.Action),
+			TargetType: utils.AuditTargetIntToString(auditLog.TargetType),
+			TargetID:   auditLog.TargetID,
+			Reason:     auditLog.Reason,
+			IPAddress:  auditLog.IPAddress,
+			CreatedAt:  auditLog.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.AuditLogsReturnPayload{
+		Total:     total,
+		Page:      page,
+		PageSize:  pageSize,
+		AuditLogs: returnAuditLogs,
+	})
+}
+
+// reads the acting admin, the target id from the path and the reason from the body,
+// writing the error response itself
+func (h *Handler) parseReasonRequest(w http.ResponseWriter, r *http.Request, target string) (*types.User, int, *types.AdminReasonPayload, bool) {
+	admin, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return nil, 0, nil, false
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid %s id", target))
+		return nil, 0, nil, false
+	}
+
+	var payload types.AdminReasonPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v \n", err)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return nil, 0, nil, false
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return nil, 0, nil, false
+	}
+
+	return admin, id, &payload, true
+}
+
+// the action already happened, so a failed audit write is only logged
+func (h *Handler) recordAuditLog(r *http.Request, admin *types.User, action int, targetType int, targetId int, reason string) {
+	err := h.auditLogStore.CreateAuditLog(types.AuditLog{
+		AdminID:    admin.ID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetId,
+		Reason:     reason,
+		IPAddress:  utils.ClientIP(r),
+	})
+	if err != nil {
+		log.Printf("error create audit log: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("error create audit log of admin %d, action %s on %s %d: %v", admin.ID,
+			utils.AuditActionIntToString(action), utils.AuditTargetIntToString(targetType), targetId, err))
+	}
+}
+
+// looks the user up and notifies them, the body goes in the email and the push alike
+func (h *Handler) notifyUser(userId int, subject string, body string, orderId int) {
+	user, err := h.userStore.GetUserByID(userId)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error get user %d to notify: %v", userId, err))
+		return
+	}
+
+	notification.NotifyUser(h.notifier, user, subject, fmt.Sprintf("<h4>%s</h4>", body), body, orderId)
+}
@@ -0,0 +1,122 @@
+package admin
+
+import (
+	"database/sql"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateAuditLog(auditLog types.AuditLog) error {
+	query := `INSERT INTO admin_audit_log (admin_id, action, target_type, target_id, reason, ip_address)
+				VALUES (?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query, auditLog.AdminID, auditLog.Action, auditLog.TargetType,
+		auditLog.TargetID, auditLog.Reason, auditLog.IPAddress)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// returns the audit logs, newest first. a negative target type returns every
+// target, a zero target id every target of the type
+func (s *Store) GetAuditLogs(targetType int, targetId int, limit int, offset int) ([]types.AuditLog, error) {
+	condition, args := auditLogCondition(targetType, targetId)
+
+	query := `SELECT aal.id, aal.admin_id, u.name, aal.action, aal.target_type,
+					aal.target_id, aal.reason, aal.ip_address, aal.created_at
+				FROM admin_audit_log AS aal
+				LEFT JOIN user AS u ON u.id = aal.admin_id
+				WHERE ` + condition + `
+				ORDER BY aal.created_at DESC, aal.id DESC
+				LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, append(args, limit, offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	auditLogs := make([]types.AuditLog, 0)
+
+	for rows.Next() {
+		auditLog, err := scanRowIntoAuditLog(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		auditLogs = append(auditLogs, *auditLog)
+	}
+
+	return auditLogs, nil
+}
+
+func (s *Store) GetAuditLogCount(targetType int, targetId int) (int, error) {
+	condition, args := auditLogCondition(targetType, targetId)
+
+	query := `SELECT COUNT(*) FROM admin_audit_log AS aal WHERE ` + condition
+
+	var count int
+	err := s.db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func auditLogCondition(targetType int, targetId int) (string, []any) {
+	condition := "1 = 1"
+	args := make([]any, 0)
+
+	if targetType >= 0 {
+		condition += " AND aal.target_type = ?"
+		args = append(args, targetType)
+
+		if targetId > 0 {
+			condition += " AND aal.target_id = ?"
+			args = append(args, targetId)
+		}
+	}
+
+	return condition, args
+}
+
+func scanRowIntoAuditLog(rows *sql.Rows) (*types.AuditLog, error) {
+	auditLog := new(types.AuditLog)
+
+	var adminName sql.NullString
+	var reason sql.NullString
+	var ipAddress sql.NullString
+
+	err := rows.Scan(
+		&auditLog.ID,
+		&auditLog.AdminID,
+		&adminName,
+		&auditLog.Action,
+		&auditLog.TargetType,
+		&auditLog.TargetID,
+		&reason,
+		&ipAddress,
+		&auditLog.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	auditLog.AdminName = adminName.String
+	auditLog.Reason = reason.String
+	auditLog.IPAddress = ipAddress.String
+
+	auditLog.CreatedAt = auditLog.CreatedAt.Local()
+
+	return auditLog, nil
+}
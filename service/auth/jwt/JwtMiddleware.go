@@ -1,43 +1,92 @@
 package jwt
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
 )
 
-func JWTMiddleware() mux.MiddlewareFunc {
+// JWTMiddleware validates the access token against its session once per request
+// and puts the token details and the user in the request context
+func JWTMiddleware(userStore types.UserStore) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			// log.Println("auth middleware ok!")
-
-			// log.Println(r.Header)
+			accessDetails, err := ExtractAccessTokenFromClient(r)
+			if err != nil || accessDetails == nil {
+				log.Printf("token invalid: %v", err)
+				logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+				return
+			}
 
-			token, err := VerifyAccessToken(r)
+			// a revoked or rotated token no longer has its row in verify_token
+			user, err := userStore.GetUserByAccessDetails(accessDetails)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusForbidden)
+				log.Printf("token invalid: %v", err)
+				logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
 				return
 			}
 
-			claims, ok := token.Claims.(jwt.MapClaims)
+			// the role is read from the token so it must still match the stored one,
+			// after a role change the client has to refresh to get a new token
+			if accessDetails.Role != user.Role {
+				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("role changed, refresh the token"))
+				return
+			}
 
-			if ok && token.Valid {
-				_, ok := claims["tokenUuid"].(string)
-				if !ok {
-					http.Error(w, "error token uuid", http.StatusForbidden)
-					return
-				}
+			ctx := context.WithValue(r.Context(), AccessDetailsKey, accessDetails)
+			ctx = context.WithValue(ctx, UserKey, user)
 
-				_, ok = claims["userId"]
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
 
-				if !ok {
-					http.Error(w, "error user id", http.StatusForbidden)
-					return
-				}
+// RequireRoles only lets through users whose token carries one of the roles,
+// it must run after the JWTMiddleware
+func RequireRoles(roles ...int) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			accessDetails, err := GetAccessDetailsFromContext(r.Context())
+			if err != nil {
+				utils.WriteError(w, http.StatusUnauthorized, err)
+				return
+			}
 
-				next.ServeHTTP(w, r)
+			if !slices.Contains(roles, accessDetails.Role) {
+				utils.WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+				return
 			}
+
+			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+// returns the user the JWTMiddleware authenticated
+func GetUserFromContext(ctx context.Context) (*types.User, error) {
+	user, ok := ctx.Value(UserKey).(*types.User)
+	if !ok || user == nil {
+		return nil, fmt.Errorf("no authenticated user")
+	}
+
+	return user, nil
+}
+
+// returns the access token details the JWTMiddleware validated
+func GetAccessDetailsFromContext(ctx context.Context) (*types.AccessDetails, error) {
+	accessDetails, ok := ctx.Value(AccessDetailsKey).(*types.AccessDetails)
+	if !ok || accessDetails == nil {
+		return nil, fmt.Errorf("no authenticated session")
+	}
+
+	return accessDetails, nil
+}
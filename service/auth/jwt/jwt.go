@@ -16,9 +16,10 @@ import (
 
 type contextKey string
 
-const UserKey contextKey = "userID"
+const UserKey contextKey = "user"
+const AccessDetailsKey contextKey = "accessDetails"
 
-func CreateAccessToken(userId int) (*types.TokenDetails, error) {
+func CreateAccessToken(userId int, sessionId int, role int) (*types.TokenDetails, error) {
 	tokenDetails := new(types.TokenDetails)
 
 	tokenExp := time.Second * time.Duration(config.Envs.JWTAccessExpInSeconds)
@@ -38,6 +39,8 @@ func CreateAccessToken(userId int) (*types.TokenDetails, error) {
 		"authorized": true,
 		"tokenUuid":  tokenDetails.UUID,
 		"userId":     userId,
+		"sessionId":  sessionId,
+		"role":       role,
 		"expiredAt":  tokenDetails.TokenExp, // expired of the token
 	})
 	tokenDetails.Token, err = token.SignedString(tokenSecret)
@@ -70,9 +73,23 @@ func ExtractAccessTokenFromClient(r *http.Request) (*types.AccessDetails, error)
 			return nil, err
 		}
 
+		sessionId, err := strconv.Atoi(fmt.Sprintf("%.f", claims["sessionId"]))
+		if err != nil {
+			log.Println("jwt session id error")
+			return nil, err
+		}
+
+		role, err := strconv.Atoi(fmt.Sprintf("%.f", claims["role"]))
+		if err != nil {
+			log.Println("jwt role error")
+			return nil, err
+		}
+
 		return &types.AccessDetails{
-			UUID:   tokenUuid,
-			UserID: userId,
+			UUID:      tokenUuid,
+			UserID:    userId,
+			SessionID: sessionId,
+			Role:      role,
 		}, nil
 	}
 
@@ -114,7 +131,7 @@ func extractToken(r *http.Request) (string, error) {
 	return "", fmt.Errorf("invalid token")
 }
 
-func CreateRefreshToken(userId int) (*types.TokenDetails, error) {
+func CreateRefreshToken(userId int, sessionId int) (*types.TokenDetails, error) {
 	tokenDetails := new(types.TokenDetails)
 
 	tokenExp := time.Second * time.Duration(config.Envs.JWTRefreshExpInSeconds)
@@ -132,6 +149,7 @@ func CreateRefreshToken(userId int) (*types.TokenDetails, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"tokenUuid": tokenDetails.UUID,
 		"userId":    userId,
+		"sessionId": sessionId,
 		"expiredAt": tokenDetails.TokenExp, // expired of the token
 	})
 	tokenDetails.Token, err = token.SignedString(tokenSecret)
@@ -164,9 +182,16 @@ func ExtractRefreshTokenFromClient(refreshToken string) (*types.AccessDetails, e
 			return nil, err
 		}
 
+		sessionId, err := strconv.Atoi(fmt.Sprintf("%.f", claims["sessionId"]))
+		if err != nil {
+			log.Println("jwt session id error")
+			return nil, err
+		}
+
 		return &types.AccessDetails{
-			UUID:   tokenUuid,
-			UserID: userId,
+			UUID:      tokenUuid,
+			UserID:    userId,
+			SessionID: sessionId,
 		}, nil
 	}
 
@@ -0,0 +1,22 @@
+package blob
+
+import (
+	"fmt"
+
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// builds the blob store for the configured storage
+func NewBlobStoreFromConfig() (types.BlobStore, error) {
+	switch config.Envs.BlobStorage {
+	case constants.BLOB_STORAGE_LOCAL:
+		return NewLocalStore(config.Envs.BlobLocalRoot), nil
+	case constants.BLOB_STORAGE_S3:
+		return NewS3Store(config.Envs.S3Endpoint, config.Envs.S3Region, config.Envs.S3Bucket,
+			config.Envs.S3AccessKeyID, config.Envs.S3SecretAccessKey)
+	default:
+		return nil, fmt.Errorf("unknown blob storage: %s", config.Envs.BlobStorage)
+	}
+}
@@ -0,0 +1,176 @@
+package blob
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// LocalStore keeps the blobs as files under a directory, the key being the path below it.
+// only usable when a single server instance runs, or the directory is shared
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) *LocalStore {
+	return &LocalStore{root: root}
+}
+
+func (s *LocalStore) Put(key string, data []byte, contentType string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0744); err != nil {
+		return err
+	}
+
+	// written next to the final file and renamed, so a reader never sees half an image
+	file, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	err = file.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filePath)
+}
+
+func (s *LocalStore) Get(key string) (io.ReadCloser, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, types.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return file, nil
+}
+
+// deleting a missing blob is not an error
+func (s *LocalStore) Delete(key string) error {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(filePath)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *LocalStore) Stat(key string) (*types.BlobInfo, error) {
+	filePath, err := s.filePath(key)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(filePath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, types.ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.IsDir() {
+		return nil, types.ErrBlobNotFound
+	}
+
+	return blobInfo(key, info), nil
+}
+
+func (s *LocalStore) List(prefix string) ([]types.BlobInfo, error) {
+	blobs := make([]types.BlobInfo, 0)
+
+	// only the directory the prefix points into has to be walked
+	dirPath := filepath.Join(s.root, filepath.FromSlash(path.Dir(prefix+"x")))
+
+	err := filepath.WalkDir(dirPath, func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(s.root, filePath)
+		if err != nil {
+			return err
+		}
+
+		key := filepath.ToSlash(relPath)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		blobs = append(blobs, *blobInfo(key, info))
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return blobs, nil
+}
+
+// a key can't leave the root directory
+func (s *LocalStore) filePath(key string) (string, error) {
+	if key == "" || !filepath.IsLocal(filepath.FromSlash(key)) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func blobInfo(key string, info fs.FileInfo) *types.BlobInfo {
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	return &types.BlobInfo{
+		Key:         key,
+		Size:        info.Size(),
+		ContentType: contentType,
+		ModifiedAt:  info.ModTime(),
+	}
+}
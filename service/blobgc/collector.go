@@ -0,0 +1,91 @@
+package blobgc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+// the prefixes the uploads are stored under, nothing else in the blob store is touched
+var collectedKeyPrefixes = []string{
+	constants.PROFILE_IMG_KEY_PREFIX,
+	constants.PACKAGE_IMG_KEY_PREFIX,
+	constants.PAYMENT_PROOF_KEY_PREFIX,
+}
+
+type Collector struct {
+	blobStore      types.BlobStore
+	referenceStore types.BlobReferenceStore
+	gracePeriod    time.Duration
+}
+
+// an orphan younger than gracePeriod is only reported. an upload is stored before
+// the row that points to it is written, so a fresh one may just not be referenced yet
+func NewCollector(blobStore types.BlobStore, referenceStore types.BlobReferenceStore, gracePeriod time.Duration) *Collector {
+	return &Collector{
+		blobStore:      blobStore,
+		referenceStore: referenceStore,
+		gracePeriod:    gracePeriod,
+	}
+}
+
+// the blobs are listed before the references are read, so an upload that gets
+// referenced in between is still seen as referenced.
+// a variant is kept as long as its original is referenced
+func (c *Collector) Collect(dryRun bool) (*types.BlobGCReport, error) {
+	blobs := make([]types.BlobInfo, 0)
+
+	for _, keyPrefix := range collectedKeyPrefixes {
+		listed, err := c.blobStore.List(keyPrefix)
+		if err != nil {
+			return nil, fmt.Errorf("error list %s: %v", keyPrefix, err)
+		}
+
+		blobs = append(blobs, listed...)
+	}
+
+	referencedKeys, err := c.referenceStore.GetReferencedBlobKeys()
+	if err != nil {
+		return nil, fmt.Errorf("error get referenced keys: %v", err)
+	}
+	referencedKeys[constants.DEFAULT_PROFILE_IMG_KEY] = true
+
+	report := &types.BlobGCReport{
+		DryRun:  dryRun,
+		Scanned: len(blobs),
+		Orphans: make([]types.OrphanedBlob, 0),
+	}
+
+	cutoff := time.Now().Add(-c.gracePeriod)
+
+	var errs []error
+
+	for _, blob := range blobs {
+		if referencedKeys[blob.Key] || referencedKeys[utils.ImageOriginalKey(blob.Key)] {
+			continue
+		}
+
+		orphan := types.OrphanedBlob{
+			BlobInfo:      blob,
+			InGracePeriod: blob.ModifiedAt.After(cutoff),
+		}
+
+		if !orphan.InGracePeriod && !dryRun {
+			err := c.blobStore.Delete(blob.Key)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("blob %s: %v", blob.Key, err))
+			} else {
+				orphan.Deleted = true
+				report.DeletedCount++
+			}
+		}
+
+		report.Orphans = append(report.Orphans, orphan)
+	}
+
+	return report, errors.Join(errs...)
+}
@@ -0,0 +1,58 @@
+package blobgc
+
+import (
+	"database/sql"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// the keys of the profile pictures, the images of the orders that aren't deleted,
+// the package images of the delivery requests and the attachments of the outbox
+// messages that can still be sent (a dead one can be replayed by an admin)
+func (s *Store) GetReferencedBlobKeys() (map[string]bool, error) {
+	query := `SELECT profile_picture_url FROM user 
+				WHERE profile_picture_url IS NOT NULL AND profile_picture_url != '' 
+			UNION 
+			SELECT package_img_url FROM order_list 
+				WHERE deleted_at IS NULL 
+				AND package_img_url IS NOT NULL AND package_img_url != '' 
+			UNION 
+			SELECT payment_proof_url FROM order_list 
+				WHERE deleted_at IS NULL 
+				AND payment_proof_url IS NOT NULL AND payment_proof_url != '' 
+			UNION 
+			SELECT package_img_url FROM delivery_request 
+				WHERE package_img_url IS NOT NULL AND package_img_url != '' 
+			UNION 
+			SELECT attachment_url FROM notification_outbox 
+				WHERE status != ? 
+				AND attachment_url IS NOT NULL AND attachment_url != ''`
+	rows, err := s.db.Query(query, constants.OUTBOX_STATUS_SENT)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make(map[string]bool)
+
+	for rows.Next() {
+		var key string
+
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[key] = true
+	}
+
+	return keys, rows.Err()
+}
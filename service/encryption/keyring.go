@@ -0,0 +1,198 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// Keyring does envelope encryption. every record gets a data key of its own that
+// encrypts its fields with AES-256-GCM, and the data key is stored wrapped by one
+// of the master keys, AES-256-GCM too. the master keys only live in the config.
+// rotating means adding a key, making it the current one and re-wrapping the data
+// keys with Rewrap. the old key can be dropped once nothing is wrapped by it anymore
+type Keyring struct {
+	masterKeys   map[string][]byte
+	currentKeyID string
+}
+
+func NewKeyring(masterKeys map[string][]byte, currentKeyID string) (*Keyring, error) {
+	for keyID, key := range masterKeys {
+		if len(key) != 32 {
+			return nil, fmt.Errorf("encryption key %s must be 32 bytes, got %d", keyID, len(key))
+		}
+	}
+
+	if _, ok := masterKeys[currentKeyID]; !ok {
+		return nil, fmt.Errorf("current encryption key %q is not in the keyring", currentKeyID)
+	}
+
+	return &Keyring{
+		masterKeys:   masterKeys,
+		currentKeyID: currentKeyID,
+	}, nil
+}
+
+// BANK_ENCRYPTION_KEYS is a comma separated list of <key id>:<base64 of 32 bytes>,
+// BANK_ENCRYPTION_KEY_ID the one new records are wrapped with
+func NewKeyringFromConfig() (*Keyring, error) {
+	if config.Envs.BankEncryptionKeys == "" {
+		return nil, fmt.Errorf("BANK_ENCRYPTION_KEYS is not set")
+	}
+
+	masterKeys := make(map[string][]byte)
+
+	for _, entry := range strings.Split(config.Envs.BankEncryptionKeys, ",") {
+		keyID, encodedKey, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("invalid encryption key entry, expected <key id>:<base64 key>")
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encodedKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption key %s: %v", keyID, err)
+		}
+
+		masterKeys[keyID] = key
+	}
+
+	return NewKeyring(masterKeys, config.Envs.BankEncryptionKeyID)
+}
+
+func (k *Keyring) CurrentKeyID() string {
+	return k.currentKeyID
+}
+
+// each value is bound to its position, so two fields of a record can't be swapped
+func (k *Keyring) Seal(plaintexts ...string) (*types.SealedFields, error) {
+	dataKey := make([]byte, 32)
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := seal(k.masterKeys[k.currentKeyID], dataKey, []byte(k.currentKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	sealed := &types.SealedFields{
+		KeyID:      k.currentKeyID,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrappedKey),
+		Values:     make([]string, len(plaintexts)),
+	}
+
+	for i, plaintext := range plaintexts {
+		ciphertext, err := seal(dataKey, []byte(plaintext), []byte(strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+
+		sealed.Values[i] = base64.StdEncoding.EncodeToString(ciphertext)
+	}
+
+	return sealed, nil
+}
+
+func (k *Keyring) Open(sealed *types.SealedFields) ([]string, error) {
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintexts := make([]string, len(sealed.Values))
+
+	for i, value := range sealed.Values {
+		ciphertext, err := base64.StdEncoding.DecodeString(value)
+		if err != nil {
+			return nil, err
+		}
+
+		plaintext, err := open(dataKey, ciphertext, []byte(strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+
+		plaintexts[i] = string(plaintext)
+	}
+
+	return plaintexts, nil
+}
+
+func (k *Keyring) Rewrap(sealed *types.SealedFields) (*types.SealedFields, error) {
+	dataKey, err := k.unwrap(sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	wrappedKey, err := seal(k.masterKeys[k.currentKeyID], dataKey, []byte(k.currentKeyID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.SealedFields{
+		KeyID:      k.currentKeyID,
+		WrappedKey: base64.StdEncoding.EncodeToString(wrappedKey),
+		Values:     sealed.Values,
+	}, nil
+}
+
+func (k *Keyring) unwrap(sealed *types.SealedFields) ([]byte, error) {
+	masterKey, ok := k.masterKeys[sealed.KeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", types.ErrUnknownEncryptionKey, sealed.KeyID)
+	}
+
+	wrappedKey, err := base64.StdEncoding.DecodeString(sealed.WrappedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// the key id is the additional data, a data key can't be passed off as wrapped by another key
+	return open(masterKey, wrappedKey, []byte(sealed.KeyID))
+}
+
+// the nonce is put in front of the ciphertext
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, ciphertext []byte, additionalData []byte) ([]byte, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext is too short")
+	}
+
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
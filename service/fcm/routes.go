@@ -0,0 +1,156 @@
+package fcm
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	fcmHistoryStore types.FCMHistoryStore
+}
+
+func NewHandler(fcmHistoryStore types.FCMHistoryStore) *Handler {
+	return &Handler{fcmHistoryStore: fcmHistoryStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/notification", h.handleGetAll).Methods(http.MethodGet)
+	router.HandleFunc("/notification", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/notification/read-all", h.handleMarkAllRead).Methods(http.MethodPatch)
+	router.HandleFunc("/notification/read-all", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/notification/{id}/read", h.handleMarkRead).Methods(http.MethodPatch)
+	router.HandleFunc("/notification/{id}/read", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	unreadOnly := false
+	if unreadOnlyStr := r.URL.Query().Get("unreadOnly"); unreadOnlyStr != "" {
+		var err error
+		unreadOnly, err = strconv.ParseBool(unreadOnlyStr)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid unreadOnly"))
+			return
+		}
+	}
+
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	total, err := h.fcmHistoryStore.GetFCMHistoryCountByUserID(user.ID, unreadOnly)
+	if err != nil {
+		log.Printf("error count notifications: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count notifications: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	unreadCount := total
+	if !unreadOnly {
+		unreadCount, err = h.fcmHistoryStore.GetFCMHistoryCountByUserID(user.ID, true)
+		if err != nil {
+			log.Printf("error count unread notifications: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count unread notifications: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+	}
+
+	fcmHistories, err := h.fcmHistoryStore.GetFCMHistoriesByUserID(user.ID, unreadOnly, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("error get notifications: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get notifications: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	notifications := make([]types.NotificationReturnPayload, 0)
+
+	for _, fcmHistory := range fcmHistories {
+		notifications = append(notifications, types.NotificationReturnPayload{
+			ID:        fcmHistory.ID,
+			Title:     fcmHistory.Title,
+			Body:      fcmHistory.Body,
+			ImageURL:  fcmHistory.ImageURL,
+			Link:      fcmHistory.Link,
+			Data:      fcmHistory.Data,
+			Read:      fcmHistory.ReadAt.Valid,
+			ReadAt:    fcmHistory.ReadAt.Time,
+			CreatedAt: fcmHistory.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.NotificationsReturnPayload{
+		Total:         total,
+		UnreadCount:   unreadCount,
+		Page:          page,
+		PageSize:      pageSize,
+		Notifications: notifications,
+	})
+}
+
+func (h *Handler) handleMarkRead(w http.ResponseWriter, r *http.Request) {
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid notification id"))
+		return
+	}
+
+	found, err := h.fcmHistoryStore.MarkFCMHistoryRead(id, user.ID)
+	if err != nil {
+		log.Printf("error mark notification as read: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error mark notification as read: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !found {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("notification %d not found", id))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "notification marked as read")
+}
+
+func (h *Handler) handleMarkAllRead(w http.ResponseWriter, r *http.Request) {
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	err = h.fcmHistoryStore.MarkAllFCMHistoriesRead(user.ID)
+	if err != nil {
+		log.Printf("error mark all notifications as read: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error mark all notifications as read: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "all notifications marked as read")
+}
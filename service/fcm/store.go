@@ -2,6 +2,8 @@ package fcm
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/nicolaics/jim-carrier-server/types"
 )
@@ -14,14 +16,159 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) CreateFCMHistory(fcmHistory types.FCMHistory) error {
-	query := `INSERT INTO fcm_history (to_user_id, title, body, image_url, link, response) 
-				VALUES (?, ?, ?, ? ,? , ?)`
-	_, err := s.db.Exec(query, fcmHistory.ToUserID, fcmHistory.Title, fcmHistory.Body,
-		fcmHistory.ImageURL, fcmHistory.Link, fcmHistory.Response)
+// stores the push as an inbox item and returns its id
+func (s *Store) CreateFCMHistory(fcmHistory types.FCMHistory) (int, error) {
+	data, err := json.Marshal(fcmHistory.Data)
+	if err != nil {
+		return 0, err
+	}
+
+	query := `INSERT INTO fcm_history (to_user_id, to_token, title, body, image_url, link, data, response) 
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	res, err := s.db.Exec(query, fcmHistory.ToUserID, fcmHistory.ToToken, fcmHistory.Title, fcmHistory.Body,
+		fcmHistory.ImageURL, fcmHistory.Link, string(data), fcmHistory.Response)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *Store) UpdateFCMHistoryResponse(id int, toToken string, response string) error {
+	query := `UPDATE fcm_history SET to_token = ?, response = ? WHERE id = ?`
+
+	_, err := s.db.Exec(query, toToken, response, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// returns the inbox of the user, newest first
+func (s *Store) GetFCMHistoriesByUserID(userId int, unreadOnly bool, limit int, offset int) ([]types.FCMHistory, error) {
+	query := `SELECT id, to_user_id, to_token, title, body, image_url, link, data, response, read_at, created_at 
+				FROM fcm_history 
+				WHERE to_user_id = ? `
+	if unreadOnly {
+		query += `AND read_at IS NULL `
+	}
+	query += `ORDER BY created_at DESC, id DESC 
+				LIMIT ? OFFSET ?`
+
+	rows, err := s.db.Query(query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fcmHistories := make([]types.FCMHistory, 0)
+
+	for rows.Next() {
+		fcmHistory, err := scanRowIntoFCMHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		fcmHistories = append(fcmHistories, *fcmHistory)
+	}
+
+	return fcmHistories, nil
+}
+
+func (s *Store) GetFCMHistoryCountByUserID(userId int, unreadOnly bool) (int, error) {
+	query := `SELECT COUNT(*) FROM fcm_history WHERE to_user_id = ?`
+	if unreadOnly {
+		query += ` AND read_at IS NULL`
+	}
+
+	var count int
+	err := s.db.QueryRow(query, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// returns false if the user has no such notification
+func (s *Store) MarkFCMHistoryRead(id int, userId int) (bool, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM fcm_history WHERE id = ? AND to_user_id = ?`, id, userId).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	if count == 0 {
+		return false, nil
+	}
+
+	query := `UPDATE fcm_history SET read_at = ? WHERE id = ? AND to_user_id = ? AND read_at IS NULL`
+
+	_, err = s.db.Exec(query, time.Now(), id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) MarkAllFCMHistoriesRead(userId int) error {
+	query := `UPDATE fcm_history SET read_at = ? WHERE to_user_id = ? AND read_at IS NULL`
+
+	_, err := s.db.Exec(query, time.Now(), userId)
 	if err != nil {
 		return err
 	}
 
 	return nil
 }
+
+func scanRowIntoFCMHistory(rows *sql.Rows) (*types.FCMHistory, error) {
+	fcmHistory := new(types.FCMHistory)
+
+	var toToken sql.NullString
+	var imageUrl sql.NullString
+	var link sql.NullString
+	var data sql.NullString
+	var response sql.NullString
+
+	err := rows.Scan(
+		&fcmHistory.ID,
+		&fcmHistory.ToUserID,
+		&toToken,
+		&fcmHistory.Title,
+		&fcmHistory.Body,
+		&imageUrl,
+		&link,
+		&data,
+		&response,
+		&fcmHistory.ReadAt,
+		&fcmHistory.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	fcmHistory.ToToken = toToken.String
+	fcmHistory.ImageURL = imageUrl.String
+	fcmHistory.Link = link.String
+	fcmHistory.Response = response.String
+
+	if data.Valid && data.String != "" {
+		err = json.Unmarshal([]byte(data.String), &fcmHistory.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	fcmHistory.ReadAt.Time = fcmHistory.ReadAt.Time.Local()
+	fcmHistory.CreatedAt = fcmHistory.CreatedAt.Local()
+
+	return fcmHistory, nil
+}
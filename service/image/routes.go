@@ -0,0 +1,114 @@
+package image
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	orderStore   types.OrderStore
+	requestStore types.DeliveryRequestStore
+	blobStore    types.BlobStore
+}
+
+func NewHandler(orderStore types.OrderStore, requestStore types.DeliveryRequestStore, blobStore types.BlobStore) *Handler {
+	return &Handler{
+		orderStore:   orderStore,
+		requestStore: requestStore,
+		blobStore:    blobStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/image/{kind}/{name}", h.handleGetImage).Methods(http.MethodGet)
+	router.HandleFunc("/image/{kind}/{name}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+// serves the files behind the URLs the payloads return. profile pictures are
+// public to every user, package images and payment proofs only to the people
+// of the order or delivery request. an image the user can't see is not found,
+// so its name doesn't tell whether it exists.
+// ?size=medium or ?size=thumb gives the smaller variant of the image
+func (h *Handler) handleGetImage(w http.ResponseWriter, r *http.Request) {
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	kind := vars["kind"]
+
+	imageKey, err := utils.ImageKey(kind, vars["name"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	servedKey := imageKey
+	if size := r.URL.Query().Get("size"); size != "" {
+		if !utils.IsImageVariant(size) {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown image size"))
+			return
+		}
+
+		servedKey = utils.ImageVariantKey(imageKey, size)
+	}
+
+	visible, err := h.isVisibleTo(user, kind, imageKey)
+	if err != nil {
+		log.Printf("error check image access: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error check image access: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !visible {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("image not found"))
+		return
+	}
+
+	err = utils.ServeImage(w, r, h.blobStore, servedKey)
+	if errors.Is(err, types.ErrBlobNotFound) && servedKey != imageKey {
+		// uploaded before the variants were made
+		err = utils.ServeImage(w, r, h.blobStore, imageKey)
+	}
+	if errors.Is(err, types.ErrBlobNotFound) {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("image not found"))
+		return
+	}
+	if err != nil {
+		log.Printf("error serve image: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error serve image: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+}
+
+func (h *Handler) isVisibleTo(user *types.User, kind string, imageKey string) (bool, error) {
+	switch kind {
+	case constants.IMAGE_KIND_PROFILE_STR:
+		return true, nil
+	case constants.IMAGE_KIND_PACKAGE_STR:
+		visible, err := h.orderStore.IsOrderImageVisibleTo(user.ID, imageKey)
+		if err != nil || visible {
+			return visible, err
+		}
+
+		return h.requestStore.IsPackageImageVisibleTo(user.ID, imageKey)
+	case constants.IMAGE_KIND_PAYMENT_PROOF_STR:
+		return h.orderStore.IsOrderImageVisibleTo(user.ID, imageKey)
+	}
+
+	return false, nil
+}
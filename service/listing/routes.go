@@ -1,40 +1,51 @@
 package listing
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/gorilla/mux"
 	"github.com/nicolaics/jim-carrier-server/constants"
 	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
 	"github.com/nicolaics/jim-carrier-server/types"
 	"github.com/nicolaics/jim-carrier-server/utils"
 )
 
 type Handler struct {
-	listingStore    types.ListingStore
-	userStore       types.UserStore
-	currencyStore   types.CurrencyStore
-	reviewStore     types.ReviewStore
-	bankDetailStore types.BankDetailStore
-	orderStore      types.OrderStore
-	fcmHistoryStore types.FCMHistoryStore
+	listingStore      types.ListingStore
+	userStore         types.UserStore
+	currencyStore     types.CurrencyStore
+	reviewStore       types.ReviewStore
+	payoutMethodStore types.PayoutMethodStore
+	orderStore        types.OrderStore
+	notifier          types.Notifier
+	orderUnitOfWork   types.OrderUnitOfWork
+
+	savedSearchAlerter types.SavedSearchAlerter
 }
 
 func NewHandler(listingStore types.ListingStore, userStore types.UserStore,
 	currencyStore types.CurrencyStore, reviewStore types.ReviewStore,
-	bankDetailStore types.BankDetailStore, orderStore types.OrderStore,
-	fcmHistoryStore types.FCMHistoryStore) *Handler {
+	payoutMethodStore types.PayoutMethodStore, orderStore types.OrderStore,
+	notifier types.Notifier, orderUnitOfWork types.OrderUnitOfWork,
+	savedSearchAlerter types.SavedSearchAlerter) *Handler {
 	return &Handler{
-		listingStore:    listingStore,
-		userStore:       userStore,
-		currencyStore:   currencyStore,
-		reviewStore:     reviewStore,
-		bankDetailStore: bankDetailStore,
-		orderStore:      orderStore,
-		fcmHistoryStore: fcmHistoryStore,
+		listingStore:      listingStore,
+		userStore:         userStore,
+		currencyStore:     currencyStore,
+		reviewStore:       reviewStore,
+		payoutMethodStore: payoutMethodStore,
+		orderStore:        orderStore,
+		notifier:          notifier,
+		orderUnitOfWork:   orderUnitOfWork,
+
+		savedSearchAlerter: savedSearchAlerter,
 	}
 }
 
@@ -42,12 +53,13 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/listing", h.handlePost).Methods(http.MethodPost)
 	router.HandleFunc("/listing", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
 
+	// before {reqType}, which would take the id as an unknown request parameter
+	router.HandleFunc("/listing/{id:[0-9]+}", h.handleGetDetail).Methods(http.MethodGet)
+	router.HandleFunc("/listing/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
 	router.HandleFunc("/listing/{reqType}", h.handleGetAll).Methods(http.MethodGet)
 	router.HandleFunc("/listing/{reqType}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
 
-	// router.HandleFunc("/listing/detail", h.handleGetDetail).Methods(http.MethodPost)
-	// router.HandleFunc("/listing/detail", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
-
 	router.HandleFunc("/listing", h.handleDelete).Methods(http.MethodDelete)
 
 	router.HandleFunc("/listing", h.handleModify).Methods(http.MethodPatch)
@@ -69,6 +81,8 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	normalizeListingRoute(&payload.ListingRoutePayload)
+
 	// validate the payload
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -78,24 +92,18 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// validate token
-	carrier, err := h.userStore.ValidateUserAccessToken(w, r)
+	err := validateListingRoute(payload.ListingRoutePayload)
 	if err != nil {
-		log.Printf("invalid token: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("post listing invalid token: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	carrier, err = h.userStore.GetUserByID(carrier.ID)
-	if carrier == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user not found"))
-		return
-	}
+	destination := listingDestination(payload.Destination, payload.ListingRoutePayload)
+
+	// validate token
+	carrier, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("get user at listing: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("get user at listing: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -111,7 +119,7 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isDuplicate, err := h.listingStore.IsListingDuplicate(carrier.ID, payload.Destination, payload.WeightAvailable, *departureDate)
+	isDuplicate, err := h.listingStore.IsListingDuplicate(carrier.ID, destination, payload.WeightAvailable, *departureDate)
 	if err != nil || isDuplicate {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing with the same information exists already"))
 		return
@@ -141,16 +149,21 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	err = h.listingStore.CreateListing(types.Listing{
-		CarrierID:        carrier.ID,
-		Destination:      payload.Destination,
-		WeightAvailable:  payload.WeightAvailable,
-		PricePerKg:       payload.PricePerKg,
-		CurrencyID:       currency.ID,
-		DepartureDate:    *departureDate,
-		LastReceivedDate: *lastReceivedDate,
-		ExpStatus:        constants.EXP_STATUS_AVAILABLE,
-		Description:      payload.Description,
+	listingId, err := h.listingStore.CreateListing(types.Listing{
+		CarrierID:              carrier.ID,
+		Destination:            destination,
+		OriginCity:             payload.OriginCity,
+		OriginCountryCode:      payload.OriginCountryCode,
+		DestinationCity:        payload.DestinationCity,
+		DestinationCountryCode: payload.DestinationCountryCode,
+		Stops:                  listingStops(payload.Stops),
+		WeightAvailable:        payload.WeightAvailable,
+		PricePerKg:             payload.PricePerKg,
+		CurrencyID:             currency.ID,
+		DepartureDate:          *departureDate,
+		LastReceivedDate:       *lastReceivedDate,
+		ExpStatus:              constants.EXP_STATUS_AVAILABLE,
+		Description:            payload.Description,
 	})
 	if err != nil {
 		log.Printf("error create listing: %v", err)
@@ -159,24 +172,31 @@ func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	h.savedSearchAlerter.AlertNewListing(listingId)
+
 	utils.WriteJSON(w, http.StatusCreated, "listing successfully created")
 }
 
 func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
+	vars := mux.Vars(r)
+	reqType := vars["reqType"]
+
+	if reqType == "all" {
+		h.handleSearch(w, r, user)
+		return
+	} else if reqType != "carrier" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown request parameter"))
 		return
 	}
+
+	listings, err := h.listingStore.GetListingsByCarrierID(user.ID)
 	if err != nil {
 		log.Println(err)
 		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
@@ -184,126 +204,340 @@ func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	vars := mux.Vars(r)
-	reqType := vars["reqType"]
+	avgRating, err := h.reviewStore.GetAverageRating(user.ID, constants.REVIEW_GIVER_TO_CARRIER)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
 
-	var listings []types.ListingReturnFromDB
+	for i := range listings {
+		listings[i].CarrierRating = avgRating
+	}
 
-	if reqType == "all" {
-		listings, err = h.listingStore.GetAllListings(user.ID)
-		if err != nil {
-			log.Println(err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
-	} else if reqType == "carrier" {
-		listings, err = h.listingStore.GetListingsByCarrierID(user.ID)
-		if err != nil {
-			log.Println(err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
-	} else {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown request parameter"))
+	response, ok := h.buildListingReturnPayloads(w, listings)
+	if !ok {
 		return
 	}
 
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// returns a page of the other carriers' available listings matching the query params
+func (h *Handler) handleSearch(w http.ResponseWriter, r *http.Request, user *types.User) {
+	filter, err := parseListingSearchFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	total, err := h.listingStore.GetListingCountBySearch(user.ID, *filter)
+	if err != nil {
+		log.Printf("error count listings: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count listings: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	listings, err := h.listingStore.SearchListings(user.ID, *filter)
+	if err != nil {
+		log.Printf("error search listings: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error search listings: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	response, ok := h.buildListingReturnPayloads(w, listings)
+	if !ok {
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.ListingsReturnPayload{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		Listings: response,
+	})
+}
+
+// adds the carrier's picture to the listings, writing the error response itself.
+// a carrier usually has several listings, so each one is only looked up once.
+// the contact and payout details are left out, GET /listing/{id} only shows them
+// to the givers the carrier agreed to deliver for
+func (h *Handler) buildListingReturnPayloads(w http.ResponseWriter, listings []types.ListingReturnFromDB) ([]types.ListingReturnPayload, bool) {
+	listingIds := make([]int, 0, len(listings))
+	for _, listing := range listings {
+		listingIds = append(listingIds, listing.ID)
+	}
+
+	stops, err := h.listingStore.GetListingStopsByListingIDs(listingIds)
+	if err != nil {
+		log.Printf("error get listing stops: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get listing stops: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return nil, false
+	}
+
+	profilePictureURLs := make(map[int]string)
 	response := make([]types.ListingReturnPayload, 0)
 
 	for _, listing := range listings {
-		avgRating, err := h.reviewStore.GetAverageRating(listing.CarrierID, constants.REVIEW_GIVER_TO_CARRIER)
-		if err != nil {
-			log.Println(err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
+		profilePictureURL, ok := profilePictureURLs[listing.CarrierID]
+		if !ok {
+			carrier, err := h.userStore.GetUserByID(listing.CarrierID)
+			if err != nil {
+				log.Printf("carrier %d not found: %v", listing.CarrierID, err)
+				logFile, _ := logger.WriteServerLog(fmt.Sprintf("carrier %d not found: %v", listing.CarrierID, err))
+				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+				return nil, false
+			}
+
+			profilePictureURL = utils.ImageURL(carrier.ProfilePictureURL)
+			profilePictureURLs[listing.CarrierID] = profilePictureURL
 		}
 
-		carrier, err := h.userStore.GetUserByID(listing.CarrierID)
-		if err != nil {
-			log.Printf("carrier %d not found: %v", listing.CarrierID, err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("carrier %d not found: %v", listing.CarrierID, err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
+		expStatus := utils.ExpStatusIntToString(listing.ExpStatus)
+
+		returnStops := make([]types.ListingStopPayload, 0)
+		for _, stop := range stops[listing.ID] {
+			returnStops = append(returnStops, types.ListingStopPayload{
+				City:        stop.City,
+				CountryCode: stop.CountryCode,
+			})
 		}
 
-		imageBytes, err := utils.GetImage(carrier.ProfilePictureURL)
+		response = append(response, types.ListingReturnPayload{
+			ID:                       listing.ID,
+			CarrierID:                listing.CarrierID,
+			CarrierName:              listing.CarrierName,
+			CarrierProfilePictureURL: profilePictureURL,
+			Destination:              listing.Destination,
+			OriginCity:               listing.OriginCity,
+			OriginCountryCode:        listing.OriginCountryCode,
+			DestinationCity:          listing.DestinationCity,
+			DestinationCountryCode:   listing.DestinationCountryCode,
+			Stops:                    returnStops,
+			WeightAvailable:          listing.WeightAvailable,
+			PricePerKg:               listing.PricePerKg,
+			Currency:                 listing.Currency,
+			DepartureDate:            listing.DepartureDate,
+			LastReceivedDate:         listing.LastReceivedDate,
+			ExpStatus:                expStatus,
+			Description:              listing.Description.String,
+			CarrierRating:            listing.CarrierRating,
+			LastModifiedAt:           listing.LastModifiedAt,
+		})
+	}
+
+	return response, true
+}
+
+// the masked payout method the carrier gets paid with in the currency, nil when there is none
+func (h *Handler) getPayoutMethodPayload(carrierId int, currency string) (*types.PayoutMethodReturnPayload, error) {
+	payoutMethod, err := h.payoutMethodStore.GetPayoutMethodForCurrency(carrierId, currency)
+	if payoutMethod == nil || err != nil {
+		return nil, err
+	}
+
+	payload := utils.PayoutMethodPayload(*payoutMethod, false)
+	return &payload, nil
+}
+
+// reads the search query params, dates use the same format as the payloads
+func parseListingSearchFilter(r *http.Request) (*types.ListingSearchFilter, error) {
+	query := r.URL.Query()
+
+	filter := types.ListingSearchFilter{
+		Destination:            strings.TrimSpace(query.Get("destination")),
+		OriginCity:             strings.TrimSpace(query.Get("originCity")),
+		OriginCountryCode:      strings.ToUpper(strings.TrimSpace(query.Get("originCountry"))),
+		DestinationCity:        strings.TrimSpace(query.Get("destinationCity")),
+		DestinationCountryCode: strings.ToUpper(strings.TrimSpace(query.Get("destinationCountry"))),
+		ViaCity:                strings.TrimSpace(query.Get("via")),
+		Currency:               strings.ToUpper(strings.TrimSpace(query.Get("currency"))),
+		Sort:                   query.Get("sort"),
+	}
+
+	if filter.OriginCountryCode != "" && utils.Validate.Var(filter.OriginCountryCode, "iso3166_1_alpha2") != nil {
+		return nil, fmt.Errorf("invalid originCountry")
+	}
+
+	if filter.DestinationCountryCode != "" && utils.Validate.Var(filter.DestinationCountryCode, "iso3166_1_alpha2") != nil {
+		return nil, fmt.Errorf("invalid destinationCountry")
+	}
+
+	if filter.Sort == "" {
+		filter.Sort = constants.LISTING_SORT_DEPARTURE_STR
+	} else if !isListingSortValid(filter.Sort) {
+		return nil, fmt.Errorf("unknown sort")
+	}
+
+	if departureFrom := query.Get("departureFrom"); departureFrom != "" {
+		date, err := utils.ParseStartDate(departureFrom)
 		if err != nil {
-			log.Printf("error fetching profile picture for %d: %v", listing.CarrierID, err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error fetching profile picture for %d: %v", listing.CarrierID, err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
+			return nil, fmt.Errorf("invalid departureFrom")
 		}
 
-		bankDetail, err := h.bankDetailStore.GetBankDataOfUser(carrier.ID)
+		filter.DepartureFrom = *date
+	}
+
+	if departureTo := query.Get("departureTo"); departureTo != "" {
+		date, err := utils.ParseEndDate(departureTo)
 		if err != nil {
-			log.Printf("error fetching bank data for %d: %v", listing.CarrierID, err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error fetching bank data for %d: %v", listing.CarrierID, err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
+			return nil, fmt.Errorf("invalid departureTo")
 		}
 
-		expStatus := utils.ExpStatusIntToString(listing.ExpStatus)
+		filter.DepartureTo = *date
+	}
 
-		response = append(response, types.ListingReturnPayload{
-			ID:                    listing.ID,
-			CarrierID:             listing.CarrierID,
-			CarrierName:           listing.CarrierName,
-			CarrierEmail:          listing.CarrierEmail,
-			CarrierProfilePicture: imageBytes,
-			Destination:           listing.Destination,
-			WeightAvailable:       listing.WeightAvailable,
-			PricePerKg:            listing.PricePerKg,
-			Currency:              listing.Currency,
-			DepartureDate:         listing.DepartureDate,
-			LastReceivedDate:      listing.LastReceivedDate,
-			ExpStatus:             expStatus,
-			Description:           listing.Description.String,
-			CarrierRating:         avgRating,
-			LastModifiedAt:        listing.LastModifiedAt,
-			BankDetail:            *bankDetail,
+	var err error
+
+	filter.MinWeightAvailable, err = parseNonNegativeFloat(query.Get("minWeight"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid minWeight")
+	}
+
+	filter.MaxPricePerKg, err = parseNonNegativeFloat(query.Get("maxPricePerKg"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid maxPricePerKg")
+	}
+
+	filter.MinCarrierRating, err = parseNonNegativeFloat(query.Get("minRating"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid minRating")
+	}
+
+	return &filter, nil
+}
+
+// trims the cities and upper-cases the country codes, so they validate and compare the same
+func normalizeListingRoute(route *types.ListingRoutePayload) {
+	route.OriginCity = strings.TrimSpace(route.OriginCity)
+	route.OriginCountryCode = strings.ToUpper(strings.TrimSpace(route.OriginCountryCode))
+	route.DestinationCity = strings.TrimSpace(route.DestinationCity)
+	route.DestinationCountryCode = strings.ToUpper(strings.TrimSpace(route.DestinationCountryCode))
+
+	for i := range route.Stops {
+		route.Stops[i].City = strings.TrimSpace(route.Stops[i].City)
+		route.Stops[i].CountryCode = strings.ToUpper(strings.TrimSpace(route.Stops[i].CountryCode))
+	}
+}
+
+// the struct tags only check each field, a route also has to lead somewhere else
+func validateListingRoute(route types.ListingRoutePayload) error {
+	if strings.EqualFold(route.OriginCity, route.DestinationCity) && route.OriginCountryCode == route.DestinationCountryCode {
+		return fmt.Errorf("origin and destination must be different")
+	}
+
+	for _, stop := range route.Stops {
+		if (strings.EqualFold(stop.City, route.OriginCity) && stop.CountryCode == route.OriginCountryCode) ||
+			(strings.EqualFold(stop.City, route.DestinationCity) && stop.CountryCode == route.DestinationCountryCode) {
+			return fmt.Errorf("stop %s is already the origin or destination", stop.City)
+		}
+	}
+
+	return nil
+}
+
+// the free text destination is kept for display, it defaults to the normalized one
+func listingDestination(destination string, route types.ListingRoutePayload) string {
+	destination = strings.TrimSpace(destination)
+	if destination == "" {
+		destination = fmt.Sprintf("%s, %s", route.DestinationCity, route.DestinationCountryCode)
+	}
+
+	return destination
+}
+
+func listingStops(stopPayloads []types.ListingStopPayload) []types.ListingStop {
+	stops := make([]types.ListingStop, 0)
+
+	for _, stop := range stopPayloads {
+		stops = append(stops, types.ListingStop{
+			City:        stop.City,
+			CountryCode: stop.CountryCode,
 		})
 	}
 
-	utils.WriteJSON(w, http.StatusOK, response)
+	return stops
+}
+
+// an empty value is 0, which is not filtered on
+func parseNonNegativeFloat(value string) (float64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid value")
+	}
+
+	return f, nil
 }
 
-/*
 func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
-	var payload types.GetListingDetailPayload
+	vars := mux.Vars(r)
+	listingId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid listing id"))
+		return
+	}
 
-	if err := utils.ParseJSON(r, &payload); err != nil {
-		log.Printf("payload error: %v \n", err)
-		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	// validate the payload
-	if err := utils.Validate.Struct(payload); err != nil {
-		errors := err.(validator.ValidationErrors)
-		log.Printf("invalid payload: %v", errors)
-		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+	listing, err := h.listingStore.GetListingByID(listingId)
+	if errors.Is(err, types.ErrListingNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if err != nil {
+		log.Printf("error getting listing %d: %v", listingId, err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error getting listing %d: %v", listingId, err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
 
-	// validate token
-	_, err := h.userStore.ValidateUserAccessToken(w, r)
+	carrier, err := h.userStore.GetUserByID(listing.CarrierID)
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		log.Printf("carrier %d not found: %v", listing.CarrierID, err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("carrier %d not found: %v", listing.CarrierID, err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
 
-	listing, err := h.listingStore.GetListingByID(payload.ID)
-	if listing == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing not found"))
+	stops, err := h.listingStore.GetListingStopsByListingIDs([]int{listing.ID})
+	if err != nil {
+		log.Printf("error get listing stops: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get listing stops: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
+
+	orderCount, err := h.orderStore.GetOrderCountByListingID(listing.ID)
+	if err != nil {
+		log.Printf("error count orders of listing %d: %v", listing.ID, err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count orders of listing %d: %v", listing.ID, err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	rating, err := h.reviewStore.GetAverageRating(carrier.ID, constants.REVIEW_GIVER_TO_CARRIER)
 	if err != nil {
 		log.Println(err)
 		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
@@ -311,7 +545,7 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	avgRating, err := h.reviewStore.GetAverageRating(listing.CarrierID, constants.REVIEW_GIVER_TO_CARRIER)
+	reviewCount, err := h.reviewStore.GetReviewCount(carrier.ID, constants.REVIEW_GIVER_TO_CARRIER)
 	if err != nil {
 		log.Println(err)
 		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
@@ -319,11 +553,72 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	listing.CarrierRating = avgRating
+	// the contact and payout details are only for the people the carrier agreed to deliver for
+	contactRevealed := (user.ID == carrier.ID)
+	if !contactRevealed {
+		contactRevealed, err = h.orderStore.IsOrderConfirmed(user.ID, listing.ID)
+		if err != nil {
+			log.Println(err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+	}
+
+	carrierProfile := types.CarrierProfileReturnPayload{
+		ID:                carrier.ID,
+		Name:              carrier.Name,
+		ProfilePictureURL: utils.ImageURL(carrier.ProfilePictureURL),
+		Rating:            rating,
+		ReviewCount:       reviewCount,
+		MemberSince:       carrier.CreatedAt,
+	}
+
+	var payoutMethod *types.PayoutMethodReturnPayload
 
-	utils.WriteJSON(w, http.StatusOK, listing)
+	if contactRevealed {
+		carrierProfile.Email = carrier.Email
+		carrierProfile.PhoneNumber = carrier.PhoneNumber
+
+		payoutMethod, err = h.getPayoutMethodPayload(carrier.ID, listing.Currency)
+		if err != nil {
+			log.Printf("error fetching payout method for %d: %v", carrier.ID, err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error fetching payout method for %d: %v", carrier.ID, err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+	}
+
+	returnStops := make([]types.ListingStopPayload, 0)
+	for _, stop := range stops[listing.ID] {
+		returnStops = append(returnStops, types.ListingStopPayload{
+			City:        stop.City,
+			CountryCode: stop.CountryCode,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.ListingDetailReturnPayload{
+		ID:                     listing.ID,
+		Destination:            listing.Destination,
+		OriginCity:             listing.OriginCity,
+		OriginCountryCode:      listing.OriginCountryCode,
+		DestinationCity:        listing.DestinationCity,
+		DestinationCountryCode: listing.DestinationCountryCode,
+		Stops:                  returnStops,
+		WeightAvailable:        listing.WeightAvailable,
+		PricePerKg:             listing.PricePerKg,
+		Currency:               listing.Currency,
+		DepartureDate:          listing.DepartureDate,
+		LastReceivedDate:       listing.LastReceivedDate,
+		ExpStatus:              utils.ExpStatusIntToString(listing.ExpStatus),
+		Description:            listing.Description.String,
+		OrderCount:             orderCount,
+		LastModifiedAt:         listing.LastModifiedAt,
+		Carrier:                carrierProfile,
+		ContactRevealed:        contactRevealed,
+		PayoutMethod:           payoutMethod,
+	})
 }
-*/
 
 func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	var payload types.DeleteListingPayload
@@ -345,11 +640,9 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -404,6 +697,8 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	normalizeListingRoute(&payload.ListingRoutePayload)
+
 	// validate the payload
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -413,12 +708,16 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	err := validateListingRoute(payload.ListingRoutePayload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -439,6 +738,11 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if listing.ExpStatus == constants.EXP_STATUS_RESERVED {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing is reserved for a delivery request"))
+		return
+	}
+
 	newDepartureDate, err := utils.ParseDate(payload.DepartureDate)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error parsing date"))
@@ -478,14 +782,19 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 	}
 
 	err = h.listingStore.ModifyListing(listing.ID, types.Listing{
-		Destination:      payload.Destination,
-		WeightAvailable:  payload.WeightAvailable,
-		PricePerKg:       payload.PricePerKg,
-		CurrencyID:       currency.ID,
-		DepartureDate:    *newDepartureDate,
-		LastReceivedDate: *newLastReceivedDate,
-		ExpStatus:        constants.EXP_STATUS_AVAILABLE,
-		Description:      payload.Description,
+		Destination:            listingDestination(payload.Destination, payload.ListingRoutePayload),
+		OriginCity:             payload.OriginCity,
+		OriginCountryCode:      payload.OriginCountryCode,
+		DestinationCity:        payload.DestinationCity,
+		DestinationCountryCode: payload.DestinationCountryCode,
+		Stops:                  listingStops(payload.Stops),
+		WeightAvailable:        payload.WeightAvailable,
+		PricePerKg:             payload.PricePerKg,
+		CurrencyID:             currency.ID,
+		DepartureDate:          *newDepartureDate,
+		LastReceivedDate:       *newLastReceivedDate,
+		ExpStatus:              constants.EXP_STATUS_AVAILABLE,
+		Description:            payload.Description,
 	})
 	if err != nil {
 		log.Printf("error modify listing: %v", err)
@@ -516,11 +825,9 @@ func (h *Handler) handleUpdatePackageLocation(w http.ResponseWriter, r *http.Req
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -552,6 +859,16 @@ func (h *Handler) handleUpdatePackageLocation(w http.ResponseWriter, r *http.Req
 	subject := "Your Package Location is Updated"
 
 	for _, order := range orders {
+		// only orders the carrier is currently carrying get the new location
+		err = h.orderUnitOfWork.UpdatePackageLocation(order.ID, constants.ACTOR_CARRIER, payload.PackageLocation, payload.Note)
+		if errors.Is(err, types.ErrIllegalTransition) {
+			continue
+		}
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error update package location of order %d: %v", order.ID, err))
+			continue
+		}
+
 		giver, err := h.userStore.GetUserByEmail(order.GiverEmail)
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error finding account of %s for updating package location: %v", order.GiverEmail, err))
@@ -560,30 +877,28 @@ func (h *Handler) handleUpdatePackageLocation(w http.ResponseWriter, r *http.Req
 
 		body := fmt.Sprintf("<h4>Your package for order no. %d has an update!</h4><h4>It status now is</h4><br><h2>%s</h2>",
 			order.ID, payload.PackageLocation)
-		err = utils.SendEmail(giver.Email, subject, body, "", "")
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+			To:      giver.Email,
+			Title:   subject,
+			Body:    body,
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error sending email to %s for updating package location: %v", order.GiverEmail, err))
 		}
 
-		fcmHistory := types.FCMHistory{
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
 			ToUserID: giver.ID,
-			ToToken:  giver.FCMToken,
 			Data: types.FCMData{
 				Type:    "order_updated",
 				OrderID: fmt.Sprintf("%d", order.ID),
 			},
 			Title: subject,
 			Body:  fmt.Sprintf("Package location for order no. %d is %s", order.ID, payload.PackageLocation),
-		}
-
-		fcmHistory.Response, err = utils.SendFCMToOne(fcmHistory)
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
-		} else {
-			err = h.fcmHistoryStore.CreateFCMHistory(fcmHistory)
-			if err != nil {
-				logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
-			}
 		}
 	}
 
@@ -610,11 +925,9 @@ func (h *Handler) handleCountOrdersForOneListing(w http.ResponseWriter, r *http.
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -3,9 +3,11 @@ package listing
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
 	"github.com/nicolaics/jim-carrier-server/types"
 )
 
@@ -17,36 +19,66 @@ func NewStore(db *sql.DB) *Store {
 	return &Store{db: db}
 }
 
-func (s *Store) CreateListing(listing types.Listing) error {
+// creates the listing with its stops, returns the new listing id
+func (s *Store) CreateListing(listing types.Listing) (int, error) {
+	var id int
+
+	err := db.RunInTx(s.db, func(tx *sql.Tx) error {
+		var err error
+		id, err = s.CreateListingTx(tx, listing)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+// inserts the listing with its stops inside the caller's transaction
+func (s *Store) CreateListingTx(tx *sql.Tx, listing types.Listing) (int, error) {
 	values := "?"
-	for i := 0; i < 8; i++ {
+	for i := 0; i < 12; i++ {
 		values += ", ?"
 	}
 
 	query := `INSERT INTO listing (
-					carrier_id, destination, weight_available, 
-					price_per_kg, currency_id, departure_date, 
-					last_received_date, exp_status, description) 
+					carrier_id, destination, 
+					origin_city, origin_country_code, 
+					destination_city, destination_country_code, 
+					weight_available, price_per_kg, currency_id, 
+					departure_date, last_received_date, 
+					exp_status, description) 
 					VALUES (` + values + `)`
 
-	_, err := s.db.Exec(query, listing.CarrierID, listing.Destination, listing.WeightAvailable,
-		listing.PricePerKg, listing.CurrencyID, listing.DepartureDate,
-		listing.LastReceivedDate, listing.ExpStatus, listing.Description)
+	res, err := tx.Exec(query, listing.CarrierID, listing.Destination,
+		listing.OriginCity, listing.OriginCountryCode,
+		listing.DestinationCity, listing.DestinationCountryCode,
+		listing.WeightAvailable, listing.PricePerKg, listing.CurrencyID,
+		listing.DepartureDate, listing.LastReceivedDate,
+		listing.ExpStatus, listing.Description)
 	if err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
-}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
 
-func (s *Store) GetAllListings(carrierId int) ([]types.ListingReturnFromDB, error) {
-	err := s.UpdateListingExpStatus()
+	err = createListingStops(tx, int(id), listing.Stops)
 	if err != nil {
-		return nil, fmt.Errorf("error updating listing status: %v", err)
+		return 0, err
 	}
 
+	return int(id), nil
+}
+
+func (s *Store) GetAllListings(carrierId int) ([]types.ListingReturnFromDB, error) {
 	query := `SELECT l.id, l.carrier_id, user.name, user.email, 
 					l.destination, 
+					l.origin_city, l.origin_country_code, 
+					l.destination_city, l.destination_country_code, 
 					l.weight_available, l.price_per_kg, 
 					c.name, 
 					l.departure_date, 
@@ -80,14 +112,188 @@ func (s *Store) GetAllListings(carrierId int) ([]types.ListingReturnFromDB, erro
 	return listings, nil
 }
 
-func (s *Store) GetListingsByCarrierID(carrierId int) ([]types.ListingReturnFromDB, error) {
-	err := s.UpdateListingExpStatus()
+// the ORDER BY of every sort a search accepts, the id keeps pages stable
+var listingSortOrders = map[string]string{
+	constants.LISTING_SORT_DEPARTURE_STR:      "l.departure_date ASC, l.id ASC",
+	constants.LISTING_SORT_DEPARTURE_DESC_STR: "l.departure_date DESC, l.id DESC",
+	constants.LISTING_SORT_PRICE_STR:          "l.price_per_kg ASC, l.id ASC",
+	constants.LISTING_SORT_PRICE_DESC_STR:     "l.price_per_kg DESC, l.id DESC",
+	constants.LISTING_SORT_WEIGHT_DESC_STR:    "l.weight_available DESC, l.id DESC",
+	constants.LISTING_SORT_RATING_DESC_STR:    "carrier_rating DESC, l.id DESC",
+	constants.LISTING_SORT_NEWEST_STR:         "l.last_modified_at DESC, l.id DESC",
+}
+
+func isListingSortValid(sort string) bool {
+	_, ok := listingSortOrders[sort]
+	return ok
+}
+
+// the average rating givers gave each carrier, like the review store computes it
+const carrierRatingJoin = `LEFT JOIN (
+					SELECT r.reviewee_id, AVG(r.rating) AS avg_rating 
+					FROM review AS r 
+					JOIN order_list AS o ON r.order_id = o.id 
+					JOIN listing AS rl ON rl.id = o.listing_id 
+					WHERE r.review_type = ? 
+					AND o.deleted_at IS NULL 
+					AND rl.deleted_at IS NULL 
+					GROUP BY r.reviewee_id
+				) AS cr ON cr.reviewee_id = l.carrier_id`
+
+// returns a page of the available listings of other carriers matching the filter
+func (s *Store) SearchListings(carrierId int, filter types.ListingSearchFilter) ([]types.ListingReturnFromDB, error) {
+	condition, args := listingSearchCondition(carrierId, filter)
+
+	sortOrder, ok := listingSortOrders[filter.Sort]
+	if !ok {
+		sortOrder = listingSortOrders[constants.LISTING_SORT_DEPARTURE_STR]
+	}
+
+	query := `SELECT l.id, l.carrier_id, user.name, user.email, 
+					l.destination, 
+					l.origin_city, l.origin_country_code, 
+					l.destination_city, l.destination_country_code, 
+					l.weight_available, l.price_per_kg, 
+					c.name, 
+					l.departure_date, 
+					l.last_received_date, 
+					l.exp_status, 
+					l.description, 
+					l.last_modified_at, 
+					COALESCE(cr.avg_rating, 0) AS carrier_rating 
+				FROM listing AS l 
+				JOIN user ON user.id = l.carrier_id 
+				JOIN currency AS c ON c.id = l.currency_id 
+				` + carrierRatingJoin + ` 
+				WHERE ` + condition + ` 
+				ORDER BY ` + sortOrder + ` 
+				LIMIT ? OFFSET ?`
+
+	args = append([]any{constants.REVIEW_GIVER_TO_CARRIER}, args...)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	listings := make([]types.ListingReturnFromDB, 0)
+
+	for rows.Next() {
+		listing, err := scanRowIntoListingSearchResult(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		listings = append(listings, *listing)
+	}
+
+	return listings, nil
+}
+
+func (s *Store) GetListingCountBySearch(carrierId int, filter types.ListingSearchFilter) (int, error) {
+	condition, args := listingSearchCondition(carrierId, filter)
+
+	query := `SELECT COUNT(*) 
+				FROM listing AS l 
+				JOIN currency AS c ON c.id = l.currency_id 
+				` + carrierRatingJoin + ` 
+				WHERE ` + condition
+
+	args = append([]any{constants.REVIEW_GIVER_TO_CARRIER}, args...)
+
+	var count int
+	err := s.db.QueryRow(query, args...).Scan(&count)
 	if err != nil {
-		return nil, fmt.Errorf("error updating listing status: %v", err)
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func listingSearchCondition(carrierId int, filter types.ListingSearchFilter) (string, []any) {
+	condition := `l.exp_status = ? AND l.carrier_id != ? AND l.deleted_at IS NULL`
+	args := []any{constants.EXP_STATUS_AVAILABLE, carrierId}
+
+	if filter.Destination != "" {
+		condition += " AND (LOWER(l.destination) LIKE ? OR LOWER(l.destination_city) LIKE ?)"
+		destination := "%" + escapeLike(strings.ToLower(filter.Destination)) + "%"
+		args = append(args, destination, destination)
+	}
+
+	if filter.OriginCountryCode != "" {
+		condition += " AND l.origin_country_code = ?"
+		args = append(args, filter.OriginCountryCode)
+	}
+
+	if filter.OriginCity != "" {
+		condition += " AND LOWER(l.origin_city) = LOWER(?)"
+		args = append(args, filter.OriginCity)
+	}
+
+	if filter.DestinationCountryCode != "" {
+		condition += " AND l.destination_country_code = ?"
+		args = append(args, filter.DestinationCountryCode)
+	}
+
+	if filter.DestinationCity != "" {
+		condition += " AND LOWER(l.destination_city) = LOWER(?)"
+		args = append(args, filter.DestinationCity)
+	}
+
+	// a giver can hand the package over where the trip starts or at any stop on the way
+	if filter.ViaCity != "" {
+		condition += ` AND (LOWER(l.origin_city) = LOWER(?) OR EXISTS (
+							SELECT 1 FROM listing_stop AS ls 
+							WHERE ls.listing_id = l.id AND LOWER(ls.city) = LOWER(?)
+						))`
+		args = append(args, filter.ViaCity, filter.ViaCity)
+	}
+
+	if !filter.DepartureFrom.IsZero() {
+		condition += " AND l.departure_date >= ?"
+		args = append(args, filter.DepartureFrom)
+	}
+
+	if !filter.DepartureTo.IsZero() {
+		condition += " AND l.departure_date < ?"
+		args = append(args, filter.DepartureTo)
+	}
+
+	if filter.MinWeightAvailable > 0 {
+		condition += " AND l.weight_available >= ?"
+		args = append(args, filter.MinWeightAvailable)
 	}
 
+	if filter.MaxPricePerKg > 0 {
+		condition += " AND l.price_per_kg <= ?"
+		args = append(args, filter.MaxPricePerKg)
+	}
+
+	if filter.Currency != "" {
+		condition += " AND c.name = ?"
+		args = append(args, filter.Currency)
+	}
+
+	if filter.MinCarrierRating > 0 {
+		condition += " AND COALESCE(cr.avg_rating, 0) >= ?"
+		args = append(args, filter.MinCarrierRating)
+	}
+
+	return condition, args
+}
+
+// so the user's % and _ are matched literally
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func (s *Store) GetListingsByCarrierID(carrierId int) ([]types.ListingReturnFromDB, error) {
 	query := `SELECT l.id, l.carrier_id, user.name, user.email, 
 					l.destination, 
+					l.origin_city, l.origin_country_code, 
+					l.destination_city, l.destination_country_code, 
 					l.weight_available, l.price_per_kg, 
 					c.name, 
 					l.departure_date, 
@@ -120,15 +326,60 @@ func (s *Store) GetListingsByCarrierID(carrierId int) ([]types.ListingReturnFrom
 	return listings, nil
 }
 
-func (s *Store) UpdateListingExpStatus() error {
-	query := `UPDATE listing SET exp_status = ? AND last_modified_at = ? 
-				WHERE (departure_date < ? OR weight_available <= 0) AND deleted_at IS NULL`
-	_, err := s.db.Exec(query, constants.EXP_STATUS_EXPIRED, time.Now(), time.Now().UTC().Format("2006-01-02 15:04:05"))
+// returns the available listings that have departed or are fully booked
+func (s *Store) GetListingsToExpire() ([]types.ListingReturnFromDB, error) {
+	query := `SELECT l.id, l.carrier_id, user.name, user.email, 
+					l.destination, 
+					l.origin_city, l.origin_country_code, 
+					l.destination_city, l.destination_country_code, 
+					l.weight_available, l.price_per_kg, 
+					c.name, 
+					l.departure_date, 
+					l.last_received_date, 
+					l.exp_status, 
+					l.description, 
+					l.last_modified_at  
+				FROM listing AS l 
+				JOIN user ON user.id = l.carrier_id 
+				JOIN currency AS c ON c.id = l.currency_id 
+				WHERE (l.departure_date < ? OR l.weight_available <= 0) 
+				AND l.exp_status = ? 
+				AND l.deleted_at IS NULL`
+	rows, err := s.db.Query(query, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.EXP_STATUS_AVAILABLE)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	listings := make([]types.ListingReturnFromDB, 0)
+
+	for rows.Next() {
+		listing, err := scanRowIntoListingReturn(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		listings = append(listings, *listing)
+	}
+
+	return listings, nil
+}
+
+// returns false if the listing was already expired by someone else
+func (s *Store) ExpireListing(id int) (bool, error) {
+	query := `UPDATE listing SET exp_status = ?, last_modified_at = ? 
+				WHERE id = ? AND exp_status = ? AND deleted_at IS NULL`
+	res, err := s.db.Exec(query, constants.EXP_STATUS_EXPIRED, time.Now(), id, constants.EXP_STATUS_AVAILABLE)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
 }
 
 func (s *Store) IsListingDuplicate(carrierId int, destination string, weightAvailable float64, departureDate time.Time) (bool, error) {
@@ -155,7 +406,10 @@ func (s *Store) IsListingDuplicate(carrierId int, destination string, weightAvai
 
 func (s *Store) GetListingByPayload(carrierName string, destination string, weightAvailable float64, pricePerKg float64, departureDate time.Time) (*types.ListingReturnFromDB, error) {
 	query := `SELECT l.id, l.carrier_id, user.name, user.email, 
-					l.destination, l.weight_available, 
+					l.destination, 
+					l.origin_city, l.origin_country_code, 
+					l.destination_city, l.destination_country_code, 
+					l.weight_available, 
 					l.price_per_kg, 
 					c.name, 
 					l.departure_date, 
@@ -194,7 +448,10 @@ func (s *Store) GetListingByPayload(carrierName string, destination string, weig
 
 func (s *Store) GetListingByID(id int) (*types.ListingReturnFromDB, error) {
 	query := `SELECT l.id, l.carrier_id, user.name, user.email, 
-					l.destination, l.weight_available, 
+					l.destination, 
+					l.origin_city, l.origin_country_code, 
+					l.destination_city, l.destination_country_code, 
+					l.weight_available, 
 					l.price_per_kg, 
 					c.name, 
 					l.departure_date, 
@@ -222,7 +479,7 @@ func (s *Store) GetListingByID(id int) (*types.ListingReturnFromDB, error) {
 	}
 
 	if listing.ID == 0 {
-		return nil, fmt.Errorf("listing not found")
+		return nil, types.ErrListingNotFound
 	}
 
 	return listing, nil
@@ -239,20 +496,85 @@ func (s *Store) DeleteListing(id int) error {
 	return nil
 }
 
+// updates the listing and replaces its stops
 func (s *Store) ModifyListing(id int, listing types.Listing) error {
 	query := `UPDATE listing 
-				SET destination = ?, weight_available = ?, 
+				SET destination = ?, 
+					origin_city = ?, origin_country_code = ?, 
+					destination_city = ?, destination_country_code = ?, 
+					weight_available = ?, 
 					price_per_kg = ?, currency_id = ?, 
 					departure_date = ?, last_received_date = ?, 
 					exp_status = ?, description = ?, last_modified_at = ? 
 				WHERE id = ? AND deleted_at IS NULL`
 
-	_, err := s.db.Exec(query, listing.Destination, listing.WeightAvailable,
-		listing.PricePerKg, listing.CurrencyID, listing.DepartureDate,
-		listing.LastReceivedDate, listing.ExpStatus,
-		listing.Description, time.Now(), id)
+	return db.RunInTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(query, listing.Destination,
+			listing.OriginCity, listing.OriginCountryCode,
+			listing.DestinationCity, listing.DestinationCountryCode,
+			listing.WeightAvailable,
+			listing.PricePerKg, listing.CurrencyID, listing.DepartureDate,
+			listing.LastReceivedDate, listing.ExpStatus,
+			listing.Description, time.Now(), id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`DELETE FROM listing_stop WHERE listing_id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		return createListingStops(tx, id, listing.Stops)
+	})
+}
+
+// returns the stops of every listing, in the order the carrier passes them
+func (s *Store) GetListingStopsByListingIDs(listingIds []int) (map[int][]types.ListingStop, error) {
+	stops := make(map[int][]types.ListingStop)
+
+	if len(listingIds) == 0 {
+		return stops, nil
+	}
+
+	args := make([]any, 0, len(listingIds))
+	for _, listingId := range listingIds {
+		args = append(args, listingId)
+	}
+
+	query := `SELECT id, listing_id, stop_order, city, country_code 
+				FROM listing_stop 
+				WHERE listing_id IN (?` + strings.Repeat(", ?", len(listingIds)-1) + `) 
+				ORDER BY listing_id ASC, stop_order ASC`
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var stop types.ListingStop
+
+		err := rows.Scan(&stop.ID, &stop.ListingID, &stop.StopOrder, &stop.City, &stop.CountryCode)
+		if err != nil {
+			return nil, err
+		}
+
+		stops[stop.ListingID] = append(stops[stop.ListingID], stop)
+	}
+
+	return stops, nil
+}
+
+func createListingStops(tx *sql.Tx, listingId int, stops []types.ListingStop) error {
+	query := `INSERT INTO listing_stop (listing_id, stop_order, city, country_code) 
+				VALUES (?, ?, ?, ?)`
+
+	for i, stop := range stops {
+		_, err := tx.Exec(query, listingId, i+1, stop.City, stop.CountryCode)
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -280,6 +602,38 @@ func (s *Store) AddWeightAvailable(listingId int, addValue float64) error {
 	return nil
 }
 
+// only subtracts when there is enough weight left, so concurrent confirmations can't oversell
+func (s *Store) SubtractWeightAvailableTx(tx *sql.Tx, listingId int, minusValue float64) error {
+	query := `UPDATE listing SET weight_available = (weight_available - ?), last_modified_at = ? 
+				WHERE id = ? AND weight_available >= ? AND deleted_at IS NULL`
+	result, err := tx.Exec(query, minusValue, time.Now(), listingId, minusValue)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return types.ErrNotEnoughWeight
+	}
+
+	return nil
+}
+
+func (s *Store) AddWeightAvailableTx(tx *sql.Tx, listingId int, addValue float64) error {
+	query := `UPDATE listing SET weight_available = (weight_available + ?), last_modified_at = ? 
+				WHERE id = ? AND deleted_at IS NULL`
+	_, err := tx.Exec(query, addValue, time.Now(), listingId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 // func scanRowIntoListing(rows *sql.Rows) (*types.Listing, error) {
 // 	listing := new(types.Listing)
 
@@ -306,6 +660,41 @@ func (s *Store) AddWeightAvailable(listingId int, addValue float64) error {
 // 	return listing, nil
 // }
 
+func scanRowIntoListingSearchResult(rows *sql.Rows) (*types.ListingReturnFromDB, error) {
+	listing := new(types.ListingReturnFromDB)
+
+	err := rows.Scan(
+		&listing.ID,
+		&listing.CarrierID,
+		&listing.CarrierName,
+		&listing.CarrierEmail,
+		&listing.Destination,
+		&listing.OriginCity,
+		&listing.OriginCountryCode,
+		&listing.DestinationCity,
+		&listing.DestinationCountryCode,
+		&listing.WeightAvailable,
+		&listing.PricePerKg,
+		&listing.Currency,
+		&listing.DepartureDate,
+		&listing.LastReceivedDate,
+		&listing.ExpStatus,
+		&listing.Description,
+		&listing.LastModifiedAt,
+		&listing.CarrierRating,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listing.DepartureDate = listing.DepartureDate.Local()
+	listing.LastReceivedDate = listing.LastReceivedDate.Local()
+	listing.LastModifiedAt = listing.LastModifiedAt.Local()
+
+	return listing, nil
+}
+
 func scanRowIntoListingReturn(rows *sql.Rows) (*types.ListingReturnFromDB, error) {
 	listing := new(types.ListingReturnFromDB)
 
@@ -315,6 +704,10 @@ func scanRowIntoListingReturn(rows *sql.Rows) (*types.ListingReturnFromDB, error
 		&listing.CarrierName,
 		&listing.CarrierEmail,
 		&listing.Destination,
+		&listing.OriginCity,
+		&listing.OriginCountryCode,
+		&listing.DestinationCity,
+		&listing.DestinationCountryCode,
 		&listing.WeightAvailable,
 		&listing.PricePerKg,
 		&listing.Currency,
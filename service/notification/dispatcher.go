@@ -0,0 +1,58 @@
+package notification
+
+import (
+	"fmt"
+
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// Dispatcher is the Notifier given to the handlers.
+// It hands each message to the notifier registered for its channel.
+type Dispatcher struct {
+	notifiers map[string]types.Notifier
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{notifiers: make(map[string]types.Notifier)}
+}
+
+// builds the dispatcher for the configured notification mode
+func NewDispatcherFromConfig(fcmHistoryStore types.FCMHistoryStore, blobStore types.BlobStore) (*Dispatcher, error) {
+	dispatcher := NewDispatcher()
+
+	switch config.Envs.NotificationMode {
+	case constants.NOTIFICATION_MODE_LIVE:
+		fcmNotifier, err := NewFCMNotifier(config.Envs.GoogleApplicationCredentialsPath, fcmHistoryStore)
+		if err != nil {
+			return nil, fmt.Errorf("error creating fcm client: %v", err)
+		}
+
+		dispatcher.Register(constants.NOTIFICATION_CHANNEL_EMAIL, NewSMTPNotifier(config.Envs.SMTPHost, config.Envs.SMTPPort,
+			config.Envs.CompanyEmail, config.Envs.CompanyEmailPassword, blobStore))
+		dispatcher.Register(constants.NOTIFICATION_CHANNEL_PUSH, fcmNotifier)
+	case constants.NOTIFICATION_MODE_RECORDER:
+		recorder := NewRecorder()
+
+		dispatcher.Register(constants.NOTIFICATION_CHANNEL_EMAIL, recorder)
+		dispatcher.Register(constants.NOTIFICATION_CHANNEL_PUSH, recorder)
+	default:
+		return nil, fmt.Errorf("unknown notification mode %s", config.Envs.NotificationMode)
+	}
+
+	return dispatcher, nil
+}
+
+func (d *Dispatcher) Register(channel string, notifier types.Notifier) {
+	d.notifiers[channel] = notifier
+}
+
+func (d *Dispatcher) Notify(message types.NotificationMessage) error {
+	notifier, ok := d.notifiers[message.Channel]
+	if !ok {
+		return fmt.Errorf("no notifier for channel %s", message.Channel)
+	}
+
+	return notifier.Notify(message)
+}
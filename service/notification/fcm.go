@@ -0,0 +1,82 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	firebase "firebase.google.com/go"
+	"firebase.google.com/go/messaging"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"google.golang.org/api/option"
+)
+
+// FCMNotifier sends the push channel through Firebase Cloud Messaging.
+// The messaging client is created once and shared by every send.
+type FCMNotifier struct {
+	client          *messaging.Client
+	fcmHistoryStore types.FCMHistoryStore
+}
+
+func NewFCMNotifier(credentialsPath string, fcmHistoryStore types.FCMHistoryStore) (*FCMNotifier, error) {
+	app, err := firebase.NewApp(context.Background(), nil, option.WithCredentialsFile(credentialsPath))
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := app.Messaging(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return &FCMNotifier{
+		client:          client,
+		fcmHistoryStore: fcmHistoryStore,
+	}, nil
+}
+
+func (n *FCMNotifier) Notify(message types.NotificationMessage) error {
+	if message.To == "" {
+		return fmt.Errorf("destination token must be specified")
+	}
+
+	var fcmData map[string]string
+
+	fcmDataMarshal, _ := json.Marshal(message.Data)
+	json.Unmarshal(fcmDataMarshal, &fcmData)
+
+	response, err := n.client.Send(context.Background(), &messaging.Message{
+		Notification: &messaging.Notification{
+			Title: message.Title,
+			Body:  message.Body,
+		},
+		Token: message.To,
+		Data:  fcmData,
+		Android: &messaging.AndroidConfig{
+			Priority: "high",
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	if message.InboxID != 0 {
+		err = n.fcmHistoryStore.UpdateFCMHistoryResponse(message.InboxID, message.To, response)
+	} else {
+		_, err = n.fcmHistoryStore.CreateFCMHistory(types.FCMHistory{
+			ToUserID: message.ToUserID,
+			ToToken:  message.To,
+			Title:    message.Title,
+			Body:     message.Body,
+			Data:     message.Data,
+			Response: response,
+		})
+	}
+	if err != nil {
+		// the push went out, so only the history is missing
+		logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package notification
+
+import (
+	"fmt"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// sends the email and the push notification, logging failures instead of returning them.
+// with an order id the push opens that order in the app
+func NotifyUser(notifier types.Notifier, user *types.User, subject string, emailBody string, fcmBody string, orderId int) {
+	err := notifier.Notify(types.NotificationMessage{
+		Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+		To:      user.Email,
+		Title:   subject,
+		Body:    emailBody,
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending email to %s: %v", user.Email, err))
+	}
+
+	push := types.NotificationMessage{
+		Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
+		ToUserID: user.ID,
+		Title:    subject,
+		Body:     fcmBody,
+	}
+
+	if orderId != 0 {
+		push.Data = types.FCMData{
+			Type:    "order_updated",
+			OrderID: fmt.Sprintf("%d", orderId),
+		}
+	}
+
+	err = notifier.Notify(push)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending notification to user %d: %v", user.ID, err))
+	}
+}
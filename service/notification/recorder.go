@@ -0,0 +1,48 @@
+package notification
+
+import (
+	"log"
+	"sync"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// Recorder keeps every message in memory instead of sending it.
+// It stands in for the real channels in tests and local development.
+type Recorder struct {
+	mu       sync.Mutex
+	messages []types.NotificationMessage
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{messages: make([]types.NotificationMessage, 0)}
+}
+
+func (r *Recorder) Notify(message types.NotificationMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	log.Printf("notification (%s) to %s: %s", message.Channel, message.To, message.Title)
+
+	r.messages = append(r.messages, message)
+
+	return nil
+}
+
+// returns a copy of the messages recorded so far
+func (r *Recorder) Messages() []types.NotificationMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	messages := make([]types.NotificationMessage, len(r.messages))
+	copy(messages, r.messages)
+
+	return messages
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.messages = r.messages[:0]
+}
@@ -0,0 +1,57 @@
+package notification
+
+import (
+	"io"
+	"net/smtp"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+// SMTPNotifier sends the email channel through an SMTP server
+type SMTPNotifier struct {
+	host      string
+	port      string
+	from      string
+	password  string
+	blobStore types.BlobStore
+}
+
+func NewSMTPNotifier(host string, port string, from string, password string, blobStore types.BlobStore) *SMTPNotifier {
+	return &SMTPNotifier{
+		host:      host,
+		port:      port,
+		from:      from,
+		password:  password,
+		blobStore: blobStore,
+	}
+}
+
+func (n *SMTPNotifier) Notify(message types.NotificationMessage) error {
+	email := &utils.Message{
+		To:          []string{message.To},
+		Subject:     message.Title,
+		Body:        message.Body + "<br><br><p>Copyright 2024. Jim Carrier International.</p>",
+		Attachments: make(map[string][]byte),
+	}
+
+	// the attachment is the key of an uploaded image
+	if message.AttachmentURL != "" {
+		reader, err := n.blobStore.Get(message.AttachmentURL)
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		attachment, err := io.ReadAll(reader)
+		if err != nil {
+			return err
+		}
+
+		email.Attachments[message.AttachmentName] = attachment
+	}
+
+	auth := smtp.PlainAuth("", n.from, n.password, n.host)
+
+	return smtp.SendMail(n.host+":"+n.port, auth, n.from, []string{message.To}, email.ToBytes())
+}
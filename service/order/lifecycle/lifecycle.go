@@ -0,0 +1,87 @@
+package lifecycle
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type transition struct {
+	from int
+	to   int
+}
+
+// every allowed order status change and the parties that may trigger it
+var allowedTransitions = map[transition][]int{
+	// the giver modifying an order keeps it (or puts it back) in waiting for re-confirmation
+	{constants.ORDER_STATUS_WAITING, constants.ORDER_STATUS_WAITING}:   {constants.ACTOR_GIVER},
+	{constants.ORDER_STATUS_CONFIRMED, constants.ORDER_STATUS_WAITING}: {constants.ACTOR_GIVER},
+
+	{constants.ORDER_STATUS_WAITING, constants.ORDER_STATUS_CONFIRMED}: {constants.ACTOR_CARRIER},
+	{constants.ORDER_STATUS_WAITING, constants.ORDER_STATUS_CANCELLED}: {constants.ACTOR_GIVER, constants.ACTOR_CARRIER, constants.ACTOR_SYSTEM},
+
+	{constants.ORDER_STATUS_CONFIRMED, constants.ORDER_STATUS_EN_ROUTE}:  {constants.ACTOR_CARRIER},
+	{constants.ORDER_STATUS_CONFIRMED, constants.ORDER_STATUS_CANCELLED}: {constants.ACTOR_GIVER, constants.ACTOR_CARRIER},
+
+	{constants.ORDER_STATUS_EN_ROUTE, constants.ORDER_STATUS_COMPLETED}: {constants.ACTOR_CARRIER},
+}
+
+// returns which party of the order the user is
+func ResolveActor(userId int, giverId int, carrierId int) (int, error) {
+	switch userId {
+	case carrierId:
+		return constants.ACTOR_CARRIER, nil
+	case giverId:
+		return constants.ACTOR_GIVER, nil
+	default:
+		return -1, types.ErrNotOrderParty
+	}
+}
+
+func CheckTransition(from int, to int, actor int) error {
+	// admins resolve stuck orders, they may move an order to any other status
+	if actor == constants.ACTOR_ADMIN && from != to {
+		return nil
+	}
+
+	actors, ok := allowedTransitions[transition{from: from, to: to}]
+	if !ok || !slices.Contains(actors, actor) {
+		return fmt.Errorf("%w: %s cannot change the order from %s to %s", types.ErrIllegalTransition,
+			utils.ActorIntToString(actor), utils.OrderStatusIntToString(from), utils.OrderStatusIntToString(to))
+	}
+
+	return nil
+}
+
+// only the carrier can report where the package is, and only while carrying it
+func CheckLocationUpdate(status int, actor int) error {
+	if actor != constants.ACTOR_CARRIER ||
+		(status != constants.ORDER_STATUS_CONFIRMED && status != constants.ORDER_STATUS_EN_ROUTE) {
+		return fmt.Errorf("%w: %s cannot update the package location of a %s order", types.ErrIllegalTransition,
+			utils.ActorIntToString(actor), utils.OrderStatusIntToString(status))
+	}
+
+	return nil
+}
+
+// only the giver pays, and never for a cancelled order
+func CheckPaymentUpdate(status int, actor int) error {
+	if actor != constants.ACTOR_GIVER || status == constants.ORDER_STATUS_CANCELLED {
+		return fmt.Errorf("%w: %s cannot update the payment of a %s order", types.ErrIllegalTransition,
+			utils.ActorIntToString(actor), utils.OrderStatusIntToString(status))
+	}
+
+	return nil
+}
+
+func IsClosed(status int) bool {
+	return status == constants.ORDER_STATUS_COMPLETED || status == constants.ORDER_STATUS_CANCELLED
+}
+
+// weight is only taken from the listing once the carrier confirms the order
+func IsWeightReserved(status int) bool {
+	return status == constants.ORDER_STATUS_CONFIRMED || status == constants.ORDER_STATUS_EN_ROUTE
+}
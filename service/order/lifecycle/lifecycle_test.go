@@ -0,0 +1,104 @@
+package lifecycle
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+func TestCheckTransition(t *testing.T) {
+	const (
+		waiting   = constants.ORDER_STATUS_WAITING
+		confirmed = constants.ORDER_STATUS_CONFIRMED
+		enRoute   = constants.ORDER_STATUS_EN_ROUTE
+		completed = constants.ORDER_STATUS_COMPLETED
+		cancelled = constants.ORDER_STATUS_CANCELLED
+
+		giver   = constants.ACTOR_GIVER
+		carrier = constants.ACTOR_CARRIER
+		system  = constants.ACTOR_SYSTEM
+		admin   = constants.ACTOR_ADMIN
+	)
+
+	tests := []struct {
+		name    string
+		from    int
+		to      int
+		actor   int
+		allowed bool
+	}{
+		// allowed
+		{"giver modifies a waiting order", waiting, waiting, giver, true},
+		{"giver modifies a confirmed order", confirmed, waiting, giver, true},
+		{"carrier confirms", waiting, confirmed, carrier, true},
+		{"giver cancels a waiting order", waiting, cancelled, giver, true},
+		{"carrier declines a waiting order", waiting, cancelled, carrier, true},
+		{"system cancels after the deadline", waiting, cancelled, system, true},
+		{"carrier departs", confirmed, enRoute, carrier, true},
+		{"giver cancels a confirmed order", confirmed, cancelled, giver, true},
+		{"carrier cancels a confirmed order", confirmed, cancelled, carrier, true},
+		{"carrier delivers", enRoute, completed, carrier, true},
+
+		// illegal
+		{"giver confirms their own order", waiting, confirmed, giver, false},
+		{"carrier modifies a waiting order", waiting, waiting, carrier, false},
+		{"system cancels a confirmed order", confirmed, cancelled, system, false},
+		{"giver marks it en route", confirmed, enRoute, giver, false},
+		{"waiting skips to en route", waiting, enRoute, carrier, false},
+		{"giver completes", enRoute, completed, giver, false},
+		{"en route is cancelled", enRoute, cancelled, carrier, false},
+		{"completed is reopened", completed, waiting, giver, false},
+		{"cancelled is reconfirmed", cancelled, confirmed, carrier, false},
+		{"unknown actor", waiting, confirmed, -1, false},
+
+		// admin overrides
+		{"admin cancels an en route order", enRoute, cancelled, admin, true},
+		{"admin reopens a completed order", completed, confirmed, admin, true},
+		{"admin reopens a cancelled order", cancelled, waiting, admin, true},
+		{"admin completes a waiting order", waiting, completed, admin, true},
+		{"admin keeps the same status", confirmed, confirmed, admin, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckTransition(tt.from, tt.to, tt.actor)
+
+			if tt.allowed {
+				if err != nil {
+					t.Errorf("CheckTransition(%d, %d, %d) = %v, want nil", tt.from, tt.to, tt.actor, err)
+				}
+				return
+			}
+
+			if !errors.Is(err, types.ErrIllegalTransition) {
+				t.Errorf("CheckTransition(%d, %d, %d) = %v, want ErrIllegalTransition", tt.from, tt.to, tt.actor, err)
+			}
+		})
+	}
+}
+
+func TestResolveActor(t *testing.T) {
+	const giverId, carrierId = 1, 2
+
+	tests := []struct {
+		name    string
+		userId  int
+		want    int
+		wantErr error
+	}{
+		{"giver", giverId, constants.ACTOR_GIVER, nil},
+		{"carrier", carrierId, constants.ACTOR_CARRIER, nil},
+		{"someone else", 3, -1, types.ErrNotOrderParty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveActor(tt.userId, giverId, carrierId)
+			if got != tt.want || !errors.Is(err, tt.wantErr) {
+				t.Errorf("ResolveActor(%d) = %d, %v, want %d, %v", tt.userId, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package pricing
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// prices the weight at the listing's rate, in the listing's currency
+func Quote(listing *types.ListingReturnFromDB, weight float64, insurance bool) types.PriceQuote {
+	basePrice := round(listing.PricePerKg * weight)
+
+	fees := make([]types.PriceQuoteFee, 0)
+
+	if config.Envs.ServiceFeePercent > 0 {
+		fees = append(fees, types.PriceQuoteFee{
+			Name:   constants.SERVICE_FEE_STR,
+			Amount: round(basePrice * config.Envs.ServiceFeePercent / 100),
+		})
+	}
+
+	if insurance && config.Envs.InsuranceFeePercent > 0 {
+		fees = append(fees, types.PriceQuoteFee{
+			Name:   constants.INSURANCE_FEE_STR,
+			Amount: round(basePrice * config.Envs.InsuranceFeePercent / 100),
+		})
+	}
+
+	total := basePrice
+	for _, fee := range fees {
+		total += fee.Amount
+	}
+
+	return types.PriceQuote{
+		ListingID:  listing.ID,
+		Weight:     weight,
+		PricePerKg: listing.PricePerKg,
+		Currency:   listing.Currency,
+		BasePrice:  basePrice,
+		Fees:       fees,
+		Total:      round(total),
+	}
+}
+
+// the client doesn't have to send a price or currency,
+// but when it does they must agree with the quote
+func CheckClientPrice(quote types.PriceQuote, price float64, currency string) error {
+	if currency != "" && !strings.EqualFold(currency, quote.Currency) {
+		return fmt.Errorf("%w: the listing is priced in %s", types.ErrPriceMismatch, quote.Currency)
+	}
+
+	if price != 0 && math.Abs(price-quote.Total) >= 0.005 {
+		return fmt.Errorf("%w: expected %.2f %s", types.ErrPriceMismatch, quote.Total, quote.Currency)
+	}
+
+	return nil
+}
+
+// rounds to cents
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,138 @@
+package pricing
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+func setFees(t *testing.T, servicePercent float64, insurancePercent float64) {
+	t.Helper()
+
+	oldService, oldInsurance := config.Envs.ServiceFeePercent, config.Envs.InsuranceFeePercent
+	t.Cleanup(func() {
+		config.Envs.ServiceFeePercent, config.Envs.InsuranceFeePercent = oldService, oldInsurance
+	})
+
+	config.Envs.ServiceFeePercent, config.Envs.InsuranceFeePercent = servicePercent, insurancePercent
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		name             string
+		pricePerKg       float64
+		weight           float64
+		insurance        bool
+		servicePercent   float64
+		insurancePercent float64
+		wantBase         float64
+		wantFees         []float64
+		wantTotal        float64
+	}{
+		{
+			name:       "no fees",
+			pricePerKg: 10,
+			weight:     1.5,
+			wantBase:   15,
+			wantFees:   []float64{},
+			wantTotal:  15,
+		},
+		{
+			name:           "service fee only",
+			pricePerKg:     10,
+			weight:         2,
+			servicePercent: 5,
+			wantBase:       20,
+			wantFees:       []float64{1},
+			wantTotal:      21,
+		},
+		{
+			name:             "insurance fee is skipped without insurance",
+			pricePerKg:       10,
+			weight:           2,
+			servicePercent:   5,
+			insurancePercent: 1,
+			wantBase:         20,
+			wantFees:         []float64{1},
+			wantTotal:        21,
+		},
+		{
+			// 8.6415 + 0.2160 + 0.0864 would be 8.94 unrounded,
+			// each part is rounded to cents before adding up
+			name:             "sub-cent amounts are rounded per part",
+			pricePerKg:       12.345,
+			weight:           0.7,
+			insurance:        true,
+			servicePercent:   2.5,
+			insurancePercent: 1,
+			wantBase:         8.64,
+			wantFees:         []float64{0.22, 0.09},
+			wantTotal:        8.95,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setFees(t, tt.servicePercent, tt.insurancePercent)
+
+			listing := &types.ListingReturnFromDB{ID: 1, PricePerKg: tt.pricePerKg, Currency: "KRW"}
+			quote := Quote(listing, tt.weight, tt.insurance)
+
+			if quote.BasePrice != tt.wantBase {
+				t.Errorf("BasePrice = %v, want %v", quote.BasePrice, tt.wantBase)
+			}
+
+			if len(quote.Fees) != len(tt.wantFees) {
+				t.Fatalf("Fees = %+v, want amounts %v", quote.Fees, tt.wantFees)
+			}
+			for i, fee := range quote.Fees {
+				if fee.Amount != tt.wantFees[i] {
+					t.Errorf("fee %s = %v, want %v", fee.Name, fee.Amount, tt.wantFees[i])
+				}
+			}
+
+			if quote.Total != tt.wantTotal {
+				t.Errorf("Total = %v, want %v", quote.Total, tt.wantTotal)
+			}
+
+			if quote.Currency != "KRW" || quote.ListingID != 1 {
+				t.Errorf("quote is for listing %d in %s, want listing 1 in KRW", quote.ListingID, quote.Currency)
+			}
+		})
+	}
+}
+
+func TestCheckClientPrice(t *testing.T) {
+	quote := types.PriceQuote{Currency: "KRW", Total: 8.95}
+
+	tests := []struct {
+		name     string
+		price    float64
+		currency string
+		wantErr  bool
+	}{
+		{"nothing sent", 0, "", false},
+		{"matching price and currency", 8.95, "KRW", false},
+		{"currency in lower case", 8.95, "krw", false},
+		{"price off by less than half a cent", 8.954, "", false},
+		{"price a cent lower", 8.94, "KRW", true},
+		{"price a cent higher", 8.96, "", true},
+		{"other currency", 8.95, "USD", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckClientPrice(quote, tt.price, tt.currency)
+
+			if tt.wantErr && !errors.Is(err, types.ErrPriceMismatch) {
+				t.Errorf("CheckClientPrice(%v, %q) = %v, want ErrPriceMismatch", tt.price, tt.currency, err)
+			}
+
+			if !tt.wantErr && err != nil {
+				t.Errorf("CheckClientPrice(%v, %q) = %v, want nil", tt.price, tt.currency, err)
+			}
+		})
+	}
+}
@@ -1,9 +1,11 @@
 package order
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -11,6 +13,9 @@ import (
 	"github.com/gorilla/mux"
 	"github.com/nicolaics/jim-carrier-server/constants"
 	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/service/order/lifecycle"
+	"github.com/nicolaics/jim-carrier-server/service/order/pricing"
 	"github.com/nicolaics/jim-carrier-server/types"
 	"github.com/nicolaics/jim-carrier-server/utils"
 	"golang.org/x/text/cases"
@@ -18,24 +23,29 @@ import (
 )
 
 type Handler struct {
-	orderStore      types.OrderStore
-	userStore       types.UserStore
-	listingStore    types.ListingStore
-	currencyStore   types.CurrencyStore
-	fcmHistoryStore types.FCMHistoryStore
-	bankDetailStore types.BankDetailStore
+	orderStore        types.OrderStore
+	userStore         types.UserStore
+	listingStore      types.ListingStore
+	currencyStore     types.CurrencyStore
+	notifier          types.Notifier
+	payoutMethodStore types.PayoutMethodStore
+	orderUnitOfWork   types.OrderUnitOfWork
+	blobStore         types.BlobStore
 }
 
 func NewHandler(orderStore types.OrderStore, userStore types.UserStore,
 	listingStore types.ListingStore, currencyStore types.CurrencyStore,
-	fcmHistoryStore types.FCMHistoryStore, bankDetailStore types.BankDetailStore) *Handler {
+	notifier types.Notifier, payoutMethodStore types.PayoutMethodStore,
+	orderUnitOfWork types.OrderUnitOfWork, blobStore types.BlobStore) *Handler {
 	return &Handler{
-		orderStore:      orderStore,
-		userStore:       userStore,
-		listingStore:    listingStore,
-		currencyStore:   currencyStore,
-		fcmHistoryStore: fcmHistoryStore,
-		bankDetailStore: bankDetailStore,
+		orderStore:        orderStore,
+		userStore:         userStore,
+		listingStore:      listingStore,
+		currencyStore:     currencyStore,
+		notifier:          notifier,
+		payoutMethodStore: payoutMethodStore,
+		orderUnitOfWork:   orderUnitOfWork,
+		blobStore:         blobStore,
 	}
 }
 
@@ -43,12 +53,18 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 	router.HandleFunc("/order", h.handleRegister).Methods(http.MethodPost)
 	router.HandleFunc("/order", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
 
+	router.HandleFunc("/order/quote", h.handleQuote).Methods(http.MethodPost)
+	router.HandleFunc("/order/quote", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
 	router.HandleFunc("/order/{reqType}", h.handleGetAll).Methods(http.MethodGet)
 	router.HandleFunc("/order/{reqType}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
 
 	router.HandleFunc("/order/{reqType}/detail", h.handleGetDetail).Methods(http.MethodPost)
 	router.HandleFunc("/order/{reqType}/detail", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
 
+	router.HandleFunc("/order/{id}/timeline", h.handleGetTimeline).Methods(http.MethodGet)
+	router.HandleFunc("/order/{id}/timeline", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
 	router.HandleFunc("/order", h.handleDelete).Methods(http.MethodDelete)
 
 	router.HandleFunc("/order/{reqType}", h.handleModify).Methods(http.MethodPatch)
@@ -60,13 +76,23 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	var payload types.RegisterOrderPayload
 
-	if err := utils.ParseJSON(r, &payload); err != nil {
+	// multipart/form-data or JSON
+	uploadedImage, err := utils.ParseUpload(w, r, &payload, "packageImage", constants.PACKAGE_IMG_MAX_BYTES)
+	if errors.Is(err, types.ErrUploadTooLarge) {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("image size exceeds the limit of 10MB"))
+		return
+	}
+	if err != nil {
 		log.Printf("payload error: %v \n", err)
 		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
 		return
 	}
 
+	if uploadedImage != nil {
+		payload.PackageImage = uploadedImage
+	}
+
 	// validate the payload
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -77,23 +103,9 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
-		return
-	}
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -134,30 +146,25 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	currency, err := h.currencyStore.GetCurrencyByName(payload.Currency)
+	// the price always comes from the listing's rate, never from the client
+	quote := pricing.Quote(listing, payload.Weight, payload.Insurance)
+
+	err = pricing.CheckClientPrice(quote, payload.Price, payload.Currency)
 	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
+	currency, err := h.currencyStore.GetCurrencyByName(quote.Currency)
 	if currency == nil {
-		err = h.currencyStore.CreateCurrency(payload.Currency)
-		if err != nil {
-			log.Printf("error create currency: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create currency: %v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
-
-		currency, err = h.currencyStore.GetCurrencyByName(payload.Currency)
-		if err != nil {
-			log.Println(err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("currency not found"))
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
 	}
 
 	var packageImgURL string
@@ -166,31 +173,24 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("image size exceeds the limit of 10MB"))
 		return
 	} else if len(payload.PackageImage) > 0 {
-		var imageExtension string
-
-		// check image type
-		mimeType := http.DetectContentType(payload.PackageImage)
-		switch mimeType {
-		case "image/jpeg":
-			imageExtension = ".jpg"
-		case "image/png":
-			imageExtension = ".png"
-		default:
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported image type"))
+		// decode, strip the metadata and build the variants
+		packageImage, err := utils.ProcessImage(payload.PackageImage)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
 			return
 		}
 
-		filePath := constants.PACKAGE_IMG_DIR_PATH + utils.GeneratePictureFileName(imageExtension)
+		filePath := constants.PACKAGE_IMG_KEY_PREFIX + utils.GeneratePictureFileName(packageImage.Extension)
 
 		isPackageImageURLExist := h.orderStore.IsPackageImageURLExist(filePath)
 
 		for isPackageImageURLExist {
-			filePath = constants.PACKAGE_IMG_DIR_PATH + utils.GeneratePictureFileName(imageExtension)
+			filePath = constants.PACKAGE_IMG_KEY_PREFIX + utils.GeneratePictureFileName(packageImage.Extension)
 			isPackageImageURLExist = h.orderStore.IsPackageImageURLExist(filePath)
 		}
 
 		// save the image
-		err := utils.SavePackageImage(payload.PackageImage, filePath)
+		err = utils.SavePackageImage(h.blobStore, packageImage, filePath)
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error saving package image: %v", err))
 		}
@@ -198,16 +198,17 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		packageImgURL = filePath
 	}
 
-	err = h.orderStore.CreateOrder(types.Order{
+	// the order and the "created" event of its timeline are inserted together
+	orderId, err := h.orderUnitOfWork.CreateOrder(types.Order{
 		ListingID:       listing.ID,
 		GiverID:         user.ID,
 		Weight:          payload.Weight,
-		Price:           payload.Price,
+		Price:           quote.Total,
 		CurrencyID:      currency.ID,
 		PackageContent:  payload.PackageContent,
 		PackageImageURL: packageImgURL,
 		Notes:           payload.Notes,
-	})
+	}, constants.ACTOR_GIVER)
 	if err != nil {
 		log.Printf("error create order: %v", err)
 		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create order: %v", err))
@@ -215,75 +216,81 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	orderId, err := h.orderStore.GetOrderID(types.Order{
-		ListingID:       listing.ID,
-		GiverID:         user.ID,
-		Weight:          payload.Weight,
-		Price:           payload.Price,
-		CurrencyID:      currency.ID,
-		PackageContent:  payload.PackageContent,
-		PackageImageURL: packageImgURL,
-		Notes:           payload.Notes,
-	})
-	if err != nil {
-		log.Printf("error finding order ID: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error finding order ID: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
 	subject := "New Order Arrived!"
 
-	body := utils.CreateEmailBodyOfOrder(subject, user.Name, listing.Destination, currency.Name, payload.Notes, payload.PackageContent, payload.Weight, payload.Price)
+	body := utils.CreateEmailBodyOfOrder(subject, user.Name, listing.Destination, currency.Name, payload.Notes, payload.PackageContent, payload.Weight, quote.Total)
 
-	err = utils.SendEmail(carrier.Email, subject, body, packageImgURL, "Package Image")
+	err = h.notifier.Notify(types.NotificationMessage{
+		Channel:        constants.NOTIFICATION_CHANNEL_EMAIL,
+		To:             carrier.Email,
+		Title:          subject,
+		Body:           body,
+		AttachmentURL:  packageImgURL,
+		AttachmentName: "Package Image",
+	})
 	if err != nil {
 		logger.WriteServerLog(fmt.Sprintf("error send email to carrier: %v", err))
 	}
 
-	fcmHistory := types.FCMHistory{
+	err = h.notifier.Notify(types.NotificationMessage{
+		Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
 		ToUserID: carrier.ID,
-		ToToken:  carrier.FCMToken,
 		Data: types.FCMData{
 			Type:    "confirm_order",
 			OrderID: fmt.Sprintf("%d", orderId),
 		},
 		Title: subject,
 		Body:  fmt.Sprintf("Confirm order no. %d before %s 23:59 KST (GMT +09)", orderId, time.Now().Local().AddDate(0, 0, 2).Format("02 Jan 2006")),
-	}
-
-	fcmHistory.Response, err = utils.SendFCMToOne(fcmHistory)
+	})
 	if err != nil {
 		logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
-	} else {
-		err = h.fcmHistoryStore.CreateFCMHistory(fcmHistory)
-		if err != nil {
-			logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
-		}
 	}
 
 	utils.WriteJSON(w, http.StatusCreated, "order created")
 }
 
-func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) handleQuote(w http.ResponseWriter, r *http.Request) {
+	var payload types.PriceQuotePayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v \n", err)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+
+	listing, err := h.listingStore.GetListingByID(payload.ListingID)
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		log.Printf("listing id %d not found: %v", payload.ListingID, err)
+		logger.WriteServerLog(fmt.Sprintf("listing id %d not found: %v", payload.ListingID, err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing not found"))
 		return
 	}
 
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
+	if payload.Weight > listing.WeightAvailable {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("ordered weight is greater than available weight"))
 		return
 	}
+
+	utils.WriteJSON(w, http.StatusOK, pricing.Quote(listing, payload.Weight, payload.Insurance))
+}
+
+func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -306,14 +313,6 @@ func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
 			paymentStatus := utils.PaymentStatusIntToString(order.PaymentStatus)
 			orderStatus := utils.OrderStatusIntToString(order.OrderStatus)
 
-			packageImage, err := utils.GetImage(order.PackageImageURL)
-			if err != nil {
-				log.Printf("error reading the picture: %v", err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reading the picture: %v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
-
 			temp := types.OrderCarrierReturnPayload{
 				Listing:          order.Listing,
 				ID:               order.ID,
@@ -324,10 +323,10 @@ func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
 				Price:            order.Price,
 				Currency:         order.Currency,
 				PackageContent:   order.PackageContent,
-				PackageImage:     packageImage,
+				PackageImageURL:  utils.ImageURL(order.PackageImageURL),
 				PaymentStatus:    paymentStatus,
 				PaidAt:           order.PaidAt.Time,
-				PaymentProofURL:  order.PaymentProofURL.String,
+				PaymentProofURL:  utils.ImageURL(order.PaymentProofURL.String),
 				OrderStatus:      orderStatus,
 				PackageLocation:  order.PackageLocation,
 				Notes:            order.Notes.String,
@@ -353,14 +352,6 @@ func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
 
 			orderStatus := utils.OrderStatusIntToString(order.OrderStatus)
 
-			// packageImage, err := utils.GetImage(order.PackageImageURL)
-			// if err != nil {
-			// 	log.Printf("error reading the picture: %v", err)
-			// 	logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reading the picture: %v", err))
-			// 	utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			// 	return
-			// }
-
 			temp := types.OrderGiverReturnPayload{
 				Listing:         order.Listing,
 				ID:              order.ID,
@@ -368,10 +359,10 @@ func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
 				Price:           order.Price,
 				Currency:        order.Currency,
 				PackageContent:  order.PackageContent,
-				// PackageImage:    packageImage,
+				PackageImageURL: utils.ImageURL(order.PackageImageURL),
 				PaymentStatus:   paymentStatus,
 				PaidAt:          order.PaidAt.Time,
-				PaymentProofURL: order.PaymentProofURL.String,
+				PaymentProofURL: utils.ImageURL(order.PaymentProofURL.String),
 				OrderStatus:     orderStatus,
 				PackageLocation: order.PackageLocation,
 				Notes:           order.Notes.String,
@@ -411,23 +402,9 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
-		return
-	}
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -449,14 +426,6 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 
 		orderStatus := utils.OrderStatusIntToString(order.OrderStatus)
 
-		packageImage, err := utils.GetImage(order.PackageImageURL)
-		if err != nil {
-			log.Printf("error reading the picture: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reading the picture: %v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
-
 		returnOrder = types.OrderCarrierReturnPayload{
 			Listing:          order.Listing,
 			ID:               order.ID,
@@ -466,10 +435,10 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 			Price:            order.Price,
 			Currency:         order.Currency,
 			PackageContent:   order.PackageContent,
-			PackageImage:     packageImage,
+			PackageImageURL:  utils.ImageURL(order.PackageImageURL),
 			PaymentStatus:    paymentStatus,
 			PaidAt:           order.PaidAt.Time,
-			PaymentProofURL:  order.PaymentProofURL.String,
+			PaymentProofURL:  utils.ImageURL(order.PaymentProofURL.String),
 			OrderStatus:      orderStatus,
 			PackageLocation:  order.PackageLocation,
 			Notes:            order.Notes.String,
@@ -489,14 +458,6 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 
 		orderStatus := utils.OrderStatusIntToString(order.OrderStatus)
 
-		packageImage, err := utils.GetImage(order.PackageImageURL)
-		if err != nil {
-			log.Printf("error reading the picture: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reading the picture: %v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
-
 		returnOrder = types.OrderGiverReturnPayload{
 			Listing:         order.Listing,
 			ID:              order.ID,
@@ -504,10 +465,10 @@ func (h *Handler) handleGetDetail(w http.ResponseWriter, r *http.Request) {
 			Price:           order.Price,
 			Currency:        order.Currency,
 			PackageContent:  order.PackageContent,
-			PackageImage:    packageImage,
+			PackageImageURL: utils.ImageURL(order.PackageImageURL),
 			PaymentStatus:   paymentStatus,
 			PaidAt:          order.PaidAt.Time,
-			PaymentProofURL: order.PaymentProofURL.String,
+			PaymentProofURL: utils.ImageURL(order.PaymentProofURL.String),
 			OrderStatus:     orderStatus,
 			PackageLocation: order.PackageLocation,
 			Notes:           order.Notes.String,
@@ -542,26 +503,40 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
+	order, err := h.orderStore.GetOrderByID(payload.ID)
+	if err != nil {
+		log.Printf("order not found: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("order not found: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("order not found"))
 		return
 	}
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+
+	if order.GiverID != user.ID {
+		utils.WriteError(w, http.StatusForbidden, types.ErrNotOrderParty)
 		return
 	}
 
+	// an open order is cancelled first, so any weight it reserved goes back to the listing
+	if !lifecycle.IsClosed(order.OrderStatus) {
+		err = h.orderUnitOfWork.TransitionOrder(order.ID, constants.ORDER_STATUS_CANCELLED, constants.ACTOR_GIVER, "", "order deleted")
+		if errors.Is(err, types.ErrIllegalTransition) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		if err != nil {
+			log.Printf("error cancel order: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error cancel order: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+	}
+
 	err = h.orderStore.DeleteOrder(payload.ID, user.ID)
 	if err != nil {
 		log.Printf("error deleting order: %v", err)
@@ -578,23 +553,9 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 	reqType := vars["reqType"]
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
-	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
-		return
-	}
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -627,6 +588,11 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		if payload.ListingID != order.ListingID {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("the listing of an order cannot be changed"))
+			return
+		}
+
 		listing, err := h.listingStore.GetListingByID(payload.ListingID)
 		if err != nil {
 			log.Printf("listing id %d not found: %v", payload.ListingID, err)
@@ -641,36 +607,31 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		paymentStatus := utils.PaymentStatusStringToInt(payload.PaymentStatus)
-		if paymentStatus == -1 {
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown payment status"))
+		actor, err := lifecycle.ResolveActor(user.ID, order.GiverID, listing.CarrierID)
+		if err != nil {
+			utils.WriteError(w, http.StatusForbidden, err)
 			return
 		}
 
-		currency, err := h.currencyStore.GetCurrencyByName(payload.Currency)
+		// the price always comes from the listing's rate, never from the client
+		quote := pricing.Quote(listing, payload.Weight, payload.Insurance)
+
+		err = pricing.CheckClientPrice(quote, payload.Price, payload.Currency)
 		if err != nil {
-			log.Println(err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			utils.WriteError(w, http.StatusBadRequest, err)
 			return
 		}
 
+		currency, err := h.currencyStore.GetCurrencyByName(quote.Currency)
 		if currency == nil {
-			err = h.currencyStore.CreateCurrency(payload.Currency)
-			if err != nil {
-				log.Printf("error create currency: %v", err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create currency: %v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
-
-			currency, err = h.currencyStore.GetCurrencyByName(payload.Currency)
-			if err != nil {
-				log.Println(err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("currency not found"))
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
 		}
 
 		packageImgURL := order.PackageImageURL
@@ -680,31 +641,24 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("image size exceeds the limit of 10MB"))
 				return
 			} else if len(payload.PackageImage) > 0 {
-				var imageExtension string
-
-				// check image type
-				mimeType := http.DetectContentType(payload.PackageImage)
-				switch mimeType {
-				case "image/jpeg":
-					imageExtension = ".jpg"
-				case "image/png":
-					imageExtension = ".png"
-				default:
-					utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported image type"))
+				// decode, strip the metadata and build the variants
+				packageImage, err := utils.ProcessImage(payload.PackageImage)
+				if err != nil {
+					utils.WriteError(w, http.StatusBadRequest, err)
 					return
 				}
 
-				filePath := constants.PACKAGE_IMG_DIR_PATH + utils.GeneratePictureFileName(imageExtension)
+				filePath := constants.PACKAGE_IMG_KEY_PREFIX + utils.GeneratePictureFileName(packageImage.Extension)
 
 				isPackageImageURLExist := h.orderStore.IsPackageImageURLExist(filePath)
 
 				for isPackageImageURLExist {
-					filePath = constants.PACKAGE_IMG_DIR_PATH + utils.GeneratePictureFileName(imageExtension)
+					filePath = constants.PACKAGE_IMG_KEY_PREFIX + utils.GeneratePictureFileName(packageImage.Extension)
 					isPackageImageURLExist = h.orderStore.IsPackageImageURLExist(filePath)
 				}
 
 				// save the image
-				err := utils.SavePackageImage(payload.PackageImage, filePath)
+				err = utils.SavePackageImage(h.blobStore, packageImage, filePath)
 				if err != nil {
 					logger.WriteServerLog(fmt.Sprintf("error saving package image: %v", err))
 				}
@@ -713,25 +667,19 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		err = h.listingStore.AddWeightAvailable(listing.ID, order.Weight)
-		if err != nil {
-			log.Printf("error reset weight available: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reset weight available: %v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
-		}
-
-		err = h.orderStore.ModifyOrder(order.ID, types.Order{
+		// releasing the reserved weight and modifying the order happen in one transaction
+		err = h.orderUnitOfWork.ModifyOrder(order.ID, actor, types.Order{
 			Weight:          payload.Weight,
-			Price:           payload.Price,
+			Price:           quote.Total,
 			CurrencyID:      currency.ID,
 			PackageContent:  payload.PackageContent,
 			PackageImageURL: packageImgURL,
-			PaymentStatus:   paymentStatus,
-			OrderStatus:     constants.ORDER_STATUS_WAITING,
-			PackageLocation: payload.PackageLocation,
 			Notes:           payload.Notes,
 		})
+		if errors.Is(err, types.ErrIllegalTransition) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
 		if err != nil {
 			log.Printf("error modify order: %v", err)
 			logger.WriteServerLog(fmt.Sprintf("error modify order: %v", err))
@@ -741,32 +689,30 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 
 		subject := "Re-confirm Needed!"
 
-		body := utils.CreateEmailBodyOfOrder(subject, user.Name, listing.Destination, currency.Name, payload.Notes, payload.PackageContent, payload.Weight, payload.Price)
+		body := utils.CreateEmailBodyOfOrder(subject, user.Name, listing.Destination, currency.Name, payload.Notes, payload.PackageContent, payload.Weight, quote.Total)
 
-		err = utils.SendEmail(carrier.Email, subject, body, "", "")
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+			To:      carrier.Email,
+			Title:   subject,
+			Body:    body,
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error send email to carrier: %v", err))
 		}
 
-		fcmHistory := types.FCMHistory{
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
 			ToUserID: carrier.ID,
-			ToToken:  carrier.FCMToken,
 			Data: types.FCMData{
 				Type:    "confirm_order",
 				OrderID: fmt.Sprintf("%d", order.ID),
 			},
 			Title: subject,
 			Body:  fmt.Sprintf("Confirm order no. %d before %s 23:59 KST (GMT +09)", order.ID, time.Now().Local().AddDate(0, 0, 2).Format("02 Jan 2006")),
-		}
-
-		fcmHistory.Response, err = utils.SendFCMToOne(fcmHistory)
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
-		} else {
-			err = h.fcmHistoryStore.CreateFCMHistory(fcmHistory)
-			if err != nil {
-				logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
-			}
 		}
 
 		returnMsg = "order modified"
@@ -813,8 +759,14 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if carrier.ID != user.ID {
-			utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		if payload.OrderStatus != "" && orderStatus == -1 {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown order status"))
+			return
+		}
+
+		actor, err := lifecycle.ResolveActor(user.ID, order.GiverID, carrier.ID)
+		if err != nil {
+			utils.WriteError(w, http.StatusForbidden, err)
 			return
 		}
 
@@ -824,7 +776,19 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		err = h.orderStore.UpdatePackageLocation(order.ID, orderStatus, payload.PackageLocation)
+		if orderStatus == -1 || orderStatus == order.OrderStatus {
+			err = h.orderUnitOfWork.UpdatePackageLocation(order.ID, actor, payload.PackageLocation, payload.Note)
+		} else {
+			err = h.orderUnitOfWork.TransitionOrder(order.ID, orderStatus, actor, payload.PackageLocation, payload.Note)
+		}
+		if errors.Is(err, types.ErrIllegalTransition) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, types.ErrNotEnoughWeight) {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
 		if err != nil {
 			log.Printf("error update package location: %v", err)
 			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error update package location: %v", err))
@@ -835,43 +799,51 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 		subject := "Your Package Location is Updated"
 		body := fmt.Sprintf("<h4>Your package for order no. %d has an update!</h4><h4>It status now is</h4><br><h2>%s</h2>",
 			order.ID, payload.PackageLocation)
-		err = utils.SendEmail(giver.Email, subject, body, "", "")
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+			To:      giver.Email,
+			Title:   subject,
+			Body:    body,
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error sending email to %s for updating package location: %v", giver.Email, err))
 		}
 
-		fcmHistory := types.FCMHistory{
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
 			ToUserID: giver.ID,
-			ToToken:  giver.FCMToken,
 			Data: types.FCMData{
 				Type:    "order_updated",
 				OrderID: fmt.Sprintf("%d", order.ID),
 			},
 			Title: subject,
 			Body:  fmt.Sprintf("Package location for order no. %d is %s", order.ID, payload.PackageLocation),
-		}
-
-		fcmHistory.Response, err = utils.SendFCMToOne(fcmHistory)
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
-		} else {
-			err = h.fcmHistoryStore.CreateFCMHistory(fcmHistory)
-			if err != nil {
-				logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
-			}
 		}
 
 		returnMsg = "package location updated"
 	} else if reqType == "payment-status" {
 		var payload types.UpdatePaymentStatusPayload
 
-		if err := utils.ParseJSON(r, &payload); err != nil {
+		// multipart/form-data or JSON
+		uploadedImage, err := utils.ParseUpload(w, r, &payload, "paymentProof", constants.PAYMENT_PROOF_MAX_BYTES)
+		if errors.Is(err, types.ErrUploadTooLarge) {
+			utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("the image size exceeds the limit of 10MB"))
+			return
+		}
+		if err != nil {
 			log.Printf("payload error: %v \n", err)
 			logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
 			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
 			return
 		}
 
+		if uploadedImage != nil {
+			payload.PaymentProof = uploadedImage
+		}
+
 		// validate the payload
 		if err := utils.Validate.Struct(payload); err != nil {
 			errors := err.(validator.ValidationErrors)
@@ -895,6 +867,26 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		listing, err := h.listingStore.GetListingByID(order.ListingID)
+		if err != nil {
+			log.Printf("error get listing: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get listing: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+
+		actor, err := lifecycle.ResolveActor(user.ID, order.GiverID, listing.CarrierID)
+		if err != nil {
+			utils.WriteError(w, http.StatusForbidden, err)
+			return
+		}
+
+		err = lifecycle.CheckPaymentUpdate(order.OrderStatus, actor)
+		if err != nil {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+
 		var paymentProofUrl string
 		if paymentStatus == constants.PAYMENT_STATUS_COMPLETED {
 			if len(payload.PaymentProof) < 1 {
@@ -907,29 +899,23 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			var imageExtension string
-
-			mimeType := http.DetectContentType(payload.PaymentProof)
-			switch mimeType {
-			case "image/jpeg":
-				imageExtension = ".jpg"
-			case "image/png":
-				imageExtension = ".png"
-			default:
-				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported image type"))
+			// decode, strip the metadata and build the variants
+			paymentProof, err := utils.ProcessImage(payload.PaymentProof)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, err)
 				return
 			}
 
-			filePath := constants.PAYMENT_PROOF_DIR_PATH + utils.GeneratePictureFileName(imageExtension)
+			filePath := constants.PAYMENT_PROOF_KEY_PREFIX + utils.GeneratePictureFileName(paymentProof.Extension)
 
 			isPaymentProofUrlExist := h.orderStore.IsPaymentProofURLExist(filePath)
 
 			for isPaymentProofUrlExist {
-				filePath = constants.PAYMENT_PROOF_DIR_PATH + utils.GeneratePictureFileName(imageExtension)
+				filePath = constants.PAYMENT_PROOF_KEY_PREFIX + utils.GeneratePictureFileName(paymentProof.Extension)
 				isPaymentProofUrlExist = h.orderStore.IsPaymentProofURLExist(filePath)
 			}
 
-			err = utils.SavePaymentProof(payload.PaymentProof, filePath)
+			err = utils.SavePaymentProof(h.blobStore, paymentProof, filePath)
 			if err != nil {
 				log.Printf("error saving payment proof: %v", err)
 				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error saving payment proof: %v", err))
@@ -937,59 +923,14 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 
-			listing, err := h.listingStore.GetListingByID(order.ListingID)
-			if err != nil {
-				log.Printf("error get listing: %v", err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get listing: %v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
-
-			carrier, err := h.userStore.GetUserByID(listing.CarrierID)
-			if err != nil {
-				log.Printf("error get carrier: %v", err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get carrier: %v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
-
-			subject := fmt.Sprintf("Payment Completed for Order No. %d", order.ID)
-			body := fmt.Sprintf("<h4>Payment has been</h4><br><h2>completed</h2><br><h4>by %s for order no. %d!</h4><p>Below is the payment proof!<p>",
-				user.Name, order.ID)
-
-			err = utils.SendEmail(carrier.Email, subject, body, filePath, "Payment Proof")
-			if err != nil {
-				log.Printf("error sending payment completion email to carrier: %v", err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error sending payment completion email to carrier: %v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
-
-			fcmHistory := types.FCMHistory{
-				ToUserID: carrier.ID,
-				ToToken:  carrier.FCMToken,
-				Data: types.FCMData{
-					Type:    "payment_updated",
-					OrderID: fmt.Sprintf("%d", order.ID),
-				},
-				Title: subject,
-				Body:  fmt.Sprintf("Payment has been completed by %s for order no. %d!", user.Name, order.ID),
-			}
-
-			fcmHistory.Response, err = utils.SendFCMToOne(fcmHistory)
-			if err != nil {
-				logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
-			} else {
-				err = h.fcmHistoryStore.CreateFCMHistory(fcmHistory)
-				if err != nil {
-					logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
-				}
-			}
-
 			paymentProofUrl = filePath
 		}
 
-		err = h.orderStore.UpdatePaymentStatus(order.ID, paymentStatus, paymentProofUrl)
+		err = h.orderUnitOfWork.UpdatePaymentStatus(order.ID, actor, paymentStatus, paymentProofUrl)
+		if errors.Is(err, types.ErrIllegalTransition) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
 		if err != nil {
 			log.Printf("error update payment status: %v", err)
 			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error update payment status: %v", err))
@@ -997,6 +938,11 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
+		// only told once the update is committed, the locked re-check may still refuse it
+		if paymentStatus == constants.PAYMENT_STATUS_COMPLETED {
+			h.notifyPaymentCompleted(order.ID, listing.CarrierID, user, paymentProofUrl)
+		}
+
 		returnMsg = "payment status updated"
 	} else if reqType == "order-status" {
 		var payload types.UpdateOrderStatusPayload
@@ -1031,34 +977,30 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		if orderStatus == constants.ORDER_STATUS_CONFIRMED {
-			listing, err := h.listingStore.GetListingByID(order.ListingID)
-			if listing == nil {
-				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing not found"))
-				return
-			}
-			if err != nil {
-				log.Println(err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
-
-			if listing.CarrierID != user.ID {
-				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("you are not the carrier"))
-				return
-			}
+		listing, err := h.listingStore.GetListingByID(order.ListingID)
+		if listing == nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing not found"))
+			return
+		}
+		if err != nil {
+			log.Println(err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
 
-			if order.OrderStatus != constants.ORDER_STATUS_WAITING {
-				utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("order is not in the waiting status"))
-				return
-			}
+		actor, err := lifecycle.ResolveActor(user.ID, order.GiverID, listing.CarrierID)
+		if err != nil {
+			utils.WriteError(w, http.StatusForbidden, err)
+			return
+		}
 
+		if orderStatus == constants.ORDER_STATUS_CONFIRMED && order.OrderStatus == constants.ORDER_STATUS_WAITING {
 			deadline := time.Date(time.Now().Local().Year(), time.Now().Local().Month(), time.Now().Local().Day(), 0, 0, 0, 0, time.Now().Local().Location())
 			deadline = deadline.AddDate(0, 0, 2)
 
 			if order.OrderConfirmationDeadline.After(deadline) {
-				err = h.orderStore.UpdateOrderStatus(order.ID, constants.ORDER_STATUS_CANCELLED, "")
+				err = h.orderUnitOfWork.TransitionOrder(order.ID, constants.ORDER_STATUS_CANCELLED, constants.ACTOR_SYSTEM, "", "confirmation deadline passed")
 				if err != nil {
 					log.Printf("error update order status: %v", err)
 					logFile, _ := logger.WriteServerLog(fmt.Sprintf("error update order status: %v", err))
@@ -1069,33 +1011,21 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("order has been automatically canceled due to the deadline has passed"))
 				return
 			}
-
-			if (listing.WeightAvailable - order.Weight) < 0.0 {
-				utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("weight available is not enough"))
-				return
-			}
-
-			err = h.listingStore.SubtractWeightAvailable(listing.ID, order.Weight)
-			if err != nil {
-				log.Printf("error update weight available: %v", err)
-				logFile, _ := logger.WriteServerLog(fmt.Sprintf("error update weight available: %v", err))
-				utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-				return
-			}
 		}
 
-		err = h.orderStore.UpdateOrderStatus(order.ID, orderStatus, payload.PackageLocation)
-		if err != nil {
-			log.Printf("error update order status: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error update order status: %v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		// re-checks the status and reserves or releases the weight atomically, so two confirmations can't oversell
+		err = h.orderUnitOfWork.TransitionOrder(order.ID, orderStatus, actor, payload.PackageLocation, payload.Note)
+		if errors.Is(err, types.ErrIllegalTransition) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+		if errors.Is(err, types.ErrNotEnoughWeight) {
+			utils.WriteError(w, http.StatusBadRequest, err)
 			return
 		}
-
-		listing, err := h.listingStore.GetListingByID(order.ListingID)
 		if err != nil {
-			log.Printf("error get listing: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get listing: %v", err))
+			log.Printf("error update order status: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error update order status: %v", err))
 			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 			return
 		}
@@ -1122,33 +1052,28 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 			fcmBody = fmt.Sprintf("Order number %d has been updated into %s", order.ID, strings.ToUpper(payload.OrderStatus))
 		}
 
-		err = utils.SendEmail(giver.Email, subject, emailBody, "", "")
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+			To:      giver.Email,
+			Title:   subject,
+			Body:    emailBody,
+		})
 		if err != nil {
-			log.Printf("error sending update order status email to carrier: %v", err)
-			logFile, _ := logger.WriteServerLog(fmt.Sprintf("error sending update order status email to carrier: %v", err))
-			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-			return
+			logger.WriteServerLog(fmt.Sprintf("error sending update order status email to giver: %v", err))
 		}
 
-		fcmHistory := types.FCMHistory{
+		err = h.notifier.Notify(types.NotificationMessage{
+			Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
 			ToUserID: giver.ID,
-			ToToken:  giver.FCMToken,
 			Data: types.FCMData{
 				Type:    "order_updated",
 				OrderID: fmt.Sprintf("%d", order.ID),
 			},
 			Title: subject,
 			Body:  fcmBody,
-		}
-
-		fcmHistory.Response, err = utils.SendFCMToOne(fcmHistory)
+		})
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
-		} else {
-			err = h.fcmHistoryStore.CreateFCMHistory(fcmHistory)
-			if err != nil {
-				logger.WriteServerLog(fmt.Sprintf("error update fcm history: %v", err))
-			}
 		}
 
 		returnMsg = "order status updated"
@@ -1157,6 +1082,108 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 	utils.WriteJSON(w, http.StatusCreated, returnMsg)
 }
 
+// tells the carrier the giver has paid, with the proof attached to the email
+func (h *Handler) notifyPaymentCompleted(orderId int, carrierId int, payer *types.User, paymentProofUrl string) {
+	carrier, err := h.userStore.GetUserByID(carrierId)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("payment of order %d updated but error get carrier: %v", orderId, err))
+		return
+	}
+
+	subject := fmt.Sprintf("Payment Completed for Order No. %d", orderId)
+	body := fmt.Sprintf("<h4>Payment has been</h4><br><h2>completed</h2><br><h4>by %s for order no. %d!</h4><p>Below is the payment proof!<p>",
+		payer.Name, orderId)
+
+	err = h.notifier.Notify(types.NotificationMessage{
+		Channel:        constants.NOTIFICATION_CHANNEL_EMAIL,
+		To:             carrier.Email,
+		Title:          subject,
+		Body:           body,
+		AttachmentURL:  paymentProofUrl,
+		AttachmentName: "Payment Proof",
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending payment completion email to carrier: %v", err))
+	}
+
+	err = h.notifier.Notify(types.NotificationMessage{
+		Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
+		ToUserID: carrier.ID,
+		Data: types.FCMData{
+			Type:    "payment_updated",
+			OrderID: fmt.Sprintf("%d", orderId),
+		},
+		Title: subject,
+		Body:  fmt.Sprintf("Payment has been completed by %s for order no. %d!", payer.Name, orderId),
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending notification to carrier: %v", err))
+	}
+}
+
+func (h *Handler) handleGetTimeline(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	orderId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid order id"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	order, err := h.orderStore.GetOrderByID(orderId)
+	if err != nil {
+		log.Printf("order not found: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("order not found: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("order not found"))
+		return
+	}
+
+	listing, err := h.listingStore.GetListingByID(order.ListingID)
+	if err != nil {
+		log.Printf("listing id %d not found: %v", order.ListingID, err)
+		logger.WriteServerLog(fmt.Sprintf("listing id %d not found: %v", order.ListingID, err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("listing not found"))
+		return
+	}
+
+	_, err = lifecycle.ResolveActor(user.ID, order.GiverID, listing.CarrierID)
+	if err != nil {
+		utils.WriteError(w, http.StatusForbidden, err)
+		return
+	}
+
+	events, err := h.orderStore.GetOrderEventsByOrderID(order.ID)
+	if err != nil {
+		log.Printf("error get order events: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get order events: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	timeline := make([]types.OrderEventReturnPayload, 0)
+
+	for _, event := range events {
+		timeline = append(timeline, types.OrderEventReturnPayload{
+			ID:              event.ID,
+			EventType:       utils.OrderEventIntToString(event.EventType),
+			Actor:           utils.ActorIntToString(event.Actor),
+			OrderStatus:     utils.OrderStatusIntToString(event.OrderStatus),
+			PaymentStatus:   utils.PaymentStatusIntToString(event.PaymentStatus),
+			PackageLocation: event.PackageLocation,
+			Note:            event.Note,
+			CreatedAt:       event.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, timeline)
+}
+
 func (h *Handler) handleGetPaymentDetails(w http.ResponseWriter, r *http.Request) {
 	var payload types.GetPaymentDetailsPayload
 
@@ -1177,47 +1204,46 @@ func (h *Handler) handleGetPaymentDetails(w http.ResponseWriter, r *http.Request
 	}
 
 	// validate token
-	_, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	carrier, err := h.userStore.GetUserByID(payload.CarrierID)
-	if carrier == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
+	order, err := h.orderStore.GetGiverOrderByID(payload.OrderID, user.ID)
+	if err != nil {
+		log.Printf("order not found: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("order not found: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("order not found"))
 		return
 	}
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+
+	// the only place the full account number is shown, and only to a giver the carrier agreed to deliver for
+	if order.OrderStatus != constants.ORDER_STATUS_CONFIRMED && order.OrderStatus != constants.ORDER_STATUS_EN_ROUTE &&
+		order.OrderStatus != constants.ORDER_STATUS_COMPLETED {
+		utils.WriteError(w, http.StatusForbidden, fmt.Errorf("the order is not confirmed by the carrier"))
 		return
 	}
 
-	bankDetail, err := h.bankDetailStore.GetBankDetailByUserID(carrier.ID)
+	payoutMethod, err := h.payoutMethodStore.GetPayoutMethodForCurrency(order.Listing.CarrierID, order.Currency)
 	if err != nil {
-		log.Printf("error fetching bank data: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error fetching bank data: %v", err))
+		log.Printf("error fetching payout method: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error fetching payout method: %v", err))
 		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
 
 	var returnMsg interface{}
 
-	if bankDetail == nil {
+	if payoutMethod == nil {
 		returnMsg = map[string]string{
 			"status":  "not exist",
-			"message": "Carrier hasn't updated his/her bank account! Please contact him/her directly using email!",
+			"message": fmt.Sprintf("Carrier hasn't added a payout method for %s! Please contact him/her directly using email!", order.Currency),
 		}
 	} else {
-		returnMsg = map[string]string{
-			"status":         "exist",
-			"bank_name":      bankDetail.BankName,
-			"account_number": bankDetail.AccountNumber,
-			"account_holder": bankDetail.AccountHolder,
+		returnMsg = map[string]interface{}{
+			"status":       "exist",
+			"payoutMethod": utils.PayoutMethodPayload(*payoutMethod, true),
 		}
 	}
 
@@ -0,0 +1,123 @@
+package order
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/service/notification"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// the fakes embed the interface, a call the test didn't expect panics on the nil value
+
+type fakeOrderStore struct {
+	types.OrderStore
+	order *types.Order
+}
+
+func (s *fakeOrderStore) GetOrderByID(id int) (*types.Order, error) {
+	return s.order, nil
+}
+
+type fakeListingStore struct {
+	types.ListingStore
+	listing *types.ListingReturnFromDB
+}
+
+func (s *fakeListingStore) GetListingByID(id int) (*types.ListingReturnFromDB, error) {
+	return s.listing, nil
+}
+
+type fakeUserStore struct {
+	types.UserStore
+	users map[int]*types.User
+}
+
+func (s *fakeUserStore) GetUserByID(id int) (*types.User, error) {
+	return s.users[id], nil
+}
+
+type fakeOrderUnitOfWork struct {
+	types.OrderUnitOfWork
+	toStatus int
+	actor    int
+}
+
+func (u *fakeOrderUnitOfWork) TransitionOrder(orderId int, toStatus int, actor int, packageLocation string, note string) error {
+	u.toStatus = toStatus
+	u.actor = actor
+	return nil
+}
+
+func TestConfirmOrderNotifiesGiver(t *testing.T) {
+	giver := &types.User{ID: 1, Name: "Giver", Email: "giver@example.com"}
+	carrier := &types.User{ID: 2, Name: "Carrier", Email: "carrier@example.com"}
+
+	orderStore := &fakeOrderStore{order: &types.Order{
+		ID:                        10,
+		ListingID:                 20,
+		GiverID:                   giver.ID,
+		OrderStatus:               constants.ORDER_STATUS_WAITING,
+		OrderConfirmationDeadline: time.Now(),
+	}}
+	listingStore := &fakeListingStore{listing: &types.ListingReturnFromDB{
+		ID:          20,
+		CarrierID:   carrier.ID,
+		Destination: "Seoul, KR",
+	}}
+	userStore := &fakeUserStore{users: map[int]*types.User{giver.ID: giver, carrier.ID: carrier}}
+	unitOfWork := &fakeOrderUnitOfWork{}
+	recorder := notification.NewRecorder()
+
+	handler := NewHandler(orderStore, userStore, listingStore, nil, recorder, nil, unitOfWork, nil)
+
+	router := mux.NewRouter()
+	handler.RegisterRoutes(router)
+
+	body := []byte(`{"id": 10, "orderStatus": "confirmed"}`)
+	req := httptest.NewRequest(http.MethodPatch, "/order/order-status", bytes.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), jwt.UserKey, carrier))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d, body: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+
+	if unitOfWork.toStatus != constants.ORDER_STATUS_CONFIRMED || unitOfWork.actor != constants.ACTOR_CARRIER {
+		t.Errorf("TransitionOrder got status %d by actor %d, want %d by %d",
+			unitOfWork.toStatus, unitOfWork.actor, constants.ORDER_STATUS_CONFIRMED, constants.ACTOR_CARRIER)
+	}
+
+	messages := recorder.Messages()
+	if len(messages) != 2 {
+		t.Fatalf("recorded %d messages, want 2: %+v", len(messages), messages)
+	}
+
+	email, push := messages[0], messages[1]
+
+	if email.Channel != constants.NOTIFICATION_CHANNEL_EMAIL || email.To != giver.Email {
+		t.Errorf("first message = %s to %q, want an email to %q", email.Channel, email.To, giver.Email)
+	}
+
+	if push.Channel != constants.NOTIFICATION_CHANNEL_PUSH || push.ToUserID != giver.ID {
+		t.Errorf("second message = %s to user %d, want a push to user %d", push.Channel, push.ToUserID, giver.ID)
+	}
+
+	if push.Data.Type != "order_updated" || push.Data.OrderID != "10" {
+		t.Errorf("push data = %+v, want order_updated for order 10", push.Data)
+	}
+
+	wantTitle := "Order Confirmed for Order No. 10"
+	if email.Title != wantTitle || push.Title != wantTitle {
+		t.Errorf("titles = %q and %q, want %q", email.Title, push.Title, wantTitle)
+	}
+}
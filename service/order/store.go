@@ -18,23 +18,7 @@ func NewStore(db *sql.DB) *Store {
 }
 
 func (s *Store) CreateOrder(order types.Order) error {
-	values := "?"
-	for i := 0; i < 8; i++ {
-		values += ", ?"
-	}
-
-	query := `INSERT INTO order_list (
-					listing_id, giver_id, weight, price,
-					currency_id, package_content, package_img_url, notes, 
-					order_confirmation_deadline) 
-					VALUES (` + values + `)`
-
-	deadline := time.Date(time.Now().Local().Year(), time.Now().Local().Month(), time.Now().Local().Day(), 0, 0, 0, 0, time.Now().Local().Location())
-	deadline = deadline.AddDate(0, 0, 2)
-
-	_, err := s.db.Exec(query, order.ListingID, order.GiverID, order.Weight,
-		order.Price, order.CurrencyID, order.PackageContent, order.PackageImageURL,
-		order.Notes, deadline)
+	_, err := createOrder(s.db, order)
 	if err != nil {
 		return err
 	}
@@ -42,6 +26,11 @@ func (s *Store) CreateOrder(order types.Order) error {
 	return nil
 }
 
+// same as CreateOrder, but inside the caller's transaction and returning the new id
+func (s *Store) CreateOrderTx(tx *sql.Tx, order types.Order) (int, error) {
+	return createOrder(tx, order)
+}
+
 func (s *Store) GetOrderByID(id int) (*types.Order, error) {
 	query := `SELECT * FROM order_list WHERE id = ? AND deleted_at IS NULL`
 	rows, err := s.db.Query(query, id)
@@ -67,11 +56,6 @@ func (s *Store) GetOrderByID(id int) (*types.Order, error) {
 }
 
 func (s *Store) GetOrdersByCarrierID(id int) ([]types.OrderCarrierReturnFromDB, error) {
-	err := s.UpdateOrderStatusByDeadline()
-	if err != nil {
-		return nil, err
-	}
-
 	query := `SELECT l.id, l.destination, l.departure_date, 
 					 o.id, 
 					 user.name, user.phone_number, 
@@ -247,24 +231,11 @@ func (s *Store) DeleteOrder(orderId int, userId int) error {
 }
 
 func (s *Store) ModifyOrder(id int, order types.Order) error {
-	query := `UPDATE order_list SET weight = ?, price = ?, 
-					currency_id = ?, package_content = ?, package_img_url = ?, 
-					payment_status = ?, package_location = ?, order_confirmation_deadline = ?, 
-					order_status = ?, notes = ?, last_modified_at = ? 
-				WHERE id = ? AND deleted_at IS NULL`
-
-	deadline := time.Date(time.Now().Local().Year(), time.Now().Local().Month(), time.Now().Local().Day(), 0, 0, 0, 0, time.Now().Local().Location())
-	deadline = deadline.AddDate(0, 0, 2)
-
-	_, err := s.db.Exec(query, order.Weight, order.Price, order.CurrencyID,
-		order.PackageContent, order.PackageImageURL, order.PaymentStatus,
-		order.PackageLocation, deadline, order.OrderStatus, order.Notes,
-		time.Now(), id)
-	if err != nil {
-		return err
-	}
+	return modifyOrder(s.db, id, order)
+}
 
-	return nil
+func (s *Store) ModifyOrderTx(tx *sql.Tx, id int, order types.Order) error {
+	return modifyOrder(tx, id, order)
 }
 
 func (s *Store) UpdatePackageLocation(id int, orderStatus int, packageLocation string) error {
@@ -289,19 +260,11 @@ func (s *Store) UpdatePackageLocation(id int, orderStatus int, packageLocation s
 	return nil
 }
 
-func (s *Store) UpdatePaymentStatus(id int, paymentStatus int, paymentProofUrl string) error {
-	var err error
-
-	if paymentStatus == constants.PAYMENT_STATUS_COMPLETED {
-		query := `UPDATE order_list SET payment_status = ?, paid_at = ?, payment_proof_url = ?, 
-				last_modified_at = ? WHERE id = ? AND deleted_at IS NULL`
-		_, err = s.db.Exec(query, paymentStatus, time.Now(), paymentProofUrl, time.Now(), id)
-	} else {
-		query := `UPDATE order_list SET payment_status = ?, last_modified_at = ? 
-					WHERE id = ? AND deleted_at IS NULL`
-		_, err = s.db.Exec(query, paymentStatus, time.Now(), id)
-	}
+func (s *Store) UpdatePackageLocationTx(tx *sql.Tx, id int, packageLocation string) error {
+	query := `UPDATE order_list SET package_location = ?, last_modified_at = ? 
+				WHERE id = ? AND deleted_at IS NULL`
 
+	_, err := tx.Exec(query, packageLocation, time.Now(), id)
 	if err != nil {
 		return err
 	}
@@ -309,26 +272,20 @@ func (s *Store) UpdatePaymentStatus(id int, paymentStatus int, paymentProofUrl s
 	return nil
 }
 
-func (s *Store) UpdateOrderStatus(id int, orderStatus int, packageLocation string) error {
-	if packageLocation != "" {
-		query := `UPDATE order_list SET order_status = ?, package_location = ?, last_modified_at = ? 
-				WHERE id = ? AND deleted_at IS NULL`
+func (s *Store) UpdatePaymentStatus(id int, paymentStatus int, paymentProofUrl string) error {
+	return updatePaymentStatus(s.db, id, paymentStatus, paymentProofUrl)
+}
 
-		_, err := s.db.Exec(query, orderStatus, packageLocation, time.Now(), id)
-		if err != nil {
-			return err
-		}
-	} else {
-		query := `UPDATE order_list SET order_status = ?, last_modified_at = ? 
-				WHERE id = ? AND deleted_at IS NULL`
+func (s *Store) UpdatePaymentStatusTx(tx *sql.Tx, id int, paymentStatus int, paymentProofUrl string) error {
+	return updatePaymentStatus(tx, id, paymentStatus, paymentProofUrl)
+}
 
-		_, err := s.db.Exec(query, orderStatus, time.Now(), id)
-		if err != nil {
-			return err
-		}
-	}
+func (s *Store) UpdateOrderStatus(id int, orderStatus int, packageLocation string) error {
+	return updateOrderStatus(s.db, id, orderStatus, packageLocation)
+}
 
-	return nil
+func (s *Store) UpdateOrderStatusTx(tx *sql.Tx, id int, orderStatus int, packageLocation string) error {
+	return updateOrderStatus(tx, id, orderStatus, packageLocation)
 }
 
 func (s *Store) IsOrderDuplicate(userId int, listingId int) (bool, error) {
@@ -348,6 +305,23 @@ func (s *Store) IsOrderDuplicate(userId int, listingId int) (bool, error) {
 	return (count > 0), nil
 }
 
+// the carrier accepted one of the giver's orders on the listing, it may be on its way or delivered already
+func (s *Store) IsOrderConfirmed(giverId int, listingId int) (bool, error) {
+	query := `SELECT COUNT(*) FROM order_list 
+				WHERE listing_id = ? AND giver_id = ? 
+				AND order_status IN (?, ?, ?) 
+				AND deleted_at IS NULL`
+
+	var count int
+	err := s.db.QueryRow(query, listingId, giverId, constants.ORDER_STATUS_CONFIRMED,
+		constants.ORDER_STATUS_EN_ROUTE, constants.ORDER_STATUS_COMPLETED).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return (count > 0), nil
+}
+
 func (s *Store) IsPaymentProofURLExist(paymentProofUrl string) bool {
 	query := `SELECT COUNT(*) FROM order_list WHERE payment_proof_url = ? 
 											AND deleted_at IS NULL`
@@ -378,23 +352,51 @@ func (s *Store) IsPackageImageURLExist(packageImgUrl string) bool {
 	return (count > 0)
 }
 
-func (s *Store) UpdateOrderStatusByDeadline() error {
-	query := `UPDATE order_list SET order_status = ?, last_modified_at = ? 
+// the package image and payment proof of an order are only for its giver and carrier
+func (s *Store) IsOrderImageVisibleTo(userId int, imageUrl string) (bool, error) {
+	query := `SELECT COUNT(*) FROM order_list AS o 
+				JOIN listing AS l ON l.id = o.listing_id 
+				WHERE (o.package_img_url = ? OR o.payment_proof_url = ?) 
+				AND (o.giver_id = ? OR l.carrier_id = ?) 
+				AND o.deleted_at IS NULL`
+
+	var count int
+	err := s.db.QueryRow(query, imageUrl, imageUrl, userId, userId).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return (count > 0), nil
+}
+
+// returns the waiting orders whose confirmation deadline has passed
+func (s *Store) GetOrdersPastDeadline() ([]types.Order, error) {
+	query := `SELECT * FROM order_list 
 				WHERE order_confirmation_deadline < ? 
 				AND order_status = ? 
 				AND deleted_at IS NULL`
-
-	_, err := s.db.Exec(query, constants.ORDER_STATUS_CANCELLED, time.Now(),
-		time.Now().UTC().Format("2006-01-02 15:04:05"), constants.ORDER_STATUS_WAITING)
+	rows, err := s.db.Query(query, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.ORDER_STATUS_WAITING)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	return nil
+	orders := make([]types.Order, 0)
+
+	for rows.Next() {
+		order, err := scanRowIntoOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, *order)
+	}
+
+	return orders, nil
 }
 
 func (s *Store) GetOrdersByListingID(listingId int) ([]types.OrderBulk, error) {
-	query := `SELECT o.id, user.email 
+	query := `SELECT o.id, user.email, o.order_status 
 				FROM order_list AS o 
 				JOIN listing AS l ON l.id = o.listing_id 
 				JOIN user ON o.giver_id = user.id 
@@ -412,7 +414,7 @@ func (s *Store) GetOrdersByListingID(listingId int) ([]types.OrderBulk, error) {
 	for rows.Next() {
 		orderBulk := new(types.OrderBulk)
 
-		err = rows.Scan(&orderBulk.ID, &orderBulk.GiverEmail)
+		err = rows.Scan(&orderBulk.ID, &orderBulk.GiverEmail, &orderBulk.OrderStatus)
 		if err != nil {
 			return nil, err
 		}
@@ -453,11 +455,6 @@ func (s *Store) GetOrderID(order types.Order) (int, error) {
 }
 
 func (s *Store) GetOrderCountByListingID(listingId int) (int, error) {
-	err := s.UpdateOrderStatusByDeadline()
-	if err != nil {
-		return 0, err
-	}
-
 	query := `SELECT COUNT(*)
 				FROM order_list AS o 
 				JOIN listing AS l ON l.id = o.listing_id 
@@ -474,7 +471,7 @@ func (s *Store) GetOrderCountByListingID(listingId int) (int, error) {
 	}
 
 	var count int
-	err = row.Scan(&count)
+	err := row.Scan(&count)
 	if err != nil {
 		return 0, err
 	}
@@ -482,6 +479,167 @@ func (s *Store) GetOrderCountByListingID(listingId int) (int, error) {
 	return count, nil
 }
 
+// locks the order row until the transaction ends
+func (s *Store) GetOrderByIDForUpdate(tx *sql.Tx, id int) (*types.Order, error) {
+	query := `SELECT * FROM order_list WHERE id = ? AND deleted_at IS NULL FOR UPDATE`
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	order := new(types.Order)
+
+	for rows.Next() {
+		order, err = scanRowIntoOrder(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if order.ID == 0 {
+		return nil, fmt.Errorf("order not found")
+	}
+
+	return order, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func createOrder(db execer, order types.Order) (int, error) {
+	values := "?"
+	for i := 0; i < 8; i++ {
+		values += ", ?"
+	}
+
+	query := `INSERT INTO order_list (
+					listing_id, giver_id, weight, price,
+					currency_id, package_content, package_img_url, notes, 
+					order_confirmation_deadline) 
+					VALUES (` + values + `)`
+
+	deadline := time.Date(time.Now().Local().Year(), time.Now().Local().Month(), time.Now().Local().Day(), 0, 0, 0, 0, time.Now().Local().Location())
+	deadline = deadline.AddDate(0, 0, 2)
+
+	res, err := db.Exec(query, order.ListingID, order.GiverID, order.Weight,
+		order.Price, order.CurrencyID, order.PackageContent, order.PackageImageURL,
+		order.Notes, deadline)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func modifyOrder(db execer, id int, order types.Order) error {
+	query := `UPDATE order_list SET weight = ?, price = ?, 
+					currency_id = ?, package_content = ?, package_img_url = ?, 
+					payment_status = ?, package_location = ?, order_confirmation_deadline = ?, 
+					order_status = ?, notes = ?, last_modified_at = ? 
+				WHERE id = ? AND deleted_at IS NULL`
+
+	deadline := time.Date(time.Now().Local().Year(), time.Now().Local().Month(), time.Now().Local().Day(), 0, 0, 0, 0, time.Now().Local().Location())
+	deadline = deadline.AddDate(0, 0, 2)
+
+	_, err := db.Exec(query, order.Weight, order.Price, order.CurrencyID,
+		order.PackageContent, order.PackageImageURL, order.PaymentStatus,
+		order.PackageLocation, deadline, order.OrderStatus, order.Notes,
+		time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func updateOrderStatus(db execer, id int, orderStatus int, packageLocation string) error {
+	if packageLocation != "" {
+		query := `UPDATE order_list SET order_status = ?, package_location = ?, last_modified_at = ? 
+				WHERE id = ? AND deleted_at IS NULL`
+
+		_, err := db.Exec(query, orderStatus, packageLocation, time.Now(), id)
+		if err != nil {
+			return err
+		}
+	} else {
+		query := `UPDATE order_list SET order_status = ?, last_modified_at = ? 
+				WHERE id = ? AND deleted_at IS NULL`
+
+		_, err := db.Exec(query, orderStatus, time.Now(), id)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) CreateOrderEventTx(tx *sql.Tx, event types.OrderEvent) error {
+	query := `INSERT INTO order_event (
+					order_id, event_type, actor, order_status, 
+					payment_status, package_location, note) 
+					VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := tx.Exec(query, event.OrderID, event.EventType, event.Actor,
+		event.OrderStatus, event.PaymentStatus, event.PackageLocation, event.Note)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// returns the events of the order, oldest first
+func (s *Store) GetOrderEventsByOrderID(orderId int) ([]types.OrderEvent, error) {
+	query := `SELECT * FROM order_event WHERE order_id = ? ORDER BY created_at ASC, id ASC`
+	rows, err := s.db.Query(query, orderId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]types.OrderEvent, 0)
+
+	for rows.Next() {
+		event, err := scanRowIntoOrderEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, *event)
+	}
+
+	return events, nil
+}
+
+func updatePaymentStatus(db execer, id int, paymentStatus int, paymentProofUrl string) error {
+	var err error
+
+	if paymentStatus == constants.PAYMENT_STATUS_COMPLETED {
+		query := `UPDATE order_list SET payment_status = ?, paid_at = ?, payment_proof_url = ?, 
+				last_modified_at = ? WHERE id = ? AND deleted_at IS NULL`
+		_, err = db.Exec(query, paymentStatus, time.Now(), paymentProofUrl, time.Now(), id)
+	} else {
+		query := `UPDATE order_list SET payment_status = ?, last_modified_at = ? 
+					WHERE id = ? AND deleted_at IS NULL`
+		_, err = db.Exec(query, paymentStatus, time.Now(), id)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func scanRowIntoOrder(rows *sql.Rows) (*types.Order, error) {
 	temp := new(struct {
 		ID                        int            `json:"id"`
@@ -635,3 +793,30 @@ func scanRowIntoOrderForGiver(rows *sql.Rows) (*types.OrderGiverReturnFromDB, er
 
 	return order, nil
 }
+
+func scanRowIntoOrderEvent(rows *sql.Rows) (*types.OrderEvent, error) {
+	event := new(types.OrderEvent)
+	var packageLocation sql.NullString
+	var note sql.NullString
+
+	err := rows.Scan(
+		&event.ID,
+		&event.OrderID,
+		&event.EventType,
+		&event.Actor,
+		&event.OrderStatus,
+		&event.PaymentStatus,
+		&packageLocation,
+		&note,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.PackageLocation = packageLocation.String
+	event.Note = note.String
+	event.CreatedAt = event.CreatedAt.Local()
+
+	return event, nil
+}
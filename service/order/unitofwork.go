@@ -0,0 +1,208 @@
+package order
+
+import (
+	"database/sql"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/service/order/lifecycle"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// UnitOfWork applies order changes together with the listing weight they
+// reserve and the timeline event they record. Each operation runs in one
+// transaction with the order row locked, so it applies fully or not at all.
+type UnitOfWork struct {
+	db           *sql.DB
+	orderStore   types.OrderStore
+	listingStore types.ListingStore
+}
+
+func NewUnitOfWork(conn *sql.DB, orderStore types.OrderStore, listingStore types.ListingStore) *UnitOfWork {
+	return &UnitOfWork{
+		db:           conn,
+		orderStore:   orderStore,
+		listingStore: listingStore,
+	}
+}
+
+// inserts a waiting order and starts its timeline, returns the id of the order
+func (u *UnitOfWork) CreateOrder(order types.Order, actor int) (int, error) {
+	var orderId int
+
+	err := db.RunInTx(u.db, func(tx *sql.Tx) error {
+		var err error
+
+		orderId, err = u.CreateOrderTx(tx, order, actor, "")
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return orderId, nil
+}
+
+// same as CreateOrder, but inside the caller's transaction
+func (u *UnitOfWork) CreateOrderTx(tx *sql.Tx, order types.Order, actor int, note string) (int, error) {
+	orderId, err := u.orderStore.CreateOrderTx(tx, order)
+	if err != nil {
+		return 0, err
+	}
+
+	order.ID = orderId
+	order.OrderStatus = constants.ORDER_STATUS_WAITING
+	order.PaymentStatus = constants.PAYMENT_STATUS_PENDING
+
+	err = u.recordEvent(tx, &order, constants.ORDER_EVENT_CREATED, actor, note)
+	if err != nil {
+		return 0, err
+	}
+
+	return orderId, nil
+}
+
+// moves the order to the new status if the lifecycle allows it,
+// reserving or releasing the listing weight on the way
+func (u *UnitOfWork) TransitionOrder(orderId int, toStatus int, actor int, packageLocation string, note string) error {
+	return db.RunInTx(u.db, func(tx *sql.Tx) error {
+		return u.TransitionOrderTx(tx, orderId, toStatus, actor, packageLocation, note)
+	})
+}
+
+// same as TransitionOrder, but inside the caller's transaction
+func (u *UnitOfWork) TransitionOrderTx(tx *sql.Tx, orderId int, toStatus int, actor int, packageLocation string, note string) error {
+	order, err := u.orderStore.GetOrderByIDForUpdate(tx, orderId)
+	if err != nil {
+		return err
+	}
+
+	err = lifecycle.CheckTransition(order.OrderStatus, toStatus, actor)
+	if err != nil {
+		return err
+	}
+
+	err = u.moveReservedWeight(tx, order, toStatus)
+	if err != nil {
+		return err
+	}
+
+	err = u.orderStore.UpdateOrderStatusTx(tx, order.ID, toStatus, packageLocation)
+	if err != nil {
+		return err
+	}
+
+	if packageLocation != "" {
+		order.PackageLocation = packageLocation
+	}
+	order.OrderStatus = toStatus
+
+	return u.recordEvent(tx, order, constants.ORDER_EVENT_STATUS_CHANGED, actor, note)
+}
+
+// releases any reserved weight and puts the modified order back to waiting.
+// the payment and the package location are kept, they only change through
+// UpdatePaymentStatus and UpdatePackageLocation
+func (u *UnitOfWork) ModifyOrder(orderId int, actor int, order types.Order) error {
+	return db.RunInTx(u.db, func(tx *sql.Tx) error {
+		current, err := u.orderStore.GetOrderByIDForUpdate(tx, orderId)
+		if err != nil {
+			return err
+		}
+
+		err = lifecycle.CheckTransition(current.OrderStatus, constants.ORDER_STATUS_WAITING, actor)
+		if err != nil {
+			return err
+		}
+
+		err = u.moveReservedWeight(tx, current, constants.ORDER_STATUS_WAITING)
+		if err != nil {
+			return err
+		}
+
+		order.OrderStatus = constants.ORDER_STATUS_WAITING
+		order.PaymentStatus = current.PaymentStatus
+		order.PackageLocation = current.PackageLocation
+
+		err = u.orderStore.ModifyOrderTx(tx, current.ID, order)
+		if err != nil {
+			return err
+		}
+
+		order.ID = current.ID
+
+		return u.recordEvent(tx, &order, constants.ORDER_EVENT_MODIFIED, actor, "")
+	})
+}
+
+func (u *UnitOfWork) UpdatePackageLocation(orderId int, actor int, packageLocation string, note string) error {
+	return db.RunInTx(u.db, func(tx *sql.Tx) error {
+		order, err := u.orderStore.GetOrderByIDForUpdate(tx, orderId)
+		if err != nil {
+			return err
+		}
+
+		err = lifecycle.CheckLocationUpdate(order.OrderStatus, actor)
+		if err != nil {
+			return err
+		}
+
+		err = u.orderStore.UpdatePackageLocationTx(tx, order.ID, packageLocation)
+		if err != nil {
+			return err
+		}
+
+		order.PackageLocation = packageLocation
+
+		return u.recordEvent(tx, order, constants.ORDER_EVENT_LOCATION_UPDATED, actor, note)
+	})
+}
+
+func (u *UnitOfWork) UpdatePaymentStatus(orderId int, actor int, paymentStatus int, paymentProofUrl string) error {
+	return db.RunInTx(u.db, func(tx *sql.Tx) error {
+		order, err := u.orderStore.GetOrderByIDForUpdate(tx, orderId)
+		if err != nil {
+			return err
+		}
+
+		err = lifecycle.CheckPaymentUpdate(order.OrderStatus, actor)
+		if err != nil {
+			return err
+		}
+
+		err = u.orderStore.UpdatePaymentStatusTx(tx, order.ID, paymentStatus, paymentProofUrl)
+		if err != nil {
+			return err
+		}
+
+		order.PaymentStatus = paymentStatus
+
+		return u.recordEvent(tx, order, constants.ORDER_EVENT_PAYMENT_UPDATED, actor, "")
+	})
+}
+
+// appends the order state after the change to its timeline
+func (u *UnitOfWork) recordEvent(tx *sql.Tx, order *types.Order, eventType int, actor int, note string) error {
+	return u.orderStore.CreateOrderEventTx(tx, types.OrderEvent{
+		OrderID:         order.ID,
+		EventType:       eventType,
+		Actor:           actor,
+		OrderStatus:     order.OrderStatus,
+		PaymentStatus:   order.PaymentStatus,
+		PackageLocation: order.PackageLocation,
+		Note:            note,
+	})
+}
+
+func (u *UnitOfWork) moveReservedWeight(tx *sql.Tx, order *types.Order, toStatus int) error {
+	wasReserved := lifecycle.IsWeightReserved(order.OrderStatus)
+	willBeReserved := lifecycle.IsWeightReserved(toStatus)
+
+	if !wasReserved && willBeReserved {
+		return u.listingStore.SubtractWeightAvailableTx(tx, order.ListingID, order.Weight)
+	} else if wasReserved && !willBeReserved {
+		return u.listingStore.AddWeightAvailableTx(tx, order.ListingID, order.Weight)
+	}
+
+	return nil
+}
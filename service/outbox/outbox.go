@@ -0,0 +1,58 @@
+package outbox
+
+import (
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// Outbox is the Notifier the handlers use. It only stores the message;
+// the Worker delivers it later, so a failing channel never fails a request.
+type Outbox struct {
+	outboxStore     types.OutboxStore
+	fcmHistoryStore types.FCMHistoryStore
+	sessionStore    types.SessionStore
+}
+
+func NewOutbox(outboxStore types.OutboxStore, fcmHistoryStore types.FCMHistoryStore, sessionStore types.SessionStore) *Outbox {
+	return &Outbox{
+		outboxStore:     outboxStore,
+		fcmHistoryStore: fcmHistoryStore,
+		sessionStore:    sessionStore,
+	}
+}
+
+func (o *Outbox) Notify(message types.NotificationMessage) error {
+	if message.Channel != constants.NOTIFICATION_CHANNEL_PUSH || message.ToUserID == 0 {
+		return o.outboxStore.CreateOutboxMessage(message)
+	}
+
+	// every push lands in the user's inbox, even when there is no device to send it to
+	inboxId, err := o.fcmHistoryStore.CreateFCMHistory(types.FCMHistory{
+		ToUserID: message.ToUserID,
+		Title:    message.Title,
+		Body:     message.Body,
+		Data:     message.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	message.InboxID = inboxId
+
+	fcmTokens, err := o.sessionStore.GetActiveFCMTokensByUserID(message.ToUserID)
+	if err != nil {
+		return err
+	}
+
+	// one delivery per logged in device
+	for _, fcmToken := range fcmTokens {
+		message.To = fcmToken
+
+		err = o.outboxStore.CreateOutboxMessage(message)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	outboxStore   types.OutboxStore
+	auditLogStore types.AuditLogStore
+}
+
+func NewHandler(outboxStore types.OutboxStore, auditLogStore types.AuditLogStore) *Handler {
+	return &Handler{
+		outboxStore:   outboxStore,
+		auditLogStore: auditLogStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/outbox", h.handleGetAll).Methods(http.MethodGet)
+	router.HandleFunc("/outbox", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	// support staff can look, only admins can replay
+	router.Handle("/outbox/{id}/replay", jwt.RequireRoles(constants.ROLE_ADMIN)(http.HandlerFunc(h.handleReplay))).Methods(http.MethodPost)
+	router.HandleFunc("/outbox/{id}/replay", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+	// dead-letter messages are what admins usually look for
+	statusStr := r.URL.Query().Get("status")
+	if statusStr == "" {
+		statusStr = constants.DEAD_STATUS_STR
+	}
+
+	status := utils.OutboxStatusStringToInt(statusStr)
+	if status == -1 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown outbox status"))
+		return
+	}
+
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	total, err := h.outboxStore.GetOutboxMessageCount(status)
+	if err != nil {
+		log.Printf("error count outbox messages: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count outbox messages: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	messages, err := h.outboxStore.GetOutboxMessages(status, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("error get outbox messages: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get outbox messages: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnMessages := make([]types.OutboxMessageReturnPayload, 0)
+
+	for _, message := range messages {
+		returnMessages = append(returnMessages, types.OutboxMessageReturnPayload{
+			ID:            message.ID,
+			Message:       message.Message,
+			Status:        utils.OutboxStatusIntToString(message.Status),
+			Attempts:      message.Attempts,
+			LastError:     message.LastError.String,
+			NextAttemptAt: message.NextAttemptAt,
+			SentAt:        message.SentAt.Time,
+			CreatedAt:     message.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.OutboxMessagesReturnPayload{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		Messages: returnMessages,
+	})
+}
+
+func (h *Handler) handleReplay(w http.ResponseWriter, r *http.Request) {
+	admin, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid outbox message id"))
+		return
+	}
+
+	replayed, err := h.outboxStore.ReplayOutboxMessage(id)
+	if err != nil {
+		log.Printf("error replay outbox message: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error replay outbox message: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !replayed {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("no dead outbox message with id %d", id))
+		return
+	}
+
+	err = h.auditLogStore.CreateAuditLog(types.AuditLog{
+		AdminID:    admin.ID,
+		Action:     constants.AUDIT_ACTION_REPLAY_OUTBOX,
+		TargetType: constants.AUDIT_TARGET_OUTBOX,
+		TargetID:   id,
+		IPAddress:  utils.ClientIP(r),
+	})
+	if err != nil {
+		log.Printf("error create audit log: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("error create audit log of outbox replay %d: %v", id, err))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "outbox message queued for delivery")
+}
@@ -0,0 +1,226 @@
+package outbox
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateOutboxMessage(message types.NotificationMessage) error {
+	data, err := json.Marshal(message.Data)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO notification_outbox (
+					channel, to_user_id, recipient, title, body, 
+					attachment_url, attachment_name, data, status, next_attempt_at, inbox_id) 
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err = s.db.Exec(query, message.Channel, message.ToUserID, message.To, message.Title,
+		message.Body, message.AttachmentURL, message.AttachmentName, string(data),
+		constants.OUTBOX_STATUS_PENDING, time.Now(), message.InboxID)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// takes the due pending messages and pushes their next attempt back by the lease,
+// so another worker won't pick them up while they are being delivered
+func (s *Store) ClaimDueOutboxMessages(limit int, lease time.Duration) ([]types.OutboxMessage, error) {
+	messages := make([]types.OutboxMessage, 0)
+
+	err := db.RunInTx(s.db, func(tx *sql.Tx) error {
+		query := `SELECT * FROM notification_outbox 
+					WHERE status = ? AND next_attempt_at <= ? 
+					ORDER BY next_attempt_at ASC 
+					LIMIT ? 
+					FOR UPDATE SKIP LOCKED`
+		rows, err := tx.Query(query, constants.OUTBOX_STATUS_PENDING, time.Now(), limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			message, err := scanRowIntoOutboxMessage(rows)
+			if err != nil {
+				return err
+			}
+
+			messages = append(messages, *message)
+		}
+
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		rows.Close()
+
+		for _, message := range messages {
+			_, err = tx.Exec(`UPDATE notification_outbox SET next_attempt_at = ? WHERE id = ?`,
+				time.Now().Add(lease), message.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return messages, nil
+}
+
+func (s *Store) MarkOutboxMessageSent(id int) error {
+	query := `UPDATE notification_outbox SET status = ?, attempts = attempts + 1, 
+				last_error = NULL, sent_at = ? 
+				WHERE id = ?`
+
+	_, err := s.db.Exec(query, constants.OUTBOX_STATUS_SENT, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) MarkOutboxMessageFailed(id int, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	status := constants.OUTBOX_STATUS_PENDING
+	if dead {
+		status = constants.OUTBOX_STATUS_DEAD
+	}
+
+	query := `UPDATE notification_outbox SET status = ?, attempts = ?, 
+				last_error = ?, next_attempt_at = ? 
+				WHERE id = ?`
+
+	_, err := s.db.Exec(query, status, attempts, lastError, nextAttemptAt, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// returns the messages with the status, newest first
+func (s *Store) GetOutboxMessages(status int, limit int, offset int) ([]types.OutboxMessage, error) {
+	query := `SELECT * FROM notification_outbox 
+				WHERE status = ? 
+				ORDER BY created_at DESC, id DESC 
+				LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, status, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := make([]types.OutboxMessage, 0)
+
+	for rows.Next() {
+		message, err := scanRowIntoOutboxMessage(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		messages = append(messages, *message)
+	}
+
+	return messages, nil
+}
+
+func (s *Store) GetOutboxMessageCount(status int) (int, error) {
+	query := `SELECT COUNT(*) FROM notification_outbox WHERE status = ?`
+
+	var count int
+	err := s.db.QueryRow(query, status).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// puts a dead message back in the queue with a fresh set of attempts.
+// returns false if there is no dead message with the id
+func (s *Store) ReplayOutboxMessage(id int) (bool, error) {
+	query := `UPDATE notification_outbox SET status = ?, attempts = 0, next_attempt_at = ? 
+				WHERE id = ? AND status = ?`
+
+	res, err := s.db.Exec(query, constants.OUTBOX_STATUS_PENDING, time.Now(), id, constants.OUTBOX_STATUS_DEAD)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
+func scanRowIntoOutboxMessage(rows *sql.Rows) (*types.OutboxMessage, error) {
+	message := new(types.OutboxMessage)
+
+	var toUserId sql.NullInt64
+	var attachmentUrl sql.NullString
+	var attachmentName sql.NullString
+	var data sql.NullString
+	var inboxId sql.NullInt64
+
+	err := rows.Scan(
+		&message.ID,
+		&message.Message.Channel,
+		&toUserId,
+		&message.Message.To,
+		&message.Message.Title,
+		&message.Message.Body,
+		&attachmentUrl,
+		&attachmentName,
+		&data,
+		&message.Status,
+		&message.Attempts,
+		&message.LastError,
+		&message.NextAttemptAt,
+		&message.SentAt,
+		&message.CreatedAt,
+		&inboxId,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	message.Message.ToUserID = int(toUserId.Int64)
+	message.Message.AttachmentURL = attachmentUrl.String
+	message.Message.AttachmentName = attachmentName.String
+	message.Message.InboxID = int(inboxId.Int64)
+
+	if data.Valid && data.String != "" {
+		err = json.Unmarshal([]byte(data.String), &message.Message.Data)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	message.NextAttemptAt = message.NextAttemptAt.Local()
+	message.CreatedAt = message.CreatedAt.Local()
+	message.SentAt.Time = message.SentAt.Time.Local()
+
+	return message, nil
+}
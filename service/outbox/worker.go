@@ -0,0 +1,102 @@
+package outbox
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+const claimBatchSize = 50
+
+// how long a claimed message stays hidden from other workers
+const claimLease = 5 * time.Minute
+
+// Worker delivers the queued messages through the real notifier. A failed
+// message is retried with an exponential backoff and marked dead once it has
+// used up its attempts.
+type Worker struct {
+	outboxStore  types.OutboxStore
+	notifier     types.Notifier
+	pollInterval time.Duration
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	maxAttempts  int
+}
+
+func NewWorker(outboxStore types.OutboxStore, notifier types.Notifier, pollInterval time.Duration,
+	baseBackoff time.Duration, maxAttempts int) *Worker {
+	return &Worker{
+		outboxStore:  outboxStore,
+		notifier:     notifier,
+		pollInterval: pollInterval,
+		baseBackoff:  baseBackoff,
+		maxBackoff:   time.Hour,
+		maxAttempts:  maxAttempts,
+	}
+}
+
+func (w *Worker) Start() {
+	go func() {
+		ticker := time.NewTicker(w.pollInterval)
+		defer ticker.Stop()
+
+		for {
+			w.deliverDue()
+			<-ticker.C
+		}
+	}()
+}
+
+func (w *Worker) deliverDue() {
+	messages, err := w.outboxStore.ClaimDueOutboxMessages(claimBatchSize, claimLease)
+	if err != nil {
+		log.Printf("error claiming outbox messages: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("error claiming outbox messages: %v", err))
+		return
+	}
+
+	for _, message := range messages {
+		w.deliver(message)
+	}
+}
+
+func (w *Worker) deliver(message types.OutboxMessage) {
+	sendErr := w.notifier.Notify(message.Message)
+	if sendErr == nil {
+		err := w.outboxStore.MarkOutboxMessageSent(message.ID)
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error marking outbox message %d as sent: %v", message.ID, err))
+		}
+
+		return
+	}
+
+	attempts := message.Attempts + 1
+	dead := attempts >= w.maxAttempts
+
+	err := w.outboxStore.MarkOutboxMessageFailed(message.ID, attempts, sendErr.Error(),
+		time.Now().Add(w.backoff(attempts)), dead)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error marking outbox message %d as failed: %v", message.ID, err))
+	}
+
+	if dead {
+		logger.WriteServerLog(fmt.Sprintf("outbox message %d is dead after %d attempts: %v", message.ID, attempts, sendErr))
+	}
+}
+
+// doubles the wait after every failed attempt, up to maxBackoff
+func (w *Worker) backoff(attempts int) time.Duration {
+	backoff := w.baseBackoff
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if backoff >= w.maxBackoff {
+			return w.maxBackoff
+		}
+	}
+
+	return backoff
+}
@@ -0,0 +1,99 @@
+package outbox
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+func TestBackoff(t *testing.T) {
+	w := NewWorker(nil, nil, time.Second, time.Minute, 10)
+
+	want := []time.Duration{
+		1 * time.Minute,
+		2 * time.Minute,
+		4 * time.Minute,
+		8 * time.Minute,
+		16 * time.Minute,
+		32 * time.Minute,
+		time.Hour, // 64 minutes is over the cap
+		time.Hour,
+		time.Hour,
+	}
+
+	for i, wantBackoff := range want {
+		attempts := i + 1
+		if got := w.backoff(attempts); got != wantBackoff {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, wantBackoff)
+		}
+	}
+
+	// far past the cap, the doubling must not overflow
+	if got := w.backoff(100); got != time.Hour {
+		t.Errorf("backoff(100) = %v, want %v", got, time.Hour)
+	}
+}
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify(message types.NotificationMessage) error {
+	return fmt.Errorf("smtp is down")
+}
+
+type failedCall struct {
+	attempts      int
+	nextAttemptAt time.Time
+	dead          bool
+}
+
+type fakeOutboxStore struct {
+	types.OutboxStore
+	failed []failedCall
+}
+
+func (s *fakeOutboxStore) MarkOutboxMessageFailed(id int, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error {
+	s.failed = append(s.failed, failedCall{attempts: attempts, nextAttemptAt: nextAttemptAt, dead: dead})
+	return nil
+}
+
+func TestDeliverDeadLettersAfterMaxAttempts(t *testing.T) {
+	// a dead message is written to the server log, keep it out of the source tree
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	const maxAttempts = 3
+
+	store := &fakeOutboxStore{}
+	w := NewWorker(store, failingNotifier{}, time.Second, time.Minute, maxAttempts)
+
+	// every earlier attempt of the message already failed
+	for previousAttempts := 0; previousAttempts < maxAttempts; previousAttempts++ {
+		before := time.Now()
+		w.deliver(types.OutboxMessage{ID: 1, Attempts: previousAttempts})
+
+		call := store.failed[len(store.failed)-1]
+
+		if call.attempts != previousAttempts+1 {
+			t.Errorf("attempts = %d, want %d", call.attempts, previousAttempts+1)
+		}
+
+		wantDead := previousAttempts+1 == maxAttempts
+		if call.dead != wantDead {
+			t.Errorf("attempt %d: dead = %v, want %v", call.attempts, call.dead, wantDead)
+		}
+
+		wait := call.nextAttemptAt.Sub(before)
+		if wantWait := w.backoff(call.attempts); wait < wantWait || wait > wantWait+time.Second {
+			t.Errorf("attempt %d: next attempt in %v, want %v", call.attempts, wait, wantWait)
+		}
+	}
+}
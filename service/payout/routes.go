@@ -0,0 +1,310 @@
+package payout
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	payoutMethodStore types.PayoutMethodStore
+	currencyStore     types.CurrencyStore
+}
+
+func NewHandler(payoutMethodStore types.PayoutMethodStore, currencyStore types.CurrencyStore) *Handler {
+	return &Handler{payoutMethodStore: payoutMethodStore, currencyStore: currencyStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/payout-method", h.handlePost).Methods(http.MethodPost)
+	router.HandleFunc("/payout-method", h.handleGetAll).Methods(http.MethodGet)
+	router.HandleFunc("/payout-method", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/payout-method/{id:[0-9]+}", h.handleModify).Methods(http.MethodPatch)
+	router.HandleFunc("/payout-method/{id:[0-9]+}", h.handleDelete).Methods(http.MethodDelete)
+	router.HandleFunc("/payout-method/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/payout-method/{id:[0-9]+}/default", h.handleSetDefault).Methods(http.MethodPost)
+	router.HandleFunc("/payout-method/{id:[0-9]+}/default", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
+	var payload types.PayoutMethodPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("post payout method payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	payoutMethod, err := payoutMethodFromPayload(payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	count, err := h.payoutMethodStore.GetPayoutMethodCountByUserID(user.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if count >= constants.PAYOUT_METHOD_MAX_PER_USER {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you can add up to %d payout methods", constants.PAYOUT_METHOD_MAX_PER_USER))
+		return
+	}
+
+	currency, ok := h.getOrCreateCurrency(w, payoutMethod.Currency)
+	if !ok {
+		return
+	}
+
+	payoutMethod.UserID = user.ID
+	payoutMethod.CurrencyID = currency.ID
+
+	err = h.payoutMethodStore.CreatePayoutMethod(payoutMethod)
+	if err != nil {
+		log.Printf("error create payout method: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create payout method: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "payout method added")
+}
+
+// the user's own methods, masked like everywhere else
+func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	payoutMethods, err := h.payoutMethodStore.GetPayoutMethodsByUserID(user.ID)
+	if err != nil {
+		log.Printf("error get payout methods: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get payout methods: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnPayloads := make([]types.PayoutMethodReturnPayload, 0)
+
+	for _, payoutMethod := range payoutMethods {
+		returnPayloads = append(returnPayloads, utils.PayoutMethodPayload(payoutMethod, false))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, returnPayloads)
+}
+
+func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payout method id"))
+		return
+	}
+
+	var payload types.PayoutMethodPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("modify payout method payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	payoutMethod, err := payoutMethodFromPayload(payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	currency, ok := h.getOrCreateCurrency(w, payoutMethod.Currency)
+	if !ok {
+		return
+	}
+
+	payoutMethod.CurrencyID = currency.ID
+
+	modified, err := h.payoutMethodStore.ModifyPayoutMethod(id, user.ID, payoutMethod)
+	if err != nil {
+		log.Printf("error modify payout method: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error modify payout method: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !modified {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payout method not found"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "payout method updated")
+}
+
+func (h *Handler) handleSetDefault(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payout method id"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	updated, err := h.payoutMethodStore.SetDefaultPayoutMethod(id, user.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !updated {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payout method not found"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "default payout method updated")
+}
+
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payout method id"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	deleted, err := h.payoutMethodStore.DeletePayoutMethod(id, user.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !deleted {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payout method not found"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "payout method deleted")
+}
+
+// a currency nobody used yet is created, the same as when a listing is posted
+func (h *Handler) getOrCreateCurrency(w http.ResponseWriter, name string) (*types.Currency, bool) {
+	currency, err := h.currencyStore.GetCurrencyByName(name)
+	if err == nil && currency == nil {
+		err = h.currencyStore.CreateCurrency(name)
+		if err == nil {
+			currency, err = h.currencyStore.GetCurrencyByName(name)
+		}
+	}
+	if err == nil && currency == nil {
+		err = fmt.Errorf("currency %s not found after creating it", name)
+	}
+	if err != nil {
+		log.Printf("error get currency of payout method: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get currency of payout method: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return nil, false
+	}
+
+	return currency, true
+}
+
+// checks the fields the method type needs, the error is meant for the client
+func payoutMethodFromPayload(payload types.PayoutMethodPayload) (types.PayoutMethod, error) {
+	payoutMethod := types.PayoutMethod{
+		MethodType: utils.PayoutMethodTypeStringToInt(payload.MethodType),
+		Currency:   strings.ToUpper(strings.TrimSpace(payload.Currency)),
+		IsDefault:  payload.IsDefault,
+	}
+
+	if payoutMethod.Currency == "" {
+		return payoutMethod, fmt.Errorf("currency is required")
+	}
+
+	switch payoutMethod.MethodType {
+	case constants.PAYOUT_METHOD_BANK_ACCOUNT:
+		payoutMethod.Provider = strings.TrimSpace(payload.BankName)
+		payoutMethod.AccountNumber = strings.TrimSpace(payload.AccountNumber)
+		payoutMethod.AccountHolder = strings.TrimSpace(payload.AccountHolder)
+
+		if payoutMethod.Provider == "" || payoutMethod.AccountNumber == "" || payoutMethod.AccountHolder == "" {
+			return payoutMethod, fmt.Errorf("bankName, accountNumber and accountHolder are required for a bank account")
+		}
+	case constants.PAYOUT_METHOD_E_WALLET:
+		payoutMethod.Provider = strings.TrimSpace(payload.Provider)
+		payoutMethod.AccountNumber = strings.TrimSpace(payload.Handle)
+
+		if payoutMethod.Provider == "" || payoutMethod.AccountNumber == "" {
+			return payoutMethod, fmt.Errorf("provider and handle are required for an e-wallet")
+		}
+	default:
+		return payoutMethod, fmt.Errorf("unknown payout method type")
+	}
+
+	return payoutMethod, nil
+}
@@ -0,0 +1,337 @@
+package payout
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// the account number (or e-wallet handle) and the account holder are stored
+// encrypted by the sealer, key_id and wrapped_key tell how. rows copied from the
+// old bank_detail before they were encrypted have no key_id and are still
+// plaintext until RotateEncryptionKeys gets to them.
+// a user has at most one default method per currency
+type Store struct {
+	db     *sql.DB
+	sealer types.FieldSealer
+}
+
+func NewStore(db *sql.DB, sealer types.FieldSealer) *Store {
+	return &Store{db: db, sealer: sealer}
+}
+
+// the first method of a currency becomes its default
+func (s *Store) CreatePayoutMethod(payoutMethod types.PayoutMethod) error {
+	sealed, err := s.sealer.Seal(payoutMethod.AccountNumber, payoutMethod.AccountHolder)
+	if err != nil {
+		return err
+	}
+
+	return db.RunInTx(s.db, func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM payout_method 
+								WHERE user_id = ? AND currency_id = ? FOR UPDATE`,
+			payoutMethod.UserID, payoutMethod.CurrencyID).Scan(&count)
+		if err != nil {
+			return err
+		}
+
+		isDefault := (payoutMethod.IsDefault || count == 0)
+
+		if isDefault {
+			err = clearDefault(tx, payoutMethod.UserID, payoutMethod.CurrencyID)
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `INSERT INTO payout_method (user_id, method_type, currency_id, provider, 
+						account_number, account_holder, key_id, wrapped_key, is_default) 
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+		_, err = tx.Exec(query, payoutMethod.UserID, payoutMethod.MethodType, payoutMethod.CurrencyID,
+			payoutMethod.Provider, sealed.Values[0], sealed.Values[1], sealed.KeyID, sealed.WrappedKey, isDefault)
+		return err
+	})
+}
+
+// false when the user has no such method
+func (s *Store) ModifyPayoutMethod(id int, userId int, payoutMethod types.PayoutMethod) (bool, error) {
+	sealed, err := s.sealer.Seal(payoutMethod.AccountNumber, payoutMethod.AccountHolder)
+	if err != nil {
+		return false, err
+	}
+
+	found := false
+
+	err = db.RunInTx(s.db, func(tx *sql.Tx) error {
+		var count int
+		err := tx.QueryRow(`SELECT COUNT(*) FROM payout_method WHERE id = ? AND user_id = ? FOR UPDATE`,
+			id, userId).Scan(&count)
+		if err != nil || count == 0 {
+			return err
+		}
+
+		found = true
+
+		if payoutMethod.IsDefault {
+			err = clearDefault(tx, userId, payoutMethod.CurrencyID)
+			if err != nil {
+				return err
+			}
+		}
+
+		query := `UPDATE payout_method SET method_type = ?, currency_id = ?, provider = ?, 
+						account_number = ?, account_holder = ?, key_id = ?, wrapped_key = ?, 
+						is_default = ?, last_modified_at = ? 
+					WHERE id = ? AND user_id = ?`
+		_, err = tx.Exec(query, payoutMethod.MethodType, payoutMethod.CurrencyID, payoutMethod.Provider,
+			sealed.Values[0], sealed.Values[1], sealed.KeyID, sealed.WrappedKey,
+			payoutMethod.IsDefault, time.Now(), id, userId)
+		return err
+	})
+
+	return found, err
+}
+
+// makes the method the default of its currency, false when the user has no such method
+func (s *Store) SetDefaultPayoutMethod(id int, userId int) (bool, error) {
+	found := false
+
+	err := db.RunInTx(s.db, func(tx *sql.Tx) error {
+		var currencyId sql.NullInt64
+		err := tx.QueryRow(`SELECT currency_id FROM payout_method WHERE id = ? AND user_id = ? FOR UPDATE`,
+			id, userId).Scan(&currencyId)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		found = true
+
+		_, err = tx.Exec(`UPDATE payout_method SET is_default = FALSE 
+							WHERE user_id = ? AND currency_id <=> ? AND id != ?`,
+			userId, currencyId, id)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE payout_method SET is_default = TRUE, last_modified_at = ? WHERE id = ?`,
+			time.Now(), id)
+		return err
+	})
+
+	return found, err
+}
+
+func (s *Store) DeletePayoutMethod(id int, userId int) (bool, error) {
+	result, err := s.db.Exec(`DELETE FROM payout_method WHERE id = ? AND user_id = ?`, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (affected > 0), nil
+}
+
+func (s *Store) GetPayoutMethodsByUserID(userId int) ([]types.PayoutMethod, error) {
+	query := `SELECT p.id, p.user_id, p.method_type, 
+					COALESCE(p.currency_id, 0), COALESCE(c.name, ''), 
+					p.provider, p.account_number, p.account_holder, 
+					p.key_id, p.wrapped_key, p.is_default, 
+					p.created_at, p.last_modified_at 
+				FROM payout_method AS p 
+				LEFT JOIN currency AS c ON c.id = p.currency_id 
+				WHERE p.user_id = ? 
+				ORDER BY c.name ASC, p.is_default DESC, p.id DESC`
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	payoutMethods := make([]types.PayoutMethod, 0)
+
+	for rows.Next() {
+		payoutMethod, err := s.scanRowIntoPayoutMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		payoutMethods = append(payoutMethods, *payoutMethod)
+	}
+
+	return payoutMethods, rows.Err()
+}
+
+func (s *Store) GetPayoutMethodCountByUserID(userId int) (int, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM payout_method WHERE user_id = ?`, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// the default method of the currency, or its newest one. a method without a
+// currency is only used when the currency has none. nil when there is nothing to use
+func (s *Store) GetPayoutMethodForCurrency(userId int, currency string) (*types.PayoutMethod, error) {
+	query := `SELECT p.id, p.user_id, p.method_type, 
+					COALESCE(p.currency_id, 0), COALESCE(c.name, ''), 
+					p.provider, p.account_number, p.account_holder, 
+					p.key_id, p.wrapped_key, p.is_default, 
+					p.created_at, p.last_modified_at 
+				FROM payout_method AS p 
+				LEFT JOIN currency AS c ON c.id = p.currency_id 
+				WHERE p.user_id = ? 
+				AND (c.name = ? OR p.currency_id IS NULL) 
+				ORDER BY p.currency_id IS NULL ASC, p.is_default DESC, p.id DESC 
+				LIMIT 1`
+	rows, err := s.db.Query(query, userId, currency)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var payoutMethod *types.PayoutMethod
+
+	for rows.Next() {
+		payoutMethod, err = s.scanRowIntoPayoutMethod(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payoutMethod, rows.Err()
+}
+
+// encrypts the plaintext rows and re-wraps the data keys that aren't wrapped by
+// the current key. a row modified in the meantime already has the current key and is left alone
+func (s *Store) RotateEncryptionKeys() (int, error) {
+	query := `SELECT id, account_number, account_holder, key_id, wrapped_key 
+				FROM payout_method 
+				WHERE key_id IS NULL OR key_id != ?`
+	rows, err := s.db.Query(query, s.sealer.CurrentKeyID())
+	if err != nil {
+		return 0, err
+	}
+
+	type staleRow struct {
+		id     int
+		sealed types.SealedFields
+		plain  bool
+	}
+
+	staleRows := make([]staleRow, 0)
+
+	for rows.Next() {
+		var row staleRow
+		var accountNumber, accountHolder string
+		var keyID, wrappedKey sql.NullString
+
+		err := rows.Scan(&row.id, &accountNumber, &accountHolder, &keyID, &wrappedKey)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		row.plain = !keyID.Valid
+		row.sealed = types.SealedFields{
+			KeyID:      keyID.String,
+			WrappedKey: wrappedKey.String,
+			Values:     []string{accountNumber, accountHolder},
+		}
+
+		staleRows = append(staleRows, row)
+	}
+	rows.Close()
+
+	if rows.Err() != nil {
+		return 0, rows.Err()
+	}
+
+	rotated := 0
+
+	for _, row := range staleRows {
+		var sealed *types.SealedFields
+		if row.plain {
+			sealed, err = s.sealer.Seal(row.sealed.Values...)
+		} else {
+			sealed, err = s.sealer.Rewrap(&row.sealed)
+		}
+		if err != nil {
+			return rotated, fmt.Errorf("payout method %d: %v", row.id, err)
+		}
+
+		var oldKeyID any
+		if !row.plain {
+			oldKeyID = row.sealed.KeyID
+		}
+
+		query = `UPDATE payout_method SET account_number = ?, account_holder = ?, key_id = ?, wrapped_key = ? 
+					WHERE id = ? AND key_id <=> ?`
+		_, err = s.db.Exec(query, sealed.Values[0], sealed.Values[1], sealed.KeyID, sealed.WrappedKey, row.id, oldKeyID)
+		if err != nil {
+			return rotated, fmt.Errorf("payout method %d: %v", row.id, err)
+		}
+
+		rotated++
+	}
+
+	return rotated, nil
+}
+
+func (s *Store) scanRowIntoPayoutMethod(rows *sql.Rows) (*types.PayoutMethod, error) {
+	payoutMethod := new(types.PayoutMethod)
+	var keyID, wrappedKey sql.NullString
+
+	err := rows.Scan(
+		&payoutMethod.ID,
+		&payoutMethod.UserID,
+		&payoutMethod.MethodType,
+		&payoutMethod.CurrencyID,
+		&payoutMethod.Currency,
+		&payoutMethod.Provider,
+		&payoutMethod.AccountNumber,
+		&payoutMethod.AccountHolder,
+		&keyID,
+		&wrappedKey,
+		&payoutMethod.IsDefault,
+		&payoutMethod.CreatedAt,
+		&payoutMethod.LastModifiedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if keyID.Valid {
+		values, err := s.sealer.Open(&types.SealedFields{
+			KeyID:      keyID.String,
+			WrappedKey: wrappedKey.String,
+			Values:     []string{payoutMethod.AccountNumber, payoutMethod.AccountHolder},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error decrypt payout method %d: %v", payoutMethod.ID, err)
+		}
+
+		payoutMethod.AccountNumber = values[0]
+		payoutMethod.AccountHolder = values[1]
+	}
+
+	return payoutMethod, nil
+}
+
+func clearDefault(tx *sql.Tx, userId int, currencyId int) error {
+	_, err := tx.Exec(`UPDATE payout_method SET is_default = FALSE WHERE user_id = ? AND currency_id = ?`,
+		userId, currencyId)
+	return err
+}
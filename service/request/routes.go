@@ -0,0 +1,691 @@
+package request
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	requestStore      types.DeliveryRequestStore
+	userStore         types.UserStore
+	orderStore        types.OrderStore
+	currencyStore     types.CurrencyStore
+	notifier          types.Notifier
+	requestUnitOfWork types.DeliveryRequestUnitOfWork
+	blobStore         types.BlobStore
+}
+
+func NewHandler(requestStore types.DeliveryRequestStore, userStore types.UserStore,
+	orderStore types.OrderStore, currencyStore types.CurrencyStore,
+	notifier types.Notifier, requestUnitOfWork types.DeliveryRequestUnitOfWork,
+	blobStore types.BlobStore) *Handler {
+	return &Handler{
+		requestStore:      requestStore,
+		userStore:         userStore,
+		orderStore:        orderStore,
+		currencyStore:     currencyStore,
+		notifier:          notifier,
+		requestUnitOfWork: requestUnitOfWork,
+		blobStore:         blobStore,
+	}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/request", h.handlePost).Methods(http.MethodPost)
+	router.HandleFunc("/request", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/request/{reqType}", h.handleGetAll).Methods(http.MethodGet)
+	router.HandleFunc("/request/{reqType}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/request/{id}/cancel", h.handleCancel).Methods(http.MethodPost)
+	router.HandleFunc("/request/{id}/cancel", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/request/{id}/offer", h.handleGetOffers).Methods(http.MethodGet)
+	router.HandleFunc("/request/{id}/offer", h.handlePostOffer).Methods(http.MethodPost)
+	router.HandleFunc("/request/{id}/offer", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/request/{id}/offer/{offerId}", h.handleWithdrawOffer).Methods(http.MethodDelete)
+	router.HandleFunc("/request/{id}/offer/{offerId}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/request/{id}/offer/{offerId}/accept", h.handleAcceptOffer).Methods(http.MethodPost)
+	router.HandleFunc("/request/{id}/offer/{offerId}/accept", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
+	var payload types.PostDeliveryRequestPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("post delivery request payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	payload.OriginCity = strings.TrimSpace(payload.OriginCity)
+	payload.OriginCountryCode = strings.ToUpper(strings.TrimSpace(payload.OriginCountryCode))
+	payload.DestinationCity = strings.TrimSpace(payload.DestinationCity)
+	payload.DestinationCountryCode = strings.ToUpper(strings.TrimSpace(payload.DestinationCountryCode))
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	if strings.EqualFold(payload.OriginCity, payload.DestinationCity) && payload.OriginCountryCode == payload.DestinationCountryCode {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("origin and destination must be different"))
+		return
+	}
+
+	// validate token
+	giver, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	deadline, err := utils.ParseDate(payload.Deadline)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("error parsing date"))
+		return
+	}
+
+	if deadline.Before(time.Now()) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("deadline has passed already"))
+		return
+	}
+
+	currency, err := h.currencyStore.GetCurrencyByName(payload.Currency)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if currency == nil {
+		err = h.currencyStore.CreateCurrency(payload.Currency)
+		if err != nil {
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error create currency: %v", err))
+			return
+		}
+
+		currency, err = h.currencyStore.GetCurrencyByName(payload.Currency)
+		if err != nil {
+			log.Printf("get currency at delivery request: %v", err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("get currency at delivery request: %v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+	}
+
+	var packageImgURL string
+
+	if len(payload.PackageImage) > constants.PACKAGE_IMG_MAX_BYTES {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("image size exceeds the limit of 10MB"))
+		return
+	} else if len(payload.PackageImage) > 0 {
+		// decode, strip the metadata and build the variants
+		packageImage, err := utils.ProcessImage(payload.PackageImage)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		// the image becomes the order's package image once an offer is accepted
+		filePath := constants.PACKAGE_IMG_KEY_PREFIX + utils.GeneratePictureFileName(packageImage.Extension)
+
+		for h.orderStore.IsPackageImageURLExist(filePath) || h.requestStore.IsPackageImageURLExist(filePath) {
+			filePath = constants.PACKAGE_IMG_KEY_PREFIX + utils.GeneratePictureFileName(packageImage.Extension)
+		}
+
+		// save the image
+		err = utils.SavePackageImage(h.blobStore, packageImage, filePath)
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error saving package image: %v", err))
+		}
+
+		packageImgURL = filePath
+	}
+
+	requestId, err := h.requestStore.CreateDeliveryRequest(types.DeliveryRequest{
+		GiverID:                giver.ID,
+		OriginCity:             payload.OriginCity,
+		OriginCountryCode:      payload.OriginCountryCode,
+		DestinationCity:        payload.DestinationCity,
+		DestinationCountryCode: payload.DestinationCountryCode,
+		Weight:                 payload.Weight,
+		Budget:                 payload.Budget,
+		CurrencyID:             currency.ID,
+		Deadline:               *deadline,
+		PackageContent:         payload.PackageContent,
+		PackageImageURL:        packageImgURL,
+		Notes:                  payload.Notes,
+	})
+	if err != nil {
+		log.Printf("error create delivery request: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create delivery request: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]int{"id": requestId})
+}
+
+// "all" lists the open requests of the other givers, "matching" only the ones
+// an available listing of the carrier can take and "giver" the user's own requests
+func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	reqType := vars["reqType"]
+
+	if reqType == "giver" {
+		requests, err := h.requestStore.GetDeliveryRequestsByGiverID(user.ID)
+		if err != nil {
+			log.Println(err)
+			logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+			utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+			return
+		}
+
+		returnPayloads := make([]types.DeliveryRequestReturnPayload, 0)
+		for _, request := range requests {
+			returnPayloads = append(returnPayloads, deliveryRequestReturnPayload(request))
+		}
+
+		utils.WriteJSON(w, http.StatusOK, returnPayloads)
+		return
+	} else if reqType != "all" && reqType != "matching" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown request parameter"))
+		return
+	}
+
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	filter, err := parseDeliveryRequestFilter(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if reqType == "matching" {
+		filter.MatchingCarrierID = user.ID
+	}
+
+	total, err := h.requestStore.GetOpenDeliveryRequestCount(user.ID, filter)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	filter.Limit = pageSize
+	filter.Offset = (page - 1) * pageSize
+
+	requests, err := h.requestStore.GetOpenDeliveryRequests(user.ID, filter)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnPayloads := make([]types.DeliveryRequestReturnPayload, 0)
+	for _, request := range requests {
+		returnPayloads = append(returnPayloads, deliveryRequestReturnPayload(request))
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.DeliveryRequestsReturnPayload{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		Requests: returnPayloads,
+	})
+}
+
+func (h *Handler) handleCancel(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request id"))
+		return
+	}
+
+	// validate token
+	giver, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	offers, err := h.requestStore.GetOffersByRequestID(requestId)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	err = h.requestUnitOfWork.CancelRequest(requestId, giver.ID)
+	if errors.Is(err, types.ErrDeliveryRequestNotFound) {
+		utils.WriteError(w, http.StatusNotFound, err)
+		return
+	}
+	if errors.Is(err, types.ErrRequestNotOpen) {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	for _, offer := range offers {
+		if offer.Status != constants.OFFER_STATUS_PENDING {
+			continue
+		}
+
+		h.notifyUser(offer.CarrierID, "Delivery Request Cancelled",
+			fmt.Sprintf("Delivery request no. %d was cancelled by the giver, your offer is closed", requestId), 0)
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "delivery request cancelled")
+}
+
+func (h *Handler) handlePostOffer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request id"))
+		return
+	}
+
+	var payload types.PostRequestOfferPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("post offer payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	// validate token
+	carrier, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	request, err := h.requestStore.GetDeliveryRequestByID(requestId)
+	if err != nil {
+		log.Printf("delivery request id %d not found: %v", requestId, err)
+		logger.WriteServerLog(fmt.Sprintf("delivery request id %d not found: %v", requestId, err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("delivery request not found"))
+		return
+	}
+
+	if request.GiverID == carrier.ID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("cannot make an offer on your own request"))
+		return
+	}
+
+	if request.Status != constants.REQUEST_STATUS_OPEN {
+		utils.WriteError(w, http.StatusBadRequest, types.ErrRequestNotOpen)
+		return
+	}
+
+	if payload.Price > request.Budget {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("price is over the budget of %.2f %s", request.Budget, request.Currency))
+		return
+	}
+
+	departureDate, err := utils.ParseDate(payload.DepartureDate)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("error parsing date"))
+		return
+	}
+
+	lastReceivedDate, err := utils.ParseDate(payload.LastReceivedDate)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("error parsing date"))
+		return
+	}
+
+	if departureDate.Before(time.Now()) || lastReceivedDate.After(*departureDate) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("last received date must be before a departure date in the future"))
+		return
+	}
+
+	if departureDate.After(request.Deadline) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("departure date is after the deadline"))
+		return
+	}
+
+	isPending, err := h.requestStore.IsOfferPending(request.ID, carrier.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if isPending {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you already have a pending offer on this request"))
+		return
+	}
+
+	offerId, err := h.requestStore.CreateOffer(types.RequestOffer{
+		RequestID:        request.ID,
+		CarrierID:        carrier.ID,
+		Price:            payload.Price,
+		DepartureDate:    *departureDate,
+		LastReceivedDate: *lastReceivedDate,
+		Message:          payload.Message,
+	})
+	if err != nil {
+		log.Printf("error create offer: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create offer: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	h.notifyUser(request.GiverID, "New Offer Arrived!",
+		fmt.Sprintf("%s offered to take your delivery request no. %d to %s for %.2f %s",
+			carrier.Name, request.ID, request.DestinationCity, payload.Price, request.Currency), 0)
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]int{"id": offerId})
+}
+
+// the giver sees every offer on the request, a carrier only their own
+func (h *Handler) handleGetOffers(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request id"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	request, err := h.requestStore.GetDeliveryRequestByID(requestId)
+	if err != nil {
+		log.Printf("delivery request id %d not found: %v", requestId, err)
+		logger.WriteServerLog(fmt.Sprintf("delivery request id %d not found: %v", requestId, err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("delivery request not found"))
+		return
+	}
+
+	offers, err := h.requestStore.GetOffersByRequestID(request.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnPayloads := make([]types.RequestOfferReturnPayload, 0)
+
+	for _, offer := range offers {
+		if request.GiverID != user.ID && offer.CarrierID != user.ID {
+			continue
+		}
+
+		returnPayloads = append(returnPayloads, types.RequestOfferReturnPayload{
+			ID:               offer.ID,
+			RequestID:        offer.RequestID,
+			CarrierID:        offer.CarrierID,
+			CarrierName:      offer.CarrierName,
+			Price:            offer.Price,
+			Quote:            quoteOffer(request, &offer),
+			DepartureDate:    offer.DepartureDate,
+			LastReceivedDate: offer.LastReceivedDate,
+			Message:          offer.Message,
+			Status:           utils.OfferStatusIntToString(offer.Status),
+			CreatedAt:        offer.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, returnPayloads)
+}
+
+func (h *Handler) handleWithdrawOffer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request id"))
+		return
+	}
+
+	offerId, err := strconv.Atoi(vars["offerId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid offer id"))
+		return
+	}
+
+	// validate token
+	carrier, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	offer, err := h.requestStore.GetOfferByID(offerId)
+	if err != nil || offer.RequestID != requestId {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("offer not found"))
+		return
+	}
+
+	withdrawn, err := h.requestStore.WithdrawOffer(offer.ID, carrier.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !withdrawn {
+		utils.WriteError(w, http.StatusBadRequest, types.ErrOfferNotPending)
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "offer withdrawn")
+}
+
+func (h *Handler) handleAcceptOffer(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	requestId, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid request id"))
+		return
+	}
+
+	offerId, err := strconv.Atoi(vars["offerId"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid offer id"))
+		return
+	}
+
+	// validate token
+	giver, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	request, err := h.requestStore.GetDeliveryRequestByID(requestId)
+	if err != nil || request.GiverID != giver.ID {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("delivery request not found"))
+		return
+	}
+
+	// read before accepting, the other pending offers are rejected with it
+	offers, err := h.requestStore.GetOffersByRequestID(request.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	orderId, err := h.requestUnitOfWork.AcceptOffer(request.ID, offerId, giver.ID)
+	if err != nil {
+		if errors.Is(err, types.ErrRequestNotOpen) || errors.Is(err, types.ErrOfferNotPending) {
+			utils.WriteError(w, http.StatusConflict, err)
+			return
+		}
+
+		if errors.Is(err, types.ErrDeliveryRequestNotFound) || errors.Is(err, types.ErrOfferNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		log.Printf("error accept offer %d: %v", offerId, err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error accept offer %d: %v", offerId, err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	for _, offer := range offers {
+		if offer.ID == offerId {
+			h.notifyUser(offer.CarrierID, "Offer Accepted!",
+				fmt.Sprintf("%s accepted your offer on delivery request no. %d, it is now order no. %d",
+					giver.Name, request.ID, orderId), orderId)
+		} else if offer.Status == constants.OFFER_STATUS_PENDING {
+			h.notifyUser(offer.CarrierID, "Offer Not Accepted",
+				fmt.Sprintf("Delivery request no. %d went to another carrier", request.ID), 0)
+		}
+	}
+
+	h.notifyUser(giver.ID, "Order Created",
+		fmt.Sprintf("Your delivery request no. %d is now order no. %d, please complete the payment", request.ID, orderId), orderId)
+
+	utils.WriteJSON(w, http.StatusCreated, map[string]int{"orderId": orderId})
+}
+
+// failures are only logged, the request itself already went through
+func (h *Handler) notifyUser(userId int, subject string, body string, orderId int) {
+	user, err := h.userStore.GetUserByID(userId)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error get user %d to notify: %v", userId, err))
+		return
+	}
+
+	err = h.notifier.Notify(types.NotificationMessage{
+		Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+		To:      user.Email,
+		Title:   subject,
+		Body:    fmt.Sprintf("<h4>%s</h4>", body),
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending email to %s: %v", user.Email, err))
+	}
+
+	push := types.NotificationMessage{
+		Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
+		ToUserID: user.ID,
+		Title:    subject,
+		Body:     body,
+		Data: types.FCMData{
+			Type: "request_updated",
+		},
+	}
+
+	if orderId != 0 {
+		push.Data = types.FCMData{
+			Type:    "order_updated",
+			OrderID: fmt.Sprintf("%d", orderId),
+		}
+	}
+
+	err = h.notifier.Notify(push)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending notification to user %d: %v", user.ID, err))
+	}
+}
+
+func parseDeliveryRequestFilter(r *http.Request) (types.DeliveryRequestFilter, error) {
+	query := r.URL.Query()
+
+	filter := types.DeliveryRequestFilter{
+		OriginCity:             strings.TrimSpace(query.Get("originCity")),
+		OriginCountryCode:      strings.ToUpper(strings.TrimSpace(query.Get("originCountry"))),
+		DestinationCity:        strings.TrimSpace(query.Get("destinationCity")),
+		DestinationCountryCode: strings.ToUpper(strings.TrimSpace(query.Get("destinationCountry"))),
+	}
+
+	if maxWeight := query.Get("maxWeight"); maxWeight != "" {
+		weight, err := strconv.ParseFloat(maxWeight, 64)
+		if err != nil || weight < 0 {
+			return filter, fmt.Errorf("invalid maxWeight")
+		}
+
+		filter.MaxWeight = weight
+	}
+
+	return filter, nil
+}
+
+func deliveryRequestReturnPayload(request types.DeliveryRequest) types.DeliveryRequestReturnPayload {
+	return types.DeliveryRequestReturnPayload{
+		ID:                     request.ID,
+		GiverName:              request.GiverName,
+		OriginCity:             request.OriginCity,
+		OriginCountryCode:      request.OriginCountryCode,
+		DestinationCity:        request.DestinationCity,
+		DestinationCountryCode: request.DestinationCountryCode,
+		Weight:                 request.Weight,
+		Budget:                 request.Budget,
+		Currency:               request.Currency,
+		Deadline:               request.Deadline,
+		PackageContent:         request.PackageContent,
+		PackageImageURL:        utils.ImageURL(request.PackageImageURL),
+		Notes:                  request.Notes,
+		Status:                 utils.RequestStatusIntToString(request.Status),
+		OrderID:                request.OrderID,
+		OfferCount:             request.OfferCount,
+		CreatedAt:              request.CreatedAt,
+	}
+}
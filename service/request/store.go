@@ -0,0 +1,514 @@
+package request
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const deliveryRequestColumns = `dr.id, dr.giver_id, u.name,
+					dr.origin_city, dr.origin_country_code,
+					dr.destination_city, dr.destination_country_code,
+					dr.weight, dr.budget, dr.currency_id, c.name,
+					dr.deadline, dr.package_content, dr.package_img_url,
+					dr.notes, dr.status, dr.order_id,
+					(SELECT COUNT(*) FROM request_offer AS ro
+						WHERE ro.request_id = dr.id AND ro.status = ?) AS offer_count,
+					dr.created_at, dr.last_modified_at
+				FROM delivery_request AS dr
+				JOIN user AS u ON u.id = dr.giver_id
+				JOIN currency AS c ON c.id = dr.currency_id `
+
+const requestOfferColumns = `ro.id, ro.request_id, ro.carrier_id, u.name, u.email,
+					ro.price, ro.departure_date, ro.last_received_date,
+					ro.message, ro.status, ro.created_at, ro.last_modified_at
+				FROM request_offer AS ro
+				JOIN user AS u ON u.id = ro.carrier_id `
+
+func (s *Store) CreateDeliveryRequest(request types.DeliveryRequest) (int, error) {
+	values := "?"
+	for i := 0; i < 11; i++ {
+		values += ", ?"
+	}
+
+	query := `INSERT INTO delivery_request (
+					giver_id, origin_city, origin_country_code,
+					destination_city, destination_country_code,
+					weight, budget, currency_id, deadline,
+					package_content, package_img_url, notes)
+					VALUES (` + values + `)`
+
+	res, err := s.db.Exec(query, request.GiverID, request.OriginCity, request.OriginCountryCode,
+		request.DestinationCity, request.DestinationCountryCode,
+		request.Weight, request.Budget, request.CurrencyID, request.Deadline,
+		request.PackageContent, request.PackageImageURL, request.Notes)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *Store) GetDeliveryRequestByID(id int) (*types.DeliveryRequest, error) {
+	query := `SELECT ` + deliveryRequestColumns + `WHERE dr.id = ?`
+	rows, err := s.db.Query(query, constants.OFFER_STATUS_PENDING, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	request := new(types.DeliveryRequest)
+
+	for rows.Next() {
+		request, err = scanRowIntoDeliveryRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if request.ID == 0 {
+		return nil, fmt.Errorf("delivery request not found")
+	}
+
+	return request, nil
+}
+
+// newest first
+func (s *Store) GetDeliveryRequestsByGiverID(giverId int) ([]types.DeliveryRequest, error) {
+	query := `SELECT ` + deliveryRequestColumns + `WHERE dr.giver_id = ?
+				ORDER BY dr.created_at DESC, dr.id DESC`
+	rows, err := s.db.Query(query, constants.OFFER_STATUS_PENDING, giverId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]types.DeliveryRequest, 0)
+
+	for rows.Next() {
+		request, err := scanRowIntoDeliveryRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, *request)
+	}
+
+	return requests, nil
+}
+
+// returns the open requests of the other givers whose deadline has not passed,
+// the closest deadline first
+func (s *Store) GetOpenDeliveryRequests(giverId int, filter types.DeliveryRequestFilter) ([]types.DeliveryRequest, error) {
+	condition, args := openDeliveryRequestCondition(giverId, filter)
+
+	query := `SELECT ` + deliveryRequestColumns + `WHERE ` + condition + `
+				ORDER BY dr.deadline ASC, dr.id ASC
+				LIMIT ? OFFSET ?`
+
+	args = append([]any{constants.OFFER_STATUS_PENDING}, args...)
+	args = append(args, filter.Limit, filter.Offset)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := make([]types.DeliveryRequest, 0)
+
+	for rows.Next() {
+		request, err := scanRowIntoDeliveryRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		requests = append(requests, *request)
+	}
+
+	return requests, nil
+}
+
+func (s *Store) GetOpenDeliveryRequestCount(giverId int, filter types.DeliveryRequestFilter) (int, error) {
+	condition, args := openDeliveryRequestCondition(giverId, filter)
+
+	query := `SELECT COUNT(*) FROM delivery_request AS dr WHERE ` + condition
+
+	var count int
+	err := s.db.QueryRow(query, args...).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *Store) IsPackageImageURLExist(packageImgUrl string) bool {
+	query := `SELECT COUNT(*) FROM delivery_request WHERE package_img_url = ?`
+
+	row := s.db.QueryRow(query, packageImgUrl)
+
+	var count int
+	err := row.Scan(&count)
+	if err != nil {
+		return true
+	}
+
+	return (count > 0)
+}
+
+// any carrier can see the package of an open request, afterwards only
+// the giver and the carriers who made an offer
+func (s *Store) IsPackageImageVisibleTo(userId int, packageImgUrl string) (bool, error) {
+	query := `SELECT COUNT(*) FROM delivery_request AS dr
+				WHERE dr.package_img_url = ?
+				AND (dr.giver_id = ? OR dr.status = ? OR EXISTS (
+					SELECT 1 FROM request_offer AS ro
+					WHERE ro.request_id = dr.id AND ro.carrier_id = ?
+				))`
+
+	var count int
+	err := s.db.QueryRow(query, packageImgUrl, userId, constants.REQUEST_STATUS_OPEN, userId).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return (count > 0), nil
+}
+
+func (s *Store) CreateOffer(offer types.RequestOffer) (int, error) {
+	query := `INSERT INTO request_offer (
+					request_id, carrier_id, price,
+					departure_date, last_received_date, message)
+					VALUES (?, ?, ?, ?, ?, ?)`
+
+	res, err := s.db.Exec(query, offer.RequestID, offer.CarrierID, offer.Price,
+		offer.DepartureDate, offer.LastReceivedDate, offer.Message)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+func (s *Store) GetOfferByID(id int) (*types.RequestOffer, error) {
+	query := `SELECT ` + requestOfferColumns + `WHERE ro.id = ?`
+	rows, err := s.db.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offer := new(types.RequestOffer)
+
+	for rows.Next() {
+		offer, err = scanRowIntoRequestOffer(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if offer.ID == 0 {
+		return nil, fmt.Errorf("offer not found")
+	}
+
+	return offer, nil
+}
+
+// the cheapest offer first
+func (s *Store) GetOffersByRequestID(requestId int) ([]types.RequestOffer, error) {
+	query := `SELECT ` + requestOfferColumns + `WHERE ro.request_id = ?
+				ORDER BY ro.price ASC, ro.id ASC`
+	rows, err := s.db.Query(query, requestId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offers := make([]types.RequestOffer, 0)
+
+	for rows.Next() {
+		offer, err := scanRowIntoRequestOffer(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		offers = append(offers, *offer)
+	}
+
+	return offers, nil
+}
+
+func (s *Store) IsOfferPending(requestId int, carrierId int) (bool, error) {
+	query := `SELECT COUNT(*) FROM request_offer
+				WHERE request_id = ? AND carrier_id = ? AND status = ?`
+
+	var count int
+	err := s.db.QueryRow(query, requestId, carrierId, constants.OFFER_STATUS_PENDING).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return (count > 0), nil
+}
+
+// only a pending offer can be withdrawn
+func (s *Store) WithdrawOffer(id int, carrierId int) (bool, error) {
+	query := `UPDATE request_offer SET status = ?, last_modified_at = ?
+				WHERE id = ? AND carrier_id = ? AND status = ?`
+	res, err := s.db.Exec(query, constants.OFFER_STATUS_WITHDRAWN, time.Now(),
+		id, carrierId, constants.OFFER_STATUS_PENDING)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
+func (s *Store) GetDeliveryRequestByIDForUpdate(tx *sql.Tx, id int) (*types.DeliveryRequest, error) {
+	query := `SELECT ` + deliveryRequestColumns + `WHERE dr.id = ? FOR UPDATE`
+	rows, err := tx.Query(query, constants.OFFER_STATUS_PENDING, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	request := new(types.DeliveryRequest)
+
+	for rows.Next() {
+		request, err = scanRowIntoDeliveryRequest(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if request.ID == 0 {
+		return nil, types.ErrDeliveryRequestNotFound
+	}
+
+	return request, nil
+}
+
+func (s *Store) GetOfferByIDForUpdate(tx *sql.Tx, id int) (*types.RequestOffer, error) {
+	query := `SELECT ` + requestOfferColumns + `WHERE ro.id = ? FOR UPDATE`
+	rows, err := tx.Query(query, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offer := new(types.RequestOffer)
+
+	for rows.Next() {
+		offer, err = scanRowIntoRequestOffer(rows)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if offer.ID == 0 {
+		return nil, types.ErrOfferNotFound
+	}
+
+	return offer, nil
+}
+
+// marks the offer accepted, rejects the other pending ones
+// and closes the request with the order it became
+func (s *Store) AcceptOfferTx(tx *sql.Tx, requestId int, offerId int, orderId int) error {
+	query := `UPDATE request_offer SET status = ?, last_modified_at = ? WHERE id = ?`
+	_, err := tx.Exec(query, constants.OFFER_STATUS_ACCEPTED, time.Now(), offerId)
+	if err != nil {
+		return err
+	}
+
+	err = rejectPendingOffers(tx, requestId)
+	if err != nil {
+		return err
+	}
+
+	query = `UPDATE delivery_request SET status = ?, order_id = ?, last_modified_at = ?
+				WHERE id = ?`
+	_, err = tx.Exec(query, constants.REQUEST_STATUS_ACCEPTED, orderId, time.Now(), requestId)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// closes the request and rejects its pending offers
+func (s *Store) CancelDeliveryRequestTx(tx *sql.Tx, id int) error {
+	query := `UPDATE delivery_request SET status = ?, last_modified_at = ?
+				WHERE id = ?`
+	_, err := tx.Exec(query, constants.REQUEST_STATUS_CANCELLED, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return rejectPendingOffers(tx, id)
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func rejectPendingOffers(db execer, requestId int) error {
+	query := `UPDATE request_offer SET status = ?, last_modified_at = ?
+				WHERE request_id = ? AND status = ?`
+	_, err := db.Exec(query, constants.OFFER_STATUS_REJECTED, time.Now(),
+		requestId, constants.OFFER_STATUS_PENDING)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func openDeliveryRequestCondition(giverId int, filter types.DeliveryRequestFilter) (string, []any) {
+	today := time.Date(time.Now().Local().Year(), time.Now().Local().Month(), time.Now().Local().Day(), 0, 0, 0, 0, time.Now().Local().Location())
+
+	condition := "dr.status = ? AND dr.deadline >= ? AND dr.giver_id != ?"
+	args := []any{constants.REQUEST_STATUS_OPEN, today, giverId}
+
+	if filter.OriginCountryCode != "" {
+		condition += " AND dr.origin_country_code = ?"
+		args = append(args, filter.OriginCountryCode)
+	}
+
+	if filter.OriginCity != "" {
+		condition += " AND dr.origin_city = ?"
+		args = append(args, filter.OriginCity)
+	}
+
+	if filter.DestinationCountryCode != "" {
+		condition += " AND dr.destination_country_code = ?"
+		args = append(args, filter.DestinationCountryCode)
+	}
+
+	if filter.DestinationCity != "" {
+		condition += " AND dr.destination_city = ?"
+		args = append(args, filter.DestinationCity)
+	}
+
+	if filter.MaxWeight > 0 {
+		condition += " AND dr.weight <= ?"
+		args = append(args, filter.MaxWeight)
+	}
+
+	if filter.MatchingCarrierID > 0 {
+		condition += ` AND EXISTS (SELECT 1 FROM listing AS l
+							WHERE l.carrier_id = ? AND l.exp_status = ? AND l.deleted_at IS NULL
+							AND l.origin_country_code = dr.origin_country_code
+							AND l.destination_country_code = dr.destination_country_code
+							AND l.departure_date <= dr.deadline
+							AND l.weight_available >= dr.weight)`
+		args = append(args, filter.MatchingCarrierID, constants.EXP_STATUS_AVAILABLE)
+	}
+
+	return condition, args
+}
+
+func scanRowIntoDeliveryRequest(rows *sql.Rows) (*types.DeliveryRequest, error) {
+	request := new(types.DeliveryRequest)
+
+	var packageImgUrl sql.NullString
+	var notes sql.NullString
+	var orderId sql.NullInt64
+
+	err := rows.Scan(
+		&request.ID,
+		&request.GiverID,
+		&request.GiverName,
+		&request.OriginCity,
+		&request.OriginCountryCode,
+		&request.DestinationCity,
+		&request.DestinationCountryCode,
+		&request.Weight,
+		&request.Budget,
+		&request.CurrencyID,
+		&request.Currency,
+		&request.Deadline,
+		&request.PackageContent,
+		&packageImgUrl,
+		&notes,
+		&request.Status,
+		&orderId,
+		&request.OfferCount,
+		&request.CreatedAt,
+		&request.LastModifiedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	request.PackageImageURL = packageImgUrl.String
+	request.Notes = notes.String
+	request.OrderID = int(orderId.Int64)
+
+	request.Deadline = request.Deadline.Local()
+	request.CreatedAt = request.CreatedAt.Local()
+	request.LastModifiedAt = request.LastModifiedAt.Local()
+
+	return request, nil
+}
+
+func scanRowIntoRequestOffer(rows *sql.Rows) (*types.RequestOffer, error) {
+	offer := new(types.RequestOffer)
+
+	var message sql.NullString
+
+	err := rows.Scan(
+		&offer.ID,
+		&offer.RequestID,
+		&offer.CarrierID,
+		&offer.CarrierName,
+		&offer.CarrierEmail,
+		&offer.Price,
+		&offer.DepartureDate,
+		&offer.LastReceivedDate,
+		&message,
+		&offer.Status,
+		&offer.CreatedAt,
+		&offer.LastModifiedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	offer.Message = message.String
+
+	offer.DepartureDate = offer.DepartureDate.Local()
+	offer.LastReceivedDate = offer.LastReceivedDate.Local()
+	offer.CreatedAt = offer.CreatedAt.Local()
+	offer.LastModifiedAt = offer.LastModifiedAt.Local()
+
+	return offer, nil
+}
@@ -0,0 +1,152 @@
+package request
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/service/order/pricing"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// UnitOfWork accepts an offer by creating the carrier's listing and the giver's
+// confirmed order in one transaction, with the request and offer rows locked,
+// so a request never ends up with two orders or an order without its listing.
+// Cancelling a request locks its row the same way.
+type UnitOfWork struct {
+	db              *sql.DB
+	requestStore    types.DeliveryRequestStore
+	listingStore    types.ListingStore
+	orderUnitOfWork types.OrderUnitOfWork
+}
+
+func NewUnitOfWork(conn *sql.DB, requestStore types.DeliveryRequestStore,
+	listingStore types.ListingStore, orderUnitOfWork types.OrderUnitOfWork) *UnitOfWork {
+	return &UnitOfWork{
+		db:              conn,
+		requestStore:    requestStore,
+		listingStore:    listingStore,
+		orderUnitOfWork: orderUnitOfWork,
+	}
+}
+
+// returns the id of the created order
+func (u *UnitOfWork) AcceptOffer(requestId int, offerId int, giverId int) (int, error) {
+	var orderId int
+
+	err := db.RunInTx(u.db, func(tx *sql.Tx) error {
+		request, err := u.requestStore.GetDeliveryRequestByIDForUpdate(tx, requestId)
+		if err != nil {
+			return err
+		}
+
+		if request.GiverID != giverId {
+			return types.ErrDeliveryRequestNotFound
+		}
+
+		if request.Status != constants.REQUEST_STATUS_OPEN {
+			return types.ErrRequestNotOpen
+		}
+
+		offer, err := u.requestStore.GetOfferByIDForUpdate(tx, offerId)
+		if err != nil {
+			return err
+		}
+
+		if offer.RequestID != request.ID {
+			return types.ErrOfferNotFound
+		}
+
+		if offer.Status != constants.OFFER_STATUS_PENDING {
+			return types.ErrOfferNotPending
+		}
+
+		// the listing only carries this request, so it is reserved for it
+		// and never shows up in the search
+		listing := types.Listing{
+			CarrierID:              offer.CarrierID,
+			Destination:            fmt.Sprintf("%s, %s", request.DestinationCity, request.DestinationCountryCode),
+			OriginCity:             request.OriginCity,
+			OriginCountryCode:      request.OriginCountryCode,
+			DestinationCity:        request.DestinationCity,
+			DestinationCountryCode: request.DestinationCountryCode,
+			WeightAvailable:        request.Weight,
+			PricePerKg:             offer.Price / request.Weight,
+			CurrencyID:             request.CurrencyID,
+			DepartureDate:          offer.DepartureDate,
+			LastReceivedDate:       offer.LastReceivedDate,
+			ExpStatus:              constants.EXP_STATUS_RESERVED,
+			Description:            fmt.Sprintf("Delivery request no. %d", request.ID),
+		}
+
+		listing.ID, err = u.listingStore.CreateListingTx(tx, listing)
+		if err != nil {
+			return err
+		}
+
+		quote := quoteOffer(request, offer)
+
+		order := types.Order{
+			ListingID:       listing.ID,
+			GiverID:         request.GiverID,
+			Weight:          request.Weight,
+			Price:           quote.Total,
+			CurrencyID:      request.CurrencyID,
+			PackageContent:  request.PackageContent,
+			PackageImageURL: request.PackageImageURL,
+			Notes:           request.Notes,
+		}
+
+		orderId, err = u.orderUnitOfWork.CreateOrderTx(tx, order, constants.ACTOR_GIVER,
+			fmt.Sprintf("delivery request no. %d", request.ID))
+		if err != nil {
+			return err
+		}
+
+		// the carrier already agreed to the request by making the offer,
+		// confirming reserves the weight like any other confirmation
+		err = u.orderUnitOfWork.TransitionOrderTx(tx, orderId, constants.ORDER_STATUS_CONFIRMED,
+			constants.ACTOR_CARRIER, "", fmt.Sprintf("offer no. %d accepted", offer.ID))
+		if err != nil {
+			return err
+		}
+
+		return u.requestStore.AcceptOfferTx(tx, request.ID, offer.ID, orderId)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return orderId, nil
+}
+
+// cancels an open request of the giver and rejects its pending offers,
+// with the request row locked so an accept can't run in between
+func (u *UnitOfWork) CancelRequest(requestId int, giverId int) error {
+	return db.RunInTx(u.db, func(tx *sql.Tx) error {
+		request, err := u.requestStore.GetDeliveryRequestByIDForUpdate(tx, requestId)
+		if err != nil {
+			return err
+		}
+
+		if request.GiverID != giverId {
+			return types.ErrDeliveryRequestNotFound
+		}
+
+		if request.Status != constants.REQUEST_STATUS_OPEN {
+			return types.ErrRequestNotOpen
+		}
+
+		return u.requestStore.CancelDeliveryRequestTx(tx, request.ID)
+	})
+}
+
+// prices the offer like an order on a listing at the offered rate,
+// so the giver sees the fees before accepting
+func quoteOffer(request *types.DeliveryRequest, offer *types.RequestOffer) types.PriceQuote {
+	return pricing.Quote(&types.ListingReturnFromDB{
+		PricePerKg: offer.Price / request.Weight,
+		Currency:   request.Currency,
+	}, request.Weight, false)
+}
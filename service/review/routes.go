@@ -10,6 +10,7 @@ import (
 
 	"github.com/nicolaics/jim-carrier-server/constants"
 	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
 	"github.com/nicolaics/jim-carrier-server/types"
 	"github.com/nicolaics/jim-carrier-server/utils"
 )
@@ -66,23 +67,9 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	reviewer, err := h.userStore.ValidateUserAccessToken(w, r)
+	reviewer, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	reviewer, err = h.userStore.GetUserByID(reviewer.ID)
-	if reviewer == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
-		return
-	}
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -148,23 +135,9 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 /*
 func (h *Handler) handleGetAllSent(w http.ResponseWriter, r *http.Request) {
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("account not found"))
-		return
-	}
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -200,13 +173,6 @@ func (h *Handler) handleGetAllReceived(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	_, err := h.userStore.ValidateUserAccessToken(w, r)
-	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
 
 	reviews, err := h.reviewStore.GetReceivedReviewsByUserID(payload.CarrierID)
 	if err != nil {
@@ -239,11 +205,9 @@ func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -295,11 +259,9 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -177,6 +177,25 @@ func (s *Store) GetAverageRating(userId int, reviewType int) (float64, error) {
 	return avgRating.Float64, nil
 }
 
+func (s *Store) GetReviewCount(userId int, reviewType int) (int, error) {
+	query := `SELECT COUNT(*) 
+				FROM review AS r 
+				JOIN order_list AS o ON r.order_id = o.id 
+				JOIN listing AS l ON l.id = o.listing_id 
+				WHERE r.reviewee_id = ? 
+				AND r.review_type = ?
+				AND o.deleted_at IS NULL 
+				AND l.deleted_at IS NULL`
+
+	var count int
+	err := s.db.QueryRow(query, userId, reviewType).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
 func scanRowIntoReceivedReview(rows *sql.Rows) (*types.ReceivedReviewReturnPayload, error) {
 	temp := new(struct {
 		ID                 int
@@ -0,0 +1,109 @@
+package savedsearch
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/config"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// Alerter sends one alert per user for a new listing matching their saved
+// searches, and at most one per interval, so a burst of new trips to the same
+// city doesn't flood anyone
+type Alerter struct {
+	savedSearchStore types.SavedSearchStore
+	listingStore     types.ListingStore
+	notifier         types.Notifier
+	interval         time.Duration
+}
+
+func NewAlerter(savedSearchStore types.SavedSearchStore, listingStore types.ListingStore,
+	notifier types.Notifier, interval time.Duration) *Alerter {
+	return &Alerter{
+		savedSearchStore: savedSearchStore,
+		listingStore:     listingStore,
+		notifier:         notifier,
+		interval:         interval,
+	}
+}
+
+// failures are only logged, the listing is already created
+func (a *Alerter) AlertNewListing(listingId int) {
+	listing, err := a.listingStore.GetListingByID(listingId)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error get listing %d for saved search alerts: %v", listingId, err))
+		return
+	}
+
+	savedSearches, err := a.savedSearchStore.GetSavedSearchesMatchingListing(listingId)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error get saved searches matching listing %d: %v", listingId, err))
+		return
+	}
+
+	// the searches come ordered by user, only the first one of each user is used
+	alertedUserId := 0
+
+	for _, savedSearch := range savedSearches {
+		if savedSearch.UserID == alertedUserId {
+			continue
+		}
+		alertedUserId = savedSearch.UserID
+
+		claimed, err := a.savedSearchStore.ClaimAlertSlot(savedSearch.UserID, a.interval)
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error claim saved search alert of user %d: %v", savedSearch.UserID, err))
+			continue
+		}
+
+		if !claimed {
+			continue
+		}
+
+		a.alert(savedSearch, listing)
+	}
+}
+
+func (a *Alerter) alert(savedSearch types.SavedSearch, listing *types.ListingReturnFromDB) {
+	subject := fmt.Sprintf("New Trip for \"%s\"", savedSearch.Name)
+
+	body := fmt.Sprintf("%s is going to %s on %s, %.2f %s/kg with %.2f kg available",
+		listing.CarrierName, listing.Destination, listing.DepartureDate.Format("02 Jan 2006"),
+		listing.PricePerKg, listing.Currency, listing.WeightAvailable)
+
+	unsubscribeURL := fmt.Sprintf("%s:%s/api/v1/saved-search/unsubscribe?token=%s",
+		config.Envs.PublicHost, config.Envs.Port, savedSearch.UnsubscribeToken)
+
+	err := a.notifier.Notify(types.NotificationMessage{
+		Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+		To:      savedSearch.UserEmail,
+		Title:   subject,
+		Body: fmt.Sprintf("<h4>%s</h4><p><a href=\"%s\">Stop alerts for this search</a></p>",
+			body, unsubscribeURL),
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending saved search alert email to %s: %v", savedSearch.UserEmail, err))
+	}
+
+	err = a.notifier.Notify(types.NotificationMessage{
+		Channel:  constants.NOTIFICATION_CHANNEL_PUSH,
+		ToUserID: savedSearch.UserID,
+		Title:    subject,
+		Body:     body,
+		Data: types.FCMData{
+			Type:      "new_listing",
+			ListingID: fmt.Sprintf("%d", listing.ID),
+		},
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error sending saved search alert to user %d: %v", savedSearch.UserID, err))
+	}
+
+	err = a.savedSearchStore.UpdateLastAlertedAt(savedSearch.ID)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error update last alerted at of saved search %d: %v", savedSearch.ID, err))
+	}
+}
@@ -0,0 +1,314 @@
+package savedsearch
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gorilla/mux"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"github.com/nicolaics/jim-carrier-server/utils"
+)
+
+type Handler struct {
+	savedSearchStore types.SavedSearchStore
+}
+
+func NewHandler(savedSearchStore types.SavedSearchStore) *Handler {
+	return &Handler{savedSearchStore: savedSearchStore}
+}
+
+func (h *Handler) RegisterRoutes(router *mux.Router) {
+	router.HandleFunc("/saved-search", h.handlePost).Methods(http.MethodPost)
+	router.HandleFunc("/saved-search", h.handleGetAll).Methods(http.MethodGet)
+	router.HandleFunc("/saved-search", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/saved-search/{id:[0-9]+}", h.handleSetAlerts).Methods(http.MethodPatch)
+	router.HandleFunc("/saved-search/{id:[0-9]+}", h.handleDelete).Methods(http.MethodDelete)
+	router.HandleFunc("/saved-search/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+// the unsubscribe link in the alert emails works without logging in.
+// GET only shows a confirm page, so link scanners don't turn the alerts off
+func (h *Handler) RegisterUnprotectedRoutes(router *mux.Router) {
+	router.HandleFunc("/saved-search/unsubscribe", h.handleUnsubscribeConfirm).Methods(http.MethodGet)
+	router.HandleFunc("/saved-search/unsubscribe", h.handleUnsubscribe).Methods(http.MethodPost)
+	router.HandleFunc("/saved-search/unsubscribe", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+}
+
+func (h *Handler) handlePost(w http.ResponseWriter, r *http.Request) {
+	var payload types.SavedSearchPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("post saved search payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	payload.Name = strings.TrimSpace(payload.Name)
+	payload.Destination = strings.TrimSpace(payload.Destination)
+	payload.Currency = strings.ToUpper(strings.TrimSpace(payload.Currency))
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	savedSearch := types.SavedSearch{
+		UserID:        user.ID,
+		Name:          payload.Name,
+		Destination:   payload.Destination,
+		MaxPricePerKg: payload.MaxPricePerKg,
+		Currency:      payload.Currency,
+		MinWeight:     payload.MinWeight,
+	}
+
+	// same date window as the departureFrom and departureTo of the listing search
+	if payload.DepartureFrom != "" {
+		date, err := utils.ParseStartDate(payload.DepartureFrom)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid departureFrom"))
+			return
+		}
+
+		savedSearch.DepartureFrom = *date
+	}
+
+	if payload.DepartureTo != "" {
+		date, err := utils.ParseEndDate(payload.DepartureTo)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid departureTo"))
+			return
+		}
+
+		savedSearch.DepartureTo = *date
+	}
+
+	if !savedSearch.DepartureFrom.IsZero() && !savedSearch.DepartureTo.IsZero() &&
+		!savedSearch.DepartureFrom.Before(savedSearch.DepartureTo) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("departureFrom must be before departureTo"))
+		return
+	}
+
+	if savedSearch.Destination == "" && savedSearch.DepartureFrom.IsZero() && savedSearch.DepartureTo.IsZero() &&
+		savedSearch.MaxPricePerKg == 0 && savedSearch.MinWeight == 0 {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("at least one search criteria is required"))
+		return
+	}
+
+	if savedSearch.MaxPricePerKg > 0 && savedSearch.Currency == "" {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("currency is required with maxPricePerKg"))
+		return
+	}
+
+	count, err := h.savedSearchStore.GetSavedSearchCountByUserID(user.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if count >= constants.SAVED_SEARCH_MAX_PER_USER {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("you can save up to %d searches", constants.SAVED_SEARCH_MAX_PER_USER))
+		return
+	}
+
+	savedSearch.UnsubscribeToken, err = utils.GenerateSecureToken(32)
+	if err != nil {
+		log.Printf("error generate unsubscribe token: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error generate unsubscribe token: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	err = h.savedSearchStore.CreateSavedSearch(savedSearch)
+	if err != nil {
+		log.Printf("error create saved search: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error create saved search: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "search saved")
+}
+
+func (h *Handler) handleGetAll(w http.ResponseWriter, r *http.Request) {
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	savedSearches, err := h.savedSearchStore.GetSavedSearchesByUserID(user.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnPayloads := make([]types.SavedSearchReturnPayload, 0)
+
+	for _, savedSearch := range savedSearches {
+		returnPayloads = append(returnPayloads, types.SavedSearchReturnPayload{
+			ID:            savedSearch.ID,
+			Name:          savedSearch.Name,
+			Destination:   savedSearch.Destination,
+			DepartureFrom: savedSearch.DepartureFrom,
+			DepartureTo:   savedSearch.DepartureTo,
+			MaxPricePerKg: savedSearch.MaxPricePerKg,
+			Currency:      savedSearch.Currency,
+			MinWeight:     savedSearch.MinWeight,
+			AlertsEnabled: savedSearch.AlertsEnabled,
+			LastAlertedAt: savedSearch.LastAlertedAt,
+			CreatedAt:     savedSearch.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, returnPayloads)
+}
+
+func (h *Handler) handleSetAlerts(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid saved search id"))
+		return
+	}
+
+	var payload types.SavedSearchAlertsPayload
+
+	if err := utils.ParseJSON(r, &payload); err != nil {
+		log.Printf("payload error: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("saved search alerts payload error: %v", err))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		return
+	}
+
+	// validate the payload
+	if err := utils.Validate.Struct(payload); err != nil {
+		errors := err.(validator.ValidationErrors)
+		log.Printf("invalid payload: %v", errors)
+		logger.WriteServerLog(fmt.Sprintf("payload error: %v", errors))
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid payload"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	updated, err := h.savedSearchStore.SetAlertsEnabled(id, user.ID, *payload.AlertsEnabled)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !updated {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("saved search not found"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "saved search updated")
+}
+
+func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid saved search id"))
+		return
+	}
+
+	// validate token
+	user, err := jwt.GetUserFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	deleted, err := h.savedSearchStore.DeleteSavedSearch(id, user.ID)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !deleted {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("saved search not found"))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "saved search deleted")
+}
+
+func (h *Handler) handleUnsubscribeConfirm(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" || !h.savedSearchStore.IsUnsubscribeTokenExist(token) {
+		writeUnsubscribePage(w, http.StatusBadRequest, "<p>This link is not valid anymore.</p>")
+		return
+	}
+
+	writeUnsubscribePage(w, http.StatusOK, fmt.Sprintf(
+		"<p>Stop the alerts for this saved search?</p>"+
+			"<form method=\"post\" action=\"?token=%s\"><button type=\"submit\">Stop alerts</button></form>",
+		html.EscapeString(url.QueryEscape(token))))
+}
+
+func (h *Handler) handleUnsubscribe(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		writeUnsubscribePage(w, http.StatusBadRequest, "<p>This link is not valid anymore.</p>")
+		return
+	}
+
+	unsubscribed, err := h.savedSearchStore.UnsubscribeByToken(token)
+	if err != nil {
+		log.Println(err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !unsubscribed {
+		writeUnsubscribePage(w, http.StatusBadRequest, "<p>This link is not valid anymore.</p>")
+		return
+	}
+
+	writeUnsubscribePage(w, http.StatusOK, "<p>Alerts for this search are turned off.</p>")
+}
+
+// the unsubscribe pages are opened from an email, so they are html, not json
+func writeUnsubscribePage(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<!DOCTYPE html><html><head><title>Saved Search Alerts</title></head><body>%s</body></html>", body)
+}
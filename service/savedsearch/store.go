@@ -0,0 +1,257 @@
+package savedsearch
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+const savedSearchColumns = `ss.id, ss.user_id, u.name, u.email, ss.name, ss.destination,
+					ss.departure_from, ss.departure_to, ss.max_price_per_kg,
+					ss.currency, ss.min_weight, ss.alerts_enabled,
+					ss.unsubscribe_token, ss.last_alerted_at, ss.created_at
+				FROM saved_search AS ss
+				JOIN user AS u ON u.id = ss.user_id `
+
+func (s *Store) CreateSavedSearch(savedSearch types.SavedSearch) error {
+	query := `INSERT INTO saved_search (
+					user_id, name, destination, departure_from, departure_to,
+					max_price_per_kg, currency, min_weight, unsubscribe_token)
+					VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query, savedSearch.UserID, savedSearch.Name, savedSearch.Destination,
+		nullTime(savedSearch.DepartureFrom), nullTime(savedSearch.DepartureTo),
+		savedSearch.MaxPricePerKg, savedSearch.Currency, savedSearch.MinWeight,
+		savedSearch.UnsubscribeToken)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// newest first
+func (s *Store) GetSavedSearchesByUserID(userId int) ([]types.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + `WHERE ss.user_id = ?
+				ORDER BY ss.created_at DESC, ss.id DESC`
+	rows, err := s.db.Query(query, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	savedSearches := make([]types.SavedSearch, 0)
+
+	for rows.Next() {
+		savedSearch, err := scanRowIntoSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		savedSearches = append(savedSearches, *savedSearch)
+	}
+
+	return savedSearches, nil
+}
+
+func (s *Store) GetSavedSearchCountByUserID(userId int) (int, error) {
+	query := `SELECT COUNT(*) FROM saved_search WHERE user_id = ?`
+
+	var count int
+	err := s.db.QueryRow(query, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (s *Store) SetAlertsEnabled(id int, userId int, alertsEnabled bool) (bool, error) {
+	query := `UPDATE saved_search SET alerts_enabled = ? WHERE id = ? AND user_id = ?`
+	res, err := s.db.Exec(query, alertsEnabled, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
+func (s *Store) DeleteSavedSearch(id int, userId int) (bool, error) {
+	query := `DELETE FROM saved_search WHERE id = ? AND user_id = ?`
+	res, err := s.db.Exec(query, id, userId)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
+// turns the alerts of the search off, a search that is already off is not an error
+func (s *Store) UnsubscribeByToken(token string) (bool, error) {
+	query := `SELECT COUNT(*) FROM saved_search WHERE unsubscribe_token = ?`
+
+	var count int
+	err := s.db.QueryRow(query, token).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	if count == 0 {
+		return false, nil
+	}
+
+	query = `UPDATE saved_search SET alerts_enabled = ? WHERE unsubscribe_token = ?`
+	_, err = s.db.Exec(query, false, token)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (s *Store) IsUnsubscribeTokenExist(token string) bool {
+	query := `SELECT COUNT(*) FROM saved_search WHERE unsubscribe_token = ?`
+
+	var count int
+	err := s.db.QueryRow(query, token).Scan(&count)
+	if err != nil {
+		return true
+	}
+
+	return (count > 0)
+}
+
+// returns the saved searches with alerts on that the listing satisfies,
+// leaving out the carrier's own
+func (s *Store) GetSavedSearchesMatchingListing(listingId int) ([]types.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + `
+				JOIN listing AS l ON l.id = ?
+				JOIN currency AS c ON c.id = l.currency_id
+				WHERE ss.alerts_enabled = ? AND ss.user_id != l.carrier_id
+				AND u.suspended_at IS NULL
+				AND (ss.destination = ''
+					OR LOCATE(LOWER(ss.destination), LOWER(l.destination)) > 0
+					OR LOCATE(LOWER(ss.destination), LOWER(l.destination_city)) > 0)
+				AND (ss.departure_from IS NULL OR l.departure_date >= ss.departure_from)
+				AND (ss.departure_to IS NULL OR l.departure_date < ss.departure_to)
+				AND (ss.min_weight = 0 OR l.weight_available >= ss.min_weight)
+				AND (ss.max_price_per_kg = 0 OR l.price_per_kg <= ss.max_price_per_kg)
+				AND (ss.currency = '' OR c.name = ss.currency)
+				ORDER BY ss.user_id ASC, ss.id ASC`
+	rows, err := s.db.Query(query, listingId, true)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	savedSearches := make([]types.SavedSearch, 0)
+
+	for rows.Next() {
+		savedSearch, err := scanRowIntoSavedSearch(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		savedSearches = append(savedSearches, *savedSearch)
+	}
+
+	return savedSearches, nil
+}
+
+// takes the user's alert slot if the last alert is older than the interval.
+// the check and the update are one statement, so two listings posted at once
+// cannot both alert the same user
+func (s *Store) ClaimAlertSlot(userId int, interval time.Duration) (bool, error) {
+	query := `UPDATE user SET saved_search_alerted_at = ?
+				WHERE id = ? AND (saved_search_alerted_at IS NULL OR saved_search_alerted_at < ?)`
+	res, err := s.db.Exec(query, time.Now(), userId, time.Now().Add(-interval))
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
+func (s *Store) UpdateLastAlertedAt(id int) error {
+	query := `UPDATE saved_search SET last_alerted_at = ? WHERE id = ?`
+	_, err := s.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// the zero time means no bound
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+func scanRowIntoSavedSearch(rows *sql.Rows) (*types.SavedSearch, error) {
+	savedSearch := new(types.SavedSearch)
+
+	var departureFrom sql.NullTime
+	var departureTo sql.NullTime
+	var lastAlertedAt sql.NullTime
+
+	err := rows.Scan(
+		&savedSearch.ID,
+		&savedSearch.UserID,
+		&savedSearch.UserName,
+		&savedSearch.UserEmail,
+		&savedSearch.Name,
+		&savedSearch.Destination,
+		&departureFrom,
+		&departureTo,
+		&savedSearch.MaxPricePerKg,
+		&savedSearch.Currency,
+		&savedSearch.MinWeight,
+		&savedSearch.AlertsEnabled,
+		&savedSearch.UnsubscribeToken,
+		&lastAlertedAt,
+		&savedSearch.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if departureFrom.Valid {
+		savedSearch.DepartureFrom = departureFrom.Time.Local()
+	}
+
+	if departureTo.Valid {
+		savedSearch.DepartureTo = departureTo.Time.Local()
+	}
+
+	if lastAlertedAt.Valid {
+		savedSearch.LastAlertedAt = lastAlertedAt.Time.Local()
+	}
+
+	savedSearch.CreatedAt = savedSearch.CreatedAt.Local()
+
+	return savedSearch, nil
+}
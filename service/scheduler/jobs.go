@@ -0,0 +1,192 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/service/notification"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// cancels the waiting orders the carrier did not confirm in time
+func NewOrderDeadlineJob(interval time.Duration, orderStore types.OrderStore, listingStore types.ListingStore,
+	userStore types.UserStore, notifier types.Notifier, orderUnitOfWork types.OrderUnitOfWork) Job {
+	return Job{
+		Name:     "order_deadline",
+		Interval: interval,
+		Run: func() (int, error) {
+			orders, err := orderStore.GetOrdersPastDeadline()
+			if err != nil {
+				return 0, err
+			}
+
+			var errs []error
+			cancelled := 0
+
+			for _, order := range orders {
+				err := orderUnitOfWork.TransitionOrder(order.ID, constants.ORDER_STATUS_CANCELLED,
+					constants.ACTOR_SYSTEM, "", "confirmation deadline passed")
+				if errors.Is(err, types.ErrIllegalTransition) {
+					// confirmed or cancelled since it was read
+					continue
+				}
+				if err != nil {
+					errs = append(errs, fmt.Errorf("order %d: %v", order.ID, err))
+					continue
+				}
+
+				cancelled++
+
+				subject := fmt.Sprintf("Order No. %d Cancelled", order.ID)
+				emailBody := fmt.Sprintf("<h4>Order no. %d has been</h4><br><h2>CANCELLED</h2><br><h4>because it was not confirmed before the deadline.</h4>", order.ID)
+				fcmBody := fmt.Sprintf("Order no. %d has been cancelled because it was not confirmed before the deadline", order.ID)
+
+				giver, err := userStore.GetUserByID(order.GiverID)
+				if err != nil {
+					logger.WriteServerLog(fmt.Sprintf("error get giver of order %d: %v", order.ID, err))
+				} else {
+					notification.NotifyUser(notifier, giver, subject, emailBody, fcmBody, order.ID)
+				}
+
+				listing, err := listingStore.GetListingByID(order.ListingID)
+				if err != nil {
+					logger.WriteServerLog(fmt.Sprintf("error get listing of order %d: %v", order.ID, err))
+					continue
+				}
+
+				carrier, err := userStore.GetUserByID(listing.CarrierID)
+				if err != nil {
+					logger.WriteServerLog(fmt.Sprintf("error get carrier of order %d: %v", order.ID, err))
+					continue
+				}
+
+				notification.NotifyUser(notifier, carrier, subject, emailBody, fcmBody, order.ID)
+			}
+
+			return cancelled, errors.Join(errs...)
+		},
+	}
+}
+
+// expires the listings that have departed or are fully booked,
+// cancelling the orders on them that were never confirmed
+func NewListingExpiryJob(interval time.Duration, listingStore types.ListingStore, orderStore types.OrderStore,
+	userStore types.UserStore, notifier types.Notifier, orderUnitOfWork types.OrderUnitOfWork) Job {
+	return Job{
+		Name:     "listing_expiry",
+		Interval: interval,
+		Run: func() (int, error) {
+			listings, err := listingStore.GetListingsToExpire()
+			if err != nil {
+				return 0, err
+			}
+
+			var errs []error
+			expiredCount := 0
+
+			for _, listing := range listings {
+				expired, err := listingStore.ExpireListing(listing.ID)
+				if err != nil {
+					errs = append(errs, fmt.Errorf("listing %d: %v", listing.ID, err))
+					continue
+				}
+				if !expired {
+					continue
+				}
+
+				expiredCount++
+
+				subject := fmt.Sprintf("Listing to %s Expired", listing.Destination)
+
+				carrier, err := userStore.GetUserByID(listing.CarrierID)
+				if err != nil {
+					logger.WriteServerLog(fmt.Sprintf("error get carrier of listing %d: %v", listing.ID, err))
+				} else {
+					emailBody := fmt.Sprintf("<h4>Your listing to %s departing on %s has</h4><br><h2>EXPIRED</h2>",
+						listing.Destination, listing.DepartureDate.Format("02 Jan 2006"))
+					fcmBody := fmt.Sprintf("Your listing to %s departing on %s has expired",
+						listing.Destination, listing.DepartureDate.Format("02 Jan 2006"))
+
+					notification.NotifyUser(notifier, carrier, subject, emailBody, fcmBody, 0)
+				}
+
+				orders, err := orderStore.GetOrdersByListingID(listing.ID)
+				if err != nil {
+					logger.WriteServerLog(fmt.Sprintf("error get orders of listing %d: %v", listing.ID, err))
+					continue
+				}
+
+				// givers still waiting for a confirmation will not get one anymore
+				for _, order := range orders {
+					if order.OrderStatus != constants.ORDER_STATUS_WAITING {
+						continue
+					}
+
+					err := orderUnitOfWork.TransitionOrder(order.ID, constants.ORDER_STATUS_CANCELLED,
+						constants.ACTOR_SYSTEM, "", "listing expired")
+					if errors.Is(err, types.ErrIllegalTransition) {
+						// confirmed or cancelled since it was read
+						continue
+					}
+					if err != nil {
+						errs = append(errs, fmt.Errorf("order %d: %v", order.ID, err))
+						continue
+					}
+
+					giver, err := userStore.GetUserByEmail(order.GiverEmail)
+					if err != nil {
+						logger.WriteServerLog(fmt.Sprintf("error get giver of order %d: %v", order.ID, err))
+						continue
+					}
+
+					emailBody := fmt.Sprintf("<h4>The listing to %s of order no. %d has</h4><br><h2>EXPIRED</h2><br><h4>before the carrier confirmed your order, so the order is cancelled.</h4>",
+						listing.Destination, order.ID)
+					fcmBody := fmt.Sprintf("The listing to %s of order no. %d has expired before the carrier confirmed your order, so the order is cancelled",
+						listing.Destination, order.ID)
+
+					notification.NotifyUser(notifier, giver, subject, emailBody, fcmBody, order.ID)
+				}
+			}
+
+			return expiredCount, errors.Join(errs...)
+		},
+	}
+}
+
+// deletes the uploads no row points to anymore. a dry run only writes what it would delete to the log
+func NewOrphanedUploadJob(interval time.Duration, collector types.BlobGarbageCollector, dryRun bool) Job {
+	return Job{
+		Name:     "orphaned_upload",
+		Interval: interval,
+		Run: func() (int, error) {
+			report, err := collector.Collect(dryRun)
+			if report == nil {
+				return 0, err
+			}
+
+			// a failed delete is already in err, only a dry run has anything left to report
+			for _, orphan := range report.Orphans {
+				if !report.DryRun || orphan.InGracePeriod {
+					continue
+				}
+
+				logger.WriteServerLog(fmt.Sprintf("orphaned upload %s (%d bytes, modified %s) would be deleted",
+					orphan.Key, orphan.Size, orphan.ModifiedAt.Format(time.RFC3339)))
+			}
+
+			return report.DeletedCount, err
+		},
+	}
+}
+
+// encrypts the payout methods still in plaintext and re-wraps the ones not on the current key
+func NewPayoutKeyRotationJob(interval time.Duration, payoutMethodStore types.PayoutMethodStore) Job {
+	return Job{
+		Name:     "payout_key_rotation",
+		Interval: interval,
+		Run:      payoutMethodStore.RotateEncryptionKeys,
+	}
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/logger"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// Job is a periodic sweep. Run returns how many records it processed.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func() (int, error)
+}
+
+// Scheduler runs each job on its own ticker. Every run takes a database lock
+// named after the job first, so with several server instances only one of them
+// runs a given job at a time; the others skip that tick.
+type Scheduler struct {
+	jobRunStore types.JobRunStore
+	instance    string
+	jobs        []Job
+}
+
+func NewScheduler(jobRunStore types.JobRunStore) *Scheduler {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+
+	return &Scheduler{
+		jobRunStore: jobRunStore,
+		instance:    fmt.Sprintf("%s-%d", instance, os.Getpid()),
+	}
+}
+
+// a job without a positive interval is skipped, a ticker can't run it
+func (s *Scheduler) Register(job Job) {
+	if job.Interval <= 0 {
+		log.Printf("job %s not registered: interval must be greater than 0, got %v", job.Name, job.Interval)
+		logger.WriteServerLog(fmt.Sprintf("job %s not registered: interval must be greater than 0, got %v", job.Name, job.Interval))
+		return
+	}
+
+	s.jobs = append(s.jobs, job)
+}
+
+// starts every registered job in the background, running it once right away
+func (s *Scheduler) Start() {
+	for _, job := range s.jobs {
+		go func(job Job) {
+			ticker := time.NewTicker(job.Interval)
+			defer ticker.Stop()
+
+			for {
+				s.runOnce(job)
+				<-ticker.C
+			}
+		}(job)
+	}
+}
+
+func (s *Scheduler) runOnce(job Job) {
+	conn, acquired, err := s.jobRunStore.AcquireJobLock(job.Name)
+	if err != nil {
+		log.Printf("error acquiring lock for job %s: %v", job.Name, err)
+		logger.WriteServerLog(fmt.Sprintf("error acquiring lock for job %s: %v", job.Name, err))
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	defer func() {
+		err := s.jobRunStore.ReleaseJobLock(conn, job.Name)
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error releasing lock for job %s: %v", job.Name, err))
+		}
+	}()
+
+	jobRun := types.JobRun{
+		JobName:   job.Name,
+		Instance:  s.instance,
+		Status:    constants.JOB_RUN_SUCCESS,
+		StartedAt: time.Now(),
+	}
+
+	jobRun.ProcessedCount, err = s.safeRun(job)
+	if err != nil {
+		jobRun.Status = constants.JOB_RUN_FAILED
+		jobRun.Error = err.Error()
+
+		log.Printf("job %s failed: %v", job.Name, err)
+		logger.WriteServerLog(fmt.Sprintf("job %s failed: %v", job.Name, err))
+	}
+
+	jobRun.FinishedAt = time.Now()
+
+	err = s.jobRunStore.CreateJobRun(jobRun)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error recording run of job %s: %v", job.Name, err))
+	}
+}
+
+// a panicking job is recorded as a failed run instead of taking the server down
+func (s *Scheduler) safeRun(job Job) (count int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return job.Run()
+}
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+func (s *Store) CreateJobRun(jobRun types.JobRun) error {
+	query := `INSERT INTO job_run (
+					job_name, instance, status, processed_count, 
+					error, started_at, finished_at) 
+					VALUES (?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := s.db.Exec(query, jobRun.JobName, jobRun.Instance, jobRun.Status,
+		jobRun.ProcessedCount, jobRun.Error, jobRun.StartedAt, jobRun.FinishedAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// takes the named MySQL lock without waiting. the lock belongs to the returned
+// connection, so it must be released and closed on that same connection
+func (s *Store) AcquireJobLock(jobName string) (*sql.Conn, bool, error) {
+	conn, err := s.db.Conn(context.Background())
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired sql.NullInt64
+
+	err = conn.QueryRowContext(context.Background(), `SELECT GET_LOCK(?, 0)`, lockName(jobName)).Scan(&acquired)
+	if err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	return conn, true, nil
+}
+
+func (s *Store) ReleaseJobLock(conn *sql.Conn, jobName string) error {
+	defer conn.Close()
+
+	var released sql.NullInt64
+
+	return conn.QueryRowContext(context.Background(), `SELECT RELEASE_LOCK(?)`, lockName(jobName)).Scan(&released)
+}
+
+func lockName(jobName string) string {
+	return "jim_carrier_job_" + jobName
+}
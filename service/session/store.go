@@ -0,0 +1,403 @@
+package session
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(db *sql.DB) *Store {
+	return &Store{db: db}
+}
+
+// a session is active while it is not revoked and its refresh token has not expired
+const activeSessionCondition = `revoked_at IS NULL
+				AND EXISTS (
+					SELECT 1 FROM verify_token
+					WHERE verify_token.session_id = user_session.id
+					AND verify_token.token_type = ?
+					AND verify_token.expired_at >= ?
+				)`
+
+func (s *Store) CreateSession(userId int, deviceName string, fcmToken string) (int, error) {
+	query := `INSERT INTO user_session (user_id, device_name, fcm_token, last_used_at)
+				VALUES (?, ?, ?, ?)`
+
+	res, err := s.db.Exec(query, userId, deviceName, fcmToken, time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(id), nil
+}
+
+// returns the active sessions of the user, the most recently used first
+func (s *Store) GetActiveSessionsByUserID(userId int) ([]types.Session, error) {
+	query := `SELECT id, user_id, device_name, fcm_token, last_used_at, revoked_at, created_at
+				FROM user_session
+				WHERE user_id = ? AND ` + activeSessionCondition + `
+				ORDER BY last_used_at DESC, id DESC`
+	rows, err := s.db.Query(query, userId, constants.REFRESH_TOKEN, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]types.Session, 0)
+
+	for rows.Next() {
+		session, err := scanRowIntoSession(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, *session)
+	}
+
+	return sessions, nil
+}
+
+// returns the device tokens of every active session of the user, without duplicates
+func (s *Store) GetActiveFCMTokensByUserID(userId int) ([]string, error) {
+	query := `SELECT DISTINCT fcm_token FROM user_session
+				WHERE user_id = ? AND fcm_token IS NOT NULL AND fcm_token != ''
+				AND ` + activeSessionCondition
+	rows, err := s.db.Query(query, userId, constants.REFRESH_TOKEN, time.Now().UTC().Format("2006-01-02 15:04:05"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	fcmTokens := make([]string, 0)
+
+	for rows.Next() {
+		var fcmToken string
+
+		err := rows.Scan(&fcmToken)
+		if err != nil {
+			return nil, err
+		}
+
+		fcmTokens = append(fcmTokens, fcmToken)
+	}
+
+	return fcmTokens, nil
+}
+
+func (s *Store) UpdateSessionFCMToken(id int, fcmToken string) error {
+	query := `UPDATE user_session SET fcm_token = ? WHERE id = ?`
+	_, err := s.db.Exec(query, fcmToken, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// revokes the session and deletes its tokens.
+// returns false if the user has no such session that is not revoked yet
+func (s *Store) RevokeSession(id int, userId int) (bool, error) {
+	revoked := false
+
+	err := db.RunInTx(s.db, func(tx *sql.Tx) error {
+		query := `UPDATE user_session SET revoked_at = ?
+					WHERE id = ? AND user_id = ? AND revoked_at IS NULL`
+		res, err := tx.Exec(query, time.Now(), id, userId)
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(`DELETE FROM verify_token WHERE session_id = ?`, id)
+		if err != nil {
+			return err
+		}
+
+		revoked = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return revoked, nil
+}
+
+// revokes every session of the user except the current one, returns the ids of the revoked ones
+func (s *Store) RevokeOtherSessions(userId int, currentSessionId int) ([]int, error) {
+	ids := make([]int, 0)
+
+	err := db.RunInTx(s.db, func(tx *sql.Tx) error {
+		query := `SELECT id FROM user_session 
+					WHERE user_id = ? AND id != ? AND revoked_at IS NULL 
+					FOR UPDATE`
+		rows, err := tx.Query(query, userId, currentSessionId)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var id int
+
+			err = rows.Scan(&id)
+			if err != nil {
+				return err
+			}
+
+			ids = append(ids, id)
+		}
+
+		if err = rows.Err(); err != nil {
+			return err
+		}
+
+		query = `UPDATE user_session SET revoked_at = ?
+					WHERE user_id = ? AND id != ? AND revoked_at IS NULL`
+		_, err = tx.Exec(query, time.Now(), userId, currentSessionId)
+		if err != nil {
+			return err
+		}
+
+		query = `DELETE FROM verify_token WHERE user_id = ? AND (session_id IS NULL OR session_id != ?)`
+		_, err = tx.Exec(query, userId, currentSessionId)
+		if err != nil {
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// swaps the session's tokens for the new pair, if the refresh token being
+// rotated is still the current one. returns false if it is not, which means
+// the token was already used, possibly by a concurrent refresh
+func (s *Store) RotateSessionTokens(event types.SessionEvent, accessTokenDetails *types.TokenDetails, refreshTokenDetails *types.TokenDetails) (bool, error) {
+	accessTokenExp := time.Unix(accessTokenDetails.TokenExp, 0)   //converting Unix to UTC(to Time object)
+	refreshTokenExp := time.Unix(refreshTokenDetails.TokenExp, 0) //converting Unix to UTC(to Time object)
+
+	rotated := false
+
+	err := db.RunInTx(s.db, func(tx *sql.Tx) error {
+		query := `DELETE FROM verify_token 
+					WHERE session_id = ? AND uuid = ? AND token_type = ? AND expired_at >= ?`
+		res, err := tx.Exec(query, event.SessionID, event.TokenUUID, constants.REFRESH_TOKEN,
+			time.Now().UTC().Format("2006-01-02 15:04:05"))
+		if err != nil {
+			return err
+		}
+
+		rowsAffected, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		if rowsAffected == 0 {
+			return nil
+		}
+
+		_, err = tx.Exec(`DELETE FROM verify_token WHERE session_id = ? AND token_type = ?`,
+			event.SessionID, constants.ACCESS_TOKEN)
+		if err != nil {
+			return err
+		}
+
+		query = "INSERT INTO verify_token(user_id, session_id, uuid, token_type, expired_at) VALUES (?, ?, ?, ?, ?)"
+		_, err = tx.Exec(query, event.UserID, event.SessionID, accessTokenDetails.UUID, constants.ACCESS_TOKEN, accessTokenExp)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(query, event.UserID, event.SessionID, refreshTokenDetails.UUID, constants.REFRESH_TOKEN, refreshTokenExp)
+		if err != nil {
+			return err
+		}
+
+		// the old uuid is kept in the event, that is how a reuse is recognized later
+		event.EventType = constants.SESSION_EVENT_REFRESHED
+		err = createSessionEvent(tx, event)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`UPDATE user_session SET last_used_at = ? WHERE id = ?`, time.Now(), event.SessionID)
+		if err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return rotated, nil
+}
+
+// true if the refresh token was already rotated away in the session
+func (s *Store) IsRotatedRefreshToken(sessionId int, tokenUuid string) (bool, error) {
+	query := `SELECT COUNT(*) FROM session_event 
+				WHERE session_id = ? AND token_uuid = ? AND event_type = ?`
+
+	var count int
+	err := s.db.QueryRow(query, sessionId, tokenUuid, constants.SESSION_EVENT_REFRESHED).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return (count > 0), nil
+}
+
+func (s *Store) CreateSessionEvent(event types.SessionEvent) error {
+	return createSessionEvent(s.db, event)
+}
+
+// returns the events of every session of the user, newest first
+func (s *Store) GetSessionEventsByUserID(userId int, limit int, offset int) ([]types.SessionEvent, error) {
+	query := `SELECT se.id, se.session_id, se.user_id, se.event_type, se.token_uuid, 
+					se.ip_address, se.user_agent, us.device_name, se.created_at 
+				FROM session_event AS se 
+				LEFT JOIN user_session AS us ON us.id = se.session_id 
+				WHERE se.user_id = ? 
+				ORDER BY se.created_at DESC, se.id DESC 
+				LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, userId, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := make([]types.SessionEvent, 0)
+
+	for rows.Next() {
+		event, err := scanRowIntoSessionEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, *event)
+	}
+
+	return events, nil
+}
+
+func (s *Store) GetSessionEventCountByUserID(userId int) (int, error) {
+	query := `SELECT COUNT(*) FROM session_event WHERE user_id = ?`
+
+	var count int
+	err := s.db.QueryRow(query, userId).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// execer is satisfied by both *sql.DB and *sql.Tx
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+func createSessionEvent(conn execer, event types.SessionEvent) error {
+	query := `INSERT INTO session_event (session_id, user_id, event_type, token_uuid, ip_address, user_agent) 
+				VALUES (?, ?, ?, ?, ?, ?)`
+
+	userAgent := event.UserAgent
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+
+	_, err := conn.Exec(query, event.SessionID, event.UserID, event.EventType, event.TokenUUID,
+		event.IPAddress, userAgent)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func scanRowIntoSession(rows *sql.Rows) (*types.Session, error) {
+	session := new(types.Session)
+
+	var fcmToken sql.NullString
+
+	err := rows.Scan(
+		&session.ID,
+		&session.UserID,
+		&session.DeviceName,
+		&fcmToken,
+		&session.LastUsedAt,
+		&session.RevokedAt,
+		&session.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	session.FCMToken = fcmToken.String
+
+	session.LastUsedAt = session.LastUsedAt.Local()
+	session.CreatedAt = session.CreatedAt.Local()
+	session.RevokedAt.Time = session.RevokedAt.Time.Local()
+
+	return session, nil
+}
+
+func scanRowIntoSessionEvent(rows *sql.Rows) (*types.SessionEvent, error) {
+	event := new(types.SessionEvent)
+
+	var tokenUuid sql.NullString
+	var ipAddress sql.NullString
+	var userAgent sql.NullString
+	var deviceName sql.NullString
+
+	err := rows.Scan(
+		&event.ID,
+		&event.SessionID,
+		&event.UserID,
+		&event.EventType,
+		&tokenUuid,
+		&ipAddress,
+		&userAgent,
+		&deviceName,
+		&event.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	event.TokenUUID = tokenUuid.String
+	event.IPAddress = ipAddress.String
+	event.UserAgent = userAgent.String
+	event.DeviceName = deviceName.String
+
+	event.CreatedAt = event.CreatedAt.Local()
+
+	return event, nil
+}
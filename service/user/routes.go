@@ -1,9 +1,11 @@
 package user
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
@@ -18,11 +20,20 @@ import (
 )
 
 type Handler struct {
-	userStore types.UserStore
+	userStore    types.UserStore
+	sessionStore types.SessionStore
+	notifier     types.Notifier
+	blobStore    types.BlobStore
 }
 
-func NewHandler(userStore types.UserStore) *Handler {
-	return &Handler{userStore: userStore}
+func NewHandler(userStore types.UserStore, sessionStore types.SessionStore, notifier types.Notifier,
+	blobStore types.BlobStore) *Handler {
+	return &Handler{
+		userStore:    userStore,
+		sessionStore: sessionStore,
+		notifier:     notifier,
+		blobStore:    blobStore,
+	}
 }
 
 func (h *Handler) RegisterRoutes(router *mux.Router) {
@@ -43,6 +54,18 @@ func (h *Handler) RegisterRoutes(router *mux.Router) {
 
 	router.HandleFunc("/user/logout", h.handleLogout).Methods(http.MethodPost)
 	router.HandleFunc("/user/logout", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/user/sessions", h.handleGetSessions).Methods(http.MethodGet)
+	router.HandleFunc("/user/sessions", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/user/sessions/events", h.handleGetSessionEvents).Methods(http.MethodGet)
+	router.HandleFunc("/user/sessions/events", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/user/sessions/revoke-others", h.handleRevokeOtherSessions).Methods(http.MethodPost)
+	router.HandleFunc("/user/sessions/revoke-others", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
+
+	router.HandleFunc("/user/sessions/{id}", h.handleRevokeSession).Methods(http.MethodDelete)
+	router.HandleFunc("/user/sessions/{id}", func(w http.ResponseWriter, r *http.Request) { utils.WriteJSONForOptions(w, http.StatusOK, nil) }).Methods(http.MethodOptions)
 }
 
 func (h *Handler) RegisterUnprotectedRoutes(router *mux.Router) {
@@ -119,6 +142,11 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if user.SuspendedAt.Valid {
+		utils.WriteError(w, http.StatusForbidden, types.ErrAccountSuspended)
+		return
+	}
+
 	password, err := h.userStore.GetUserPasswordByEmail(payload.Email)
 	if err != nil {
 		log.Println(err)
@@ -133,39 +161,10 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	isAccessTokenExist, err := h.userStore.IsAccessTokenExist(user.ID)
+	accessTokenDetails, refreshTokenDetails, err := h.createSession(r, user, deviceName(r, payload.DeviceName), payload.FCMToken)
 	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	if isAccessTokenExist {
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("logged in from other device"))
-		return
-	}
-
-	accessTokenDetails, err := jwt.CreateAccessToken(user.ID)
-	if err != nil {
-		log.Printf("failed to generate access token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate access token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	refreshTokenDetails, err := jwt.CreateRefreshToken(user.ID)
-	if err != nil {
-		log.Printf("failed to generate refresh token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate refresh token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	err = h.userStore.SaveToken(user.ID, accessTokenDetails, refreshTokenDetails)
-	if err != nil {
-		log.Printf("error saving token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error saving token: %v", err))
+		log.Printf("error creating session: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error creating session: %v", err))
 		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
@@ -178,13 +177,6 @@ func (h *Handler) handleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user.FCMToken != payload.FCMToken {
-		err = h.userStore.UpdateFCMToken(user.ID, payload.FCMToken)
-		if err != nil {
-			logger.WriteServerLog(fmt.Sprintf("error update FCM token for user %s: %v", user.Email, err))
-		}
-	}
-
 	tokens := map[string]string{
 		"access_token":  accessTokenDetails.Token,
 		"refresh_token": refreshTokenDetails.Token,
@@ -277,22 +269,15 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("image size exceeds the limit of 5MB"))
 		return
 	} else if len(payload.ProfilePicture) > 0 {
-		var imageExtension string
-
-		// check image type
-		mimeType := http.DetectContentType(payload.ProfilePicture)
-		switch mimeType {
-		case "image/jpeg":
-			imageExtension = ".jpg"
-		case "image/png":
-			imageExtension = ".png"
-		default:
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported image type"))
+		// decode, strip the metadata and build the variants
+		profilePicture, err := utils.ProcessImage(payload.ProfilePicture)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
 			return
 		}
 
 		// save the image
-		imageURL, err := utils.SaveProfilePicture(user.ID, payload.ProfilePicture, imageExtension)
+		imageURL, err := utils.SaveProfilePicture(h.blobStore, user.ID, user.ProfilePictureURL, profilePicture)
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("user %s created but error saving profile picture: %v", payload.Email, err))
 		}
@@ -310,32 +295,23 @@ func (h *Handler) handleRegister(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
-	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	imageBytes, err := utils.GetImage(user.ProfilePictureURL)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("error reading the picture: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error reading the picture: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
 	response := types.ReturnUserPayload{
-		ID:             user.ID,
-		Name:           user.Name,
-		Email:          user.Email,
-		PhoneNumber:    user.PhoneNumber,
-		Provider:       user.Provider,
-		ProfilePicture: imageBytes,
-		FCMToken:       user.FCMToken,
-		LastLoggedIn:   user.LastLoggedIn,
-		CreatedAt:      user.CreatedAt,
+		ID:                user.ID,
+		Name:              user.Name,
+		Email:             user.Email,
+		PhoneNumber:       user.PhoneNumber,
+		Provider:          user.Provider,
+		ProfilePictureURL: utils.ImageURL(user.ProfilePictureURL),
+		FCMToken:          user.FCMToken,
+		Role:              utils.RoleIntToString(user.Role),
+		LastLoggedIn:      user.LastLoggedIn,
+		CreatedAt:         user.CreatedAt,
 	}
 
 	utils.WriteJSON(w, http.StatusOK, response)
@@ -343,19 +319,9 @@ func (h *Handler) handleGetCurrentUser(w http.ResponseWriter, r *http.Request) {
 
 func (h *Handler) handleDelete(w http.ResponseWriter, r *http.Request) {
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil || err != nil {
-		log.Printf("payload error: %v \n", err)
-		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -403,19 +369,9 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	user, err = h.userStore.GetUserByID(user.ID)
-	if user == nil {
-		log.Printf("payload error: %v \n", err)
-		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -434,20 +390,9 @@ func (h *Handler) handleModify(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
-	accessDetails, err := jwt.ExtractAccessTokenFromClient(r)
-	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
-		return
-	}
-
-	// check user exists or not
-	_, err = h.userStore.GetUserByID(accessDetails.UserID)
+	accessDetails, err := jwt.GetAccessDetailsFromContext(r.Context())
 	if err != nil {
-		log.Printf("user id %d doesn't exists: %v", accessDetails.UserID, err)
-		logger.WriteServerLog(fmt.Sprintf("user id %d doesn't exists: %v", accessDetails.UserID, err))
-		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("user doesn't exists"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -459,7 +404,8 @@ func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userStore.DeleteToken(accessDetails.UserID)
+	// only this device is logged out
+	revoked, err := h.sessionStore.RevokeSession(accessDetails.SessionID, accessDetails.UserID)
 	if err != nil {
 		log.Println(err)
 		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
@@ -467,6 +413,10 @@ func (h *Handler) handleLogout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if revoked {
+		h.recordSessionEvent(r, constants.SESSION_EVENT_REVOKED, accessDetails.UserID, accessDetails.SessionID, "")
+	}
+
 	utils.WriteJSON(w, http.StatusOK, "successfully logged out")
 }
 
@@ -527,7 +477,12 @@ func (h *Handler) handleSendVerification(w http.ResponseWriter, r *http.Request)
 
 	subject := fmt.Sprintf("Your Verification Code for %s", accountStatus)
 	body := fmt.Sprintf("<p>Your verification code for %s is:</p><br><h2>%s</h2>", strings.ToLower(accountStatus), code)
-	err = utils.SendEmail(payload.Email, subject, body, "", "")
+	err = h.notifier.Notify(types.NotificationMessage{
+		Channel: constants.NOTIFICATION_CHANNEL_EMAIL,
+		To:      payload.Email,
+		Title:   subject,
+		Body:    body,
+	})
 	if err != nil {
 		log.Printf("failed to send email: %v", err)
 		logger.WriteServerLog(fmt.Sprintf("failed to send email: %v", err))
@@ -613,11 +568,9 @@ func (h *Handler) handleUpdatePassword(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
@@ -656,13 +609,23 @@ func (h *Handler) handleUpdatePassword(w http.ResponseWriter, r *http.Request) {
 func (h *Handler) handleUpdateProfilePicture(w http.ResponseWriter, r *http.Request) {
 	var payload types.UpdateProfilePicturePayload
 
-	if err := utils.ParseJSON(r, &payload); err != nil {
+	// multipart/form-data or JSON
+	uploadedImage, err := utils.ParseUpload(w, r, &payload, "profilePicture", constants.PROFILE_IMG_MAX_BYTES)
+	if errors.Is(err, types.ErrUploadTooLarge) {
+		utils.WriteError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("image size exceeds the limit of 5MB"))
+		return
+	}
+	if err != nil {
 		log.Printf("payload error: %v \n", err)
 		logger.WriteServerLog(fmt.Sprintf("payload error: %v", err))
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("payload error"))
 		return
 	}
 
+	if uploadedImage != nil {
+		payload.ProfilePicture = uploadedImage
+	}
+
 	// validate the payload
 	if err := utils.Validate.Struct(payload); err != nil {
 		errors := err.(validator.ValidationErrors)
@@ -673,34 +636,25 @@ func (h *Handler) handleUpdateProfilePicture(w http.ResponseWriter, r *http.Requ
 	}
 
 	// validate token
-	user, err := h.userStore.ValidateUserAccessToken(w, r)
+	user, err := jwt.GetUserFromContext(r.Context())
 	if err != nil {
-		log.Printf("token invalid: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("token invalid: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("token invalid"))
+		utils.WriteError(w, http.StatusUnauthorized, err)
 		return
 	}
 
-	var imageExtension string
-
 	if len(payload.ProfilePicture) > constants.PROFILE_IMG_MAX_BYTES {
 		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("image size exceeds the limit of 5MB"))
 		return
 	} else if len(payload.ProfilePicture) > 0 {
-		// check image type
-		mimeType := http.DetectContentType(payload.ProfilePicture)
-		switch mimeType {
-		case "image/jpeg":
-			imageExtension = ".jpg"
-		case "image/png":
-			imageExtension = ".png"
-		default:
-			utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("unsupported image type"))
+		// decode, strip the metadata and build the variants
+		profilePicture, err := utils.ProcessImage(payload.ProfilePicture)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, err)
 			return
 		}
 
 		// save the image
-		imageURL, err := utils.SaveProfilePicture(user.ID, payload.ProfilePicture, imageExtension)
+		imageURL, err := utils.SaveProfilePicture(h.blobStore, user.ID, user.ProfilePictureURL, profilePicture)
 		if err != nil {
 			log.Printf("failed to save image: %v", err)
 			logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to save image: %v", err))
@@ -791,53 +745,15 @@ func (h *Handler) handleLoginGoogle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if user.FCMToken != payload.FCMToken {
-		err = h.userStore.UpdateFCMToken(user.ID, payload.FCMToken)
-		if err != nil {
-			logger.WriteServerLog(fmt.Sprintf("error update FCM token for user %s: %v", user.Email, err))
-		}
-	}
-
-	isAccessTokenExist, err := h.userStore.IsAccessTokenExist(user.ID)
-	if err != nil {
-		log.Println(err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("%v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	if isAccessTokenExist {
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("logged in from other device"))
+	if user.SuspendedAt.Valid {
+		utils.WriteError(w, http.StatusForbidden, types.ErrAccountSuspended)
 		return
 	}
 
-	accessTokenDetails, err := jwt.CreateAccessToken(user.ID)
+	accessTokenDetails, refreshTokenDetails, err := h.createSession(r, user, deviceName(r, payload.DeviceName), payload.FCMToken)
 	if err != nil {
-		log.Printf("failed to generate access token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate access token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	refreshTokenDetails, err := jwt.CreateRefreshToken(user.ID)
-	if err != nil {
-		log.Printf("failed to generate refresh token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate refresh token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	log.Print("FCM token: ", payload.FCMToken)
-
-	err = h.userStore.UpdateFCMToken(user.ID, payload.FCMToken)
-	if err != nil {
-		logger.WriteServerLog(fmt.Sprintf("%s failed to update FCM Token: %v", user.Email, err))
-	}
-
-	err = h.userStore.SaveToken(user.ID, accessTokenDetails, refreshTokenDetails)
-	if err != nil {
-		log.Printf("error saving token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error saving token: %v", err))
+		log.Printf("error creating session: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error creating session: %v", err))
 		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
@@ -933,12 +849,14 @@ func (h *Handler) handleRegisterGoogle(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if payload.ProfilePictureURL != "" {
-		imageData, imageExtension, err := utils.DownloadImage(payload.ProfilePictureURL)
+		imageData, _, err := utils.DownloadImage(payload.ProfilePictureURL)
 		if err != nil {
 			logger.WriteServerLog(fmt.Sprintf("user %s created but error download profile picture: %v", email, err))
+		} else if profilePicture, err := utils.ProcessImage(imageData); err != nil {
+			logger.WriteServerLog(fmt.Sprintf("user %s created but error processing profile picture: %v", email, err))
 		} else {
 			// save the image
-			imageURL, err := utils.SaveProfilePicture(user.ID, imageData, imageExtension)
+			imageURL, err := utils.SaveProfilePicture(h.blobStore, user.ID, user.ProfilePictureURL, profilePicture)
 			if err != nil {
 				logger.WriteServerLog(fmt.Sprintf("user %s created but error saving profile picture: %v", email, err))
 			}
@@ -952,26 +870,10 @@ func (h *Handler) handleRegisterGoogle(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	accessTokenDetails, err := jwt.CreateAccessToken(user.ID)
-	if err != nil {
-		log.Printf("failed to generate access token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate access token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	refreshTokenDetails, err := jwt.CreateRefreshToken(user.ID)
+	accessTokenDetails, refreshTokenDetails, err := h.createSession(r, user, deviceName(r, payload.DeviceName), payload.FCMToken)
 	if err != nil {
-		log.Printf("failed to generate refresh token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate refresh token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	err = h.userStore.SaveToken(user.ID, accessTokenDetails, refreshTokenDetails)
-	if err != nil {
-		log.Printf("error saving token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error saving token: %v", err))
+		log.Printf("error creating session: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error creating session: %v", err))
 		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
 		return
 	}
@@ -1003,28 +905,11 @@ func (h *Handler) handleRefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userStore.ValidateUserRefreshToken(payload.RefreshToken)
-	if err != nil {
-		utils.WriteError(w, http.StatusUnauthorized, err)
-		return
-	}
-
-	accessTokenDetails, err := jwt.CreateAccessToken(user.ID)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("failed to generate access token: %v", err))
-		return
-	}
-
-	err = h.userStore.UpdateAccessToken(user.ID, accessTokenDetails)
-	if err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("error update access token: %v", err))
+	_, _, tokens, ok := h.rotateRefreshToken(w, r, payload.RefreshToken)
+	if !ok {
 		return
 	}
 
-	tokens := map[string]string{
-		"access_token": accessTokenDetails.Token,
-	}
-
 	utils.WriteJSON(w, http.StatusOK, tokens)
 }
 
@@ -1048,51 +933,15 @@ func (h *Handler) handleAutoLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userStore.ValidateUserRefreshToken(payload.RefreshToken)
-	if err != nil {
-		log.Printf("error validate user refresh token: %v", err)
-		logger.WriteServerLog(fmt.Sprintf("error validate user refresh token: %v", err))
-		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
-		return
-	}
-
-	err = h.userStore.DeleteToken(user.ID)
-	if err != nil {
-		log.Printf("error delete token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error delete token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	accessTokenDetails, err := jwt.CreateAccessToken(user.ID)
-	if err != nil {
-		log.Printf("failed to generate access token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate access token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	refreshTokenDetails, err := jwt.CreateRefreshToken(user.ID)
-	if err != nil {
-		log.Printf("failed to generate refresh token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate refresh token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+	// the device keeps its session, only the tokens are renewed
+	user, sessionId, tokens, ok := h.rotateRefreshToken(w, r, payload.RefreshToken)
+	if !ok {
 		return
 	}
 
-	err = h.userStore.SaveToken(user.ID, accessTokenDetails, refreshTokenDetails)
+	err := h.sessionStore.UpdateSessionFCMToken(sessionId, payload.FCMToken)
 	if err != nil {
-		log.Printf("error saving token: %v", err)
-		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error saving token: %v", err))
-		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
-		return
-	}
-
-	if user.FCMToken != payload.FCMToken {
-		err = h.userStore.UpdateFCMToken(user.ID, payload.FCMToken)
-		if err != nil {
-			logger.WriteServerLog(fmt.Sprintf("error update FCM token for user %s: %v", user.Email, err))
-		}
+		logger.WriteServerLog(fmt.Sprintf("error update FCM token of session %d: %v", sessionId, err))
 	}
 
 	err = h.userStore.UpdateLastLoggedIn(user.ID)
@@ -1103,11 +952,6 @@ func (h *Handler) handleAutoLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tokens := map[string]string{
-		"access_token":  accessTokenDetails.Token,
-		"refresh_token": refreshTokenDetails.Token,
-	}
-
 	utils.WriteJSON(w, http.StatusOK, tokens)
 }
 
@@ -1154,3 +998,303 @@ func (h *Handler) handleVerifyVerification(w http.ResponseWriter, r *http.Reques
 
 	utils.WriteJSON(w, http.StatusOK, "Verification successful!")
 }
+
+func (h *Handler) handleGetSessions(w http.ResponseWriter, r *http.Request) {
+	accessDetails, err := jwt.GetAccessDetailsFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	sessions, err := h.sessionStore.GetActiveSessionsByUserID(accessDetails.UserID)
+	if err != nil {
+		log.Printf("error get sessions: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get sessions: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnSessions := make([]types.SessionReturnPayload, 0)
+
+	for _, session := range sessions {
+		returnSessions = append(returnSessions, types.SessionReturnPayload{
+			ID:         session.ID,
+			DeviceName: session.DeviceName,
+			Current:    (session.ID == accessDetails.SessionID),
+			LastUsedAt: session.LastUsedAt,
+			CreatedAt:  session.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, returnSessions)
+}
+
+func (h *Handler) handleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	accessDetails, err := jwt.GetAccessDetailsFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid session id"))
+		return
+	}
+
+	revoked, err := h.sessionStore.RevokeSession(id, accessDetails.UserID)
+	if err != nil {
+		log.Printf("error revoke session: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error revoke session: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	if !revoked {
+		utils.WriteError(w, http.StatusNotFound, fmt.Errorf("session %d not found", id))
+		return
+	}
+
+	h.recordSessionEvent(r, constants.SESSION_EVENT_REVOKED, accessDetails.UserID, id, "")
+
+	utils.WriteJSON(w, http.StatusOK, "session revoked")
+}
+
+func (h *Handler) handleRevokeOtherSessions(w http.ResponseWriter, r *http.Request) {
+	accessDetails, err := jwt.GetAccessDetailsFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	revokedIds, err := h.sessionStore.RevokeOtherSessions(accessDetails.UserID, accessDetails.SessionID)
+	if err != nil {
+		log.Printf("error revoke other sessions: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error revoke other sessions: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	for _, id := range revokedIds {
+		h.recordSessionEvent(r, constants.SESSION_EVENT_REVOKED, accessDetails.UserID, id, "")
+	}
+
+	utils.WriteJSON(w, http.StatusOK, fmt.Sprintf("%d other sessions revoked", len(revokedIds)))
+}
+
+func (h *Handler) handleGetSessionEvents(w http.ResponseWriter, r *http.Request) {
+	accessDetails, err := jwt.GetAccessDetailsFromContext(r.Context())
+	if err != nil {
+		utils.WriteError(w, http.StatusUnauthorized, err)
+		return
+	}
+
+	page, pageSize, err := utils.ParsePagination(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	total, err := h.sessionStore.GetSessionEventCountByUserID(accessDetails.UserID)
+	if err != nil {
+		log.Printf("error count session events: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error count session events: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	events, err := h.sessionStore.GetSessionEventsByUserID(accessDetails.UserID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		log.Printf("error get session events: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error get session events: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return
+	}
+
+	returnEvents := make([]types.SessionEventReturnPayload, 0)
+
+	for _, event := range events {
+		returnEvents = append(returnEvents, types.SessionEventReturnPayload{
+			ID:         event.ID,
+			SessionID:  event.SessionID,
+			DeviceName: event.DeviceName,
+			EventType:  utils.SessionEventIntToString(event.EventType),
+			IPAddress:  event.IPAddress,
+			UserAgent:  event.UserAgent,
+			CreatedAt:  event.CreatedAt,
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, types.SessionEventsReturnPayload{
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+		Events:   returnEvents,
+	})
+}
+
+// starts a new device session and returns its access and refresh tokens
+func (h *Handler) createSession(r *http.Request, user *types.User, deviceName string, fcmToken string) (*types.TokenDetails, *types.TokenDetails, error) {
+	sessionId, err := h.sessionStore.CreateSession(user.ID, deviceName, fcmToken)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error create session: %v", err)
+	}
+
+	accessTokenDetails, err := jwt.CreateAccessToken(user.ID, sessionId, user.Role)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate access token: %v", err)
+	}
+
+	refreshTokenDetails, err := jwt.CreateRefreshToken(user.ID, sessionId)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	err = h.userStore.SaveToken(user.ID, sessionId, accessTokenDetails, refreshTokenDetails)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error saving token: %v", err)
+	}
+
+	h.recordSessionEvent(r, constants.SESSION_EVENT_LOGIN, user.ID, sessionId, "")
+
+	// pushes go to the session tokens, the user's one is only the latest device
+	if fcmToken != "" {
+		err = h.userStore.UpdateFCMToken(user.ID, fcmToken)
+		if err != nil {
+			logger.WriteServerLog(fmt.Sprintf("error update FCM token for user %d: %v", user.ID, err))
+		}
+	}
+
+	return accessTokenDetails, refreshTokenDetails, nil
+}
+
+// swaps the refresh token for a new token pair of the same session, writing the
+// error response itself. a refresh token is single use: presenting one that was
+// already rotated means it leaked, so the whole session is revoked
+func (h *Handler) rotateRefreshToken(w http.ResponseWriter, r *http.Request, refreshToken string) (*types.User, int, map[string]string, bool) {
+	user, refreshDetails, err := h.userStore.ValidateUserRefreshToken(refreshToken)
+	if err != nil {
+		log.Printf("error validate user refresh token: %v", err)
+		logger.WriteServerLog(fmt.Sprintf("error validate user refresh token: %v", err))
+
+		if h.detectRefreshTokenReuse(r, refreshToken) {
+			utils.WriteError(w, http.StatusUnauthorized, types.ErrRefreshTokenReused)
+			return nil, 0, nil, false
+		}
+
+		utils.WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return nil, 0, nil, false
+	}
+
+	accessTokenDetails, err := jwt.CreateAccessToken(user.ID, refreshDetails.SessionID, user.Role)
+	if err != nil {
+		log.Printf("failed to generate access token: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate access token: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return nil, 0, nil, false
+	}
+
+	refreshTokenDetails, err := jwt.CreateRefreshToken(user.ID, refreshDetails.SessionID)
+	if err != nil {
+		log.Printf("failed to generate refresh token: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("failed to generate refresh token: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return nil, 0, nil, false
+	}
+
+	rotated, err := h.sessionStore.RotateSessionTokens(types.SessionEvent{
+		SessionID: refreshDetails.SessionID,
+		UserID:    user.ID,
+		TokenUUID: refreshDetails.UUID,
+		IPAddress: utils.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	}, accessTokenDetails, refreshTokenDetails)
+	if err != nil {
+		log.Printf("error rotate refresh token: %v", err)
+		logFile, _ := logger.WriteServerLog(fmt.Sprintf("error rotate refresh token: %v", err))
+		utils.WriteError(w, http.StatusInternalServerError, fmt.Errorf("internal server error\n(%s)", logFile))
+		return nil, 0, nil, false
+	}
+
+	// another request rotated the same token in the meantime
+	if !rotated {
+		h.revokeReusedSession(r, refreshDetails)
+		utils.WriteError(w, http.StatusUnauthorized, types.ErrRefreshTokenReused)
+		return nil, 0, nil, false
+	}
+
+	tokens := map[string]string{
+		"access_token":  accessTokenDetails.Token,
+		"refresh_token": refreshTokenDetails.Token,
+	}
+
+	return user, refreshDetails.SessionID, tokens, true
+}
+
+// revokes the session if the refresh token was already rotated, returns true if it was
+func (h *Handler) detectRefreshTokenReuse(r *http.Request, refreshToken string) bool {
+	refreshDetails, err := jwt.ExtractRefreshTokenFromClient(refreshToken)
+	if err != nil || refreshDetails == nil {
+		return false
+	}
+
+	reused, err := h.sessionStore.IsRotatedRefreshToken(refreshDetails.SessionID, refreshDetails.UUID)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error check refresh token reuse of session %d: %v", refreshDetails.SessionID, err))
+		return false
+	}
+
+	if !reused {
+		return false
+	}
+
+	h.revokeReusedSession(r, refreshDetails)
+
+	return true
+}
+
+func (h *Handler) revokeReusedSession(r *http.Request, refreshDetails *types.AccessDetails) {
+	log.Printf("refresh token reuse detected in session %d of user %d", refreshDetails.SessionID, refreshDetails.UserID)
+	logger.WriteServerLog(fmt.Sprintf("refresh token reuse detected in session %d of user %d", refreshDetails.SessionID, refreshDetails.UserID))
+
+	_, err := h.sessionStore.RevokeSession(refreshDetails.SessionID, refreshDetails.UserID)
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error revoke session %d: %v", refreshDetails.SessionID, err))
+	}
+
+	h.recordSessionEvent(r, constants.SESSION_EVENT_REUSE_DETECTED, refreshDetails.UserID, refreshDetails.SessionID, refreshDetails.UUID)
+}
+
+// session events are an audit trail, failing to record one does not fail the request
+func (h *Handler) recordSessionEvent(r *http.Request, eventType int, userId int, sessionId int, tokenUuid string) {
+	err := h.sessionStore.CreateSessionEvent(types.SessionEvent{
+		SessionID: sessionId,
+		UserID:    userId,
+		EventType: eventType,
+		TokenUUID: tokenUuid,
+		IPAddress: utils.ClientIP(r),
+		UserAgent: r.UserAgent(),
+	})
+	if err != nil {
+		logger.WriteServerLog(fmt.Sprintf("error record %s event of session %d: %v",
+			utils.SessionEventIntToString(eventType), sessionId, err))
+	}
+}
+
+// the name the client gave the device, or its user agent
+func deviceName(r *http.Request, name string) string {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = r.UserAgent()
+	}
+	if name == "" {
+		name = "unknown device"
+	}
+
+	if len(name) > 255 {
+		name = name[:255]
+	}
+
+	return name
+}
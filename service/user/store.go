@@ -3,10 +3,10 @@ package user
 import (
 	"database/sql"
 	"fmt"
-	"net/http"
 	"time"
 
 	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/db"
 	"github.com/nicolaics/jim-carrier-server/service/auth/jwt"
 	"github.com/nicolaics/jim-carrier-server/types"
 )
@@ -21,7 +21,7 @@ func NewStore(db *sql.DB) *Store {
 
 func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 	query := `SELECT id, name, email, phone_number, provider, 
-					profile_picture_url, fcm_token, 
+					profile_picture_url, fcm_token, role, suspended_at, 
 					last_logged_in, created_at 
 				FROM user WHERE email = ?`
 	rows, err := s.db.Query(query, email)
@@ -49,7 +49,7 @@ func (s *Store) GetUserByEmail(email string) (*types.User, error) {
 
 func (s *Store) GetUserByName(name string) (*types.User, error) {
 	query := `SELECT id, name, email, phone_number, provider, 
-				profile_picture_url, fcm_token, 
+				profile_picture_url, fcm_token, role, suspended_at, 
 				last_logged_in, created_at 
 				FROM user WHERE name = ?`
 	rows, err := s.db.Query(query, name)
@@ -109,7 +109,7 @@ func (s *Store) GetUserBySearchName(name string) ([]types.User, error) {
 
 	if count == 0 {
 		query = `SELECT id, name, email, phone_number, provider, 
-					profile_picture_url, fcm_token, 
+					profile_picture_url, fcm_token, role, suspended_at, 
 					last_logged_in, created_at 
 					FROM user WHERE name LIKE ?`
 		searchVal := "%"
@@ -139,7 +139,7 @@ func (s *Store) GetUserBySearchName(name string) ([]types.User, error) {
 		return users, nil
 	}
 	query = `SELECT id, name, email, phone_number, provider, 
-					profile_picture_url, fcm_token, 
+					profile_picture_url, fcm_token, role, suspended_at, 
 					last_logged_in, created_at 
 					FROM user WHERE name = ?`
 	rows, err := s.db.Query(query, name)
@@ -179,7 +179,7 @@ func (s *Store) GetUserBySearchPhoneNumber(phoneNumber string) ([]types.User, er
 
 	if count == 0 {
 		query = `SELECT id, name, email, phone_number, provider, 
-					profile_picture_url, fcm_token, 
+					profile_picture_url, fcm_token, role, suspended_at, 
 					last_logged_in, created_at 
 					FROM user WHERE phone_number LIKE ?`
 		searchVal := "%"
@@ -210,7 +210,7 @@ func (s *Store) GetUserBySearchPhoneNumber(phoneNumber string) ([]types.User, er
 	}
 
 	query = `SELECT id, name, email, phone_number, provider, 
-					profile_picture_url, fcm_token, 
+					profile_picture_url, fcm_token, role, suspended_at, 
 					last_logged_in, created_at 
 					FROM user WHERE phone_number = ?`
 	rows, err := s.db.Query(query, phoneNumber)
@@ -234,7 +234,7 @@ func (s *Store) GetUserBySearchPhoneNumber(phoneNumber string) ([]types.User, er
 
 func (s *Store) GetUserByID(id int) (*types.User, error) {
 	query := `SELECT id, name, email, phone_number, provider, 
-				profile_picture_url, fcm_token, 
+				profile_picture_url, fcm_token, role, suspended_at, 
 				last_logged_in, created_at 
 				FROM user WHERE id = ?`
 	rows, err := s.db.Query(query, id)
@@ -263,14 +263,14 @@ func (s *Store) GetUserByID(id int) (*types.User, error) {
 func (s *Store) CreateUser(user types.User) error {
 	query := `INSERT INTO user (name, email, password, 
 								phone_number, provider, 
-								fcm_token, profile_picture_url) 
-				VALUES (?, ?, ?, ?, ?, ?, ?)`
+								fcm_token, profile_picture_url, role) 
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
-	defaultProfilePicture := constants.PROFILE_IMG_DIR_PATH + "default.png"
+	defaultProfilePicture := constants.DEFAULT_PROFILE_IMG_KEY
 
 	_, err := s.db.Exec(query, user.Name, user.Email, user.Password,
 		user.PhoneNumber, user.Provider, user.FCMToken,
-		defaultProfilePicture)
+		defaultProfilePicture, user.Role)
 	if err != nil {
 		return err
 	}
@@ -289,6 +289,31 @@ func (s *Store) DeleteUser(user *types.User) error {
 		return err
 	}
 
+	_, err = s.db.Exec("DELETE FROM saved_search WHERE user_id = ?", user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM payout_method WHERE user_id = ?", user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM request_offer WHERE carrier_id = ? OR request_id IN (SELECT id FROM delivery_request WHERE giver_id = ?)", user.ID, user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM delivery_request WHERE giver_id = ?", user.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec("DELETE FROM listing_stop WHERE listing_id IN (SELECT id FROM listing WHERE carrier_id = ?)", user.ID)
+	if err != nil {
+		return err
+	}
+
 	_, err = s.db.Exec("DELETE FROM listing WHERE carrier_id = ?", user.ID)
 	if err != nil {
 		return err
@@ -343,58 +368,33 @@ func (s *Store) UpdateProfilePicture(id int, profPicUrl string) error {
 	return nil
 }
 
-func (s *Store) SaveToken(userId int, accessTokenDetails *types.TokenDetails, refreshTokenDetails *types.TokenDetails) error {
+// replaces the tokens of the session with the new pair
+func (s *Store) SaveToken(userId int, sessionId int, accessTokenDetails *types.TokenDetails, refreshTokenDetails *types.TokenDetails) error {
 	accessTokenExp := time.Unix(accessTokenDetails.TokenExp, 0)   //converting Unix to UTC(to Time object)
 	refreshTokenExp := time.Unix(refreshTokenDetails.TokenExp, 0) //converting Unix to UTC(to Time object)
 
-	query := `SELECT COUNT(*) FROM verify_token WHERE user_id = ? AND token_type = ?`
-	row := s.db.QueryRow(query, userId, constants.ACCESS_TOKEN)
-	if row.Err() != nil {
-		return row.Err()
-	}
-
-	var count int
-	err := row.Scan(&count)
-	if err != nil {
-		return err
-	}
-
-	if count == 0 {
-		query = "INSERT INTO verify_token(user_id, uuid, token_type, expired_at) VALUES (?, ?, ?, ?)"
-		_, err = s.db.Exec(query, userId, accessTokenDetails.UUID, constants.ACCESS_TOKEN, accessTokenExp)
-	} else {
-		query = `UPDATE verify_token SET uuid = ?, expired_at = ? WHERE user_id = ? AND token_type = ?`
-		_, err = s.db.Exec(query, accessTokenDetails.UUID, accessTokenExp, userId, constants.ACCESS_TOKEN)
-	}
-	if err != nil {
-		return err
-	}
+	return db.RunInTx(s.db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM verify_token WHERE session_id = ?`, sessionId)
+		if err != nil {
+			return err
+		}
 
-	query = `SELECT COUNT(*) FROM verify_token WHERE user_id = ? AND token_type = ?`
-	row = s.db.QueryRow(query, userId, constants.REFRESH_TOKEN)
-	if row.Err() != nil {
-		return row.Err()
-	}
-	
-	err = row.Scan(&count)
-	if err != nil {
-		return err
-	}
+		query := "INSERT INTO verify_token(user_id, session_id, uuid, token_type, expired_at) VALUES (?, ?, ?, ?, ?)"
+		_, err = tx.Exec(query, userId, sessionId, accessTokenDetails.UUID, constants.ACCESS_TOKEN, accessTokenExp)
+		if err != nil {
+			return err
+		}
 
-	if count == 0 {
-		query = "INSERT INTO verify_token(user_id, uuid, token_type, expired_at) VALUES (?, ?, ?, ?)"
-		_, err = s.db.Exec(query, userId, refreshTokenDetails.UUID, constants.REFRESH_TOKEN, refreshTokenExp)
-	} else {
-		query = `UPDATE verify_token SET uuid = ?, expired_at = ? WHERE user_id = ? AND token_type = ?`
-		_, err = s.db.Exec(query, refreshTokenDetails.UUID, refreshTokenExp, userId, constants.REFRESH_TOKEN)
-	}
-	if err != nil {
-		return err
-	}
+		_, err = tx.Exec(query, userId, sessionId, refreshTokenDetails.UUID, constants.REFRESH_TOKEN, refreshTokenExp)
+		if err != nil {
+			return err
+		}
 
-	return nil
+		return nil
+	})
 }
 
+// deletes the tokens of every session of the user
 func (s *Store) DeleteToken(userId int) error {
 	query := "DELETE FROM verify_token WHERE user_id = ?"
 	_, err := s.db.Exec(query, userId)
@@ -405,125 +405,58 @@ func (s *Store) DeleteToken(userId int) error {
 	return nil
 }
 
-func (s *Store) ValidateUserAccessToken(w http.ResponseWriter, r *http.Request) (*types.User, error) {
+// returns the owner of the access token if the token is still the current one of its session
+func (s *Store) GetUserByAccessDetails(accessDetails *types.AccessDetails) (*types.User, error) {
+	return s.validateToken(accessDetails, constants.ACCESS_TOKEN)
+}
+
+// also returns the token details, so the caller knows which session is refreshed
+func (s *Store) ValidateUserRefreshToken(refreshToken string) (*types.User, *types.AccessDetails, error) {
 	query := "DELETE FROM verify_token WHERE expired_at < ?"
 	_, err := s.db.Exec(query, time.Now().UTC().Format("2006-01-02 15:04:05"))
 	if err != nil {
-		return nil, fmt.Errorf("error deleting expired token: %v", err)
-	}
-
-	accessDetails, err := jwt.ExtractAccessTokenFromClient(r)
-	if err != nil {
-		return nil, err
-	}
-
-	query = `SELECT COUNT(*) FROM verify_token WHERE user_id = ? AND expired_at >= ? AND token_type = ?`
-	row := s.db.QueryRow(query, accessDetails.UserID, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.ACCESS_TOKEN)
-	if row.Err() != nil {
-		return nil, row.Err()
-	}
-
-	var count int
-	err = row.Scan(&count)
-	if err != nil {
-		return nil, err
-	}
-
-	if count > 1 {
-		return nil, fmt.Errorf("logged in from other device")
-	} else if count == 0 {
-		return nil, fmt.Errorf("access token expired")
-	}
-
-	query = `SELECT user_id FROM verify_token WHERE uuid = ? AND user_id = ? AND expired_at >= ? AND token_type = ?`
-	row = s.db.QueryRow(query, accessDetails.UUID, accessDetails.UserID, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.ACCESS_TOKEN)
-	if row.Err() != nil {
-		if row.Err() == sql.ErrNoRows {
-			return nil, nil
-		}
-
-		return nil, row.Err()
+		return nil, nil, fmt.Errorf("error deleting expired token: %v", err)
 	}
 
-	var userId int
-	err = row.Scan(&userId)
+	refreshDetails, err := jwt.ExtractRefreshTokenFromClient(refreshToken)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-		return nil, err
+		return nil, nil, err
 	}
 
-	// check if user exist
-	user, err := s.GetUserByID(userId)
+	user, err := s.validateToken(refreshDetails, constants.REFRESH_TOKEN)
 	if err != nil {
-		delErr := s.DeleteToken(accessDetails.UserID)
-		if delErr != nil {
-			return nil, fmt.Errorf("delete error: %v", delErr)
-		}
-
-		return nil, fmt.Errorf("account not found")
+		return nil, nil, err
 	}
 
-	return user, nil
+	return user, refreshDetails, nil
 }
 
-func (s *Store) ValidateUserRefreshToken(refreshToken string) (*types.User, error) {
-	query := "DELETE FROM verify_token WHERE expired_at < ?"
-	_, err := s.db.Exec(query, time.Now().UTC().Format("2006-01-02 15:04:05"))
-	if err != nil {
-		return nil, fmt.Errorf("error deleting expired token: %v", err)
-	}
-
-	accessDetails, err := jwt.ExtractRefreshTokenFromClient(refreshToken)
-	if err != nil {
-		return nil, err
-	}
-
-	query = `SELECT COUNT(*) FROM verify_token WHERE user_id = ? AND expired_at >= ? AND token_type = ?`
-	row := s.db.QueryRow(query, accessDetails.UserID, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.REFRESH_TOKEN)
-	if row.Err() != nil {
-		return nil, row.Err()
-	}
+// checks the token is still the current one of its session
+func (s *Store) validateToken(accessDetails *types.AccessDetails, tokenType int) (*types.User, error) {
+	query := `SELECT COUNT(*) FROM verify_token AS vt 
+				JOIN user_session AS us ON us.id = vt.session_id 
+				WHERE vt.uuid = ? AND vt.user_id = ? AND vt.session_id = ? 
+				AND vt.expired_at >= ? AND vt.token_type = ? 
+				AND us.revoked_at IS NULL`
+	row := s.db.QueryRow(query, accessDetails.UUID, accessDetails.UserID, accessDetails.SessionID,
+		time.Now().UTC().Format("2006-01-02 15:04:05"), tokenType)
 
 	var count int
-	err = row.Scan(&count)
+	err := row.Scan(&count)
 	if err != nil {
 		return nil, err
 	}
 
 	if count == 0 {
-		err = s.DeleteToken(accessDetails.UserID)
-		if err != nil {
-			return nil, fmt.Errorf("delete error: %v", err)
+		if tokenType == constants.REFRESH_TOKEN {
+			return nil, fmt.Errorf("refresh token expired")
 		}
 
-		return nil, fmt.Errorf("refresh token expired")
-	} else if count > 1 {
-		return nil, fmt.Errorf("contact admin")
-	}
-
-	query = `SELECT user_id FROM verify_token WHERE uuid = ? AND user_id = ? AND expired_at >= ? AND token_type = ?`
-	row = s.db.QueryRow(query, accessDetails.UUID, accessDetails.UserID, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.REFRESH_TOKEN)
-	if row.Err() != nil {
-		if row.Err() == sql.ErrNoRows {
-			return nil, nil
-		}
-
-		return nil, row.Err()
-	}
-
-	var userId int
-	err = row.Scan(&userId)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
-
-		return nil, err
+		return nil, fmt.Errorf("access token expired")
 	}
 
-	user, err := s.GetUserByID(userId)
+	// check if user exist
+	user, err := s.GetUserByID(accessDetails.UserID)
 	if err != nil {
 		delErr := s.DeleteToken(accessDetails.UserID)
 		if delErr != nil {
@@ -533,35 +466,11 @@ func (s *Store) ValidateUserRefreshToken(refreshToken string) (*types.User, erro
 		return nil, fmt.Errorf("account not found")
 	}
 
-	return user, nil
-}
-
-func (s *Store) UpdateAccessToken(userId int, accessTokenDetails *types.TokenDetails) error {
-	accessTokenExp := time.Unix(accessTokenDetails.TokenExp, 0) //converting Unix to UTC(to Time object)
-
-	query := `INSERT INTO verify_token(user_id, uuid, token_type, expired_at) VALUES (?, ?, ?, ?)`
-	_, err := s.db.Exec(query, userId, accessTokenDetails.UUID, constants.ACCESS_TOKEN, accessTokenExp)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func (s *Store) IsAccessTokenExist(userId int) (bool, error) {
-	query := `SELECT COUNT(*) FROM verify_token WHERE user_id = ? AND expired_at >= ? AND token_type = ?`
-	row := s.db.QueryRow(query, userId, time.Now().UTC().Format("2006-01-02 15:04:05"), constants.ACCESS_TOKEN)
-	if row.Err() != nil {
-		return true, row.Err()
+	if user.SuspendedAt.Valid {
+		return nil, types.ErrAccountSuspended
 	}
 
-	var count int
-	err := row.Scan(&count)
-	if err != nil {
-		return true, err
-	}
-
-	return (count > 0), nil
+	return user, nil
 }
 
 func (s *Store) DelayCodeWithinTime(email string, minutes int) (bool, error) {
@@ -662,6 +571,100 @@ func (s *Store) UpdateFCMToken(id int, fcmToken string) error {
 	return nil
 }
 
+func (s *Store) UpdateUserRole(id int, role int) error {
+	query := `UPDATE user SET role = ? WHERE id = ?`
+	_, err := s.db.Exec(query, role, id)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *Store) GetUserCountByRole(role int) (int, error) {
+	query := `SELECT COUNT(*) FROM user WHERE role = ?`
+
+	var count int
+	err := s.db.QueryRow(query, role).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// returns all users, the newest first
+func (s *Store) GetUsers(limit int, offset int) ([]types.User, error) {
+	query := `SELECT id, name, email, phone_number, provider, 
+				profile_picture_url, fcm_token, role, suspended_at, 
+				last_logged_in, created_at 
+				FROM user 
+				ORDER BY created_at DESC, id DESC 
+				LIMIT ? OFFSET ?`
+	rows, err := s.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := make([]types.User, 0)
+
+	for rows.Next() {
+		user, err := scanRowIntoUser(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, *user)
+	}
+
+	return users, nil
+}
+
+func (s *Store) GetUserCount() (int, error) {
+	query := `SELECT COUNT(*) FROM user`
+
+	var count int
+	err := s.db.QueryRow(query).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// returns false if the user does not exist or is already suspended
+func (s *Store) SuspendUser(id int) (bool, error) {
+	query := `UPDATE user SET suspended_at = ? WHERE id = ? AND suspended_at IS NULL`
+	res, err := s.db.Exec(query, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
+// returns false if the user does not exist or is not suspended
+func (s *Store) ReactivateUser(id int) (bool, error) {
+	query := `UPDATE user SET suspended_at = NULL WHERE id = ? AND suspended_at IS NOT NULL`
+	res, err := s.db.Exec(query, id)
+	if err != nil {
+		return false, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return (rowsAffected > 0), nil
+}
+
 func (s *Store) IsDeleteUserAllowed(id int) (bool, error) {
 	query := `SELECT COUNT(*) FROM order_list 
 				WHERE giver_id = ? 
@@ -707,6 +710,8 @@ func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 		Provider          string
 		ProfilePictureURL string
 		FCMToken          sql.NullString
+		Role              int
+		SuspendedAt       sql.NullTime
 		LastLoggedIn      time.Time `json:"lastLoggedIn"`
 		CreatedAt         time.Time `json:"createdAt"`
 	})
@@ -719,6 +724,8 @@ func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 		&temp.Provider,
 		&temp.ProfilePictureURL,
 		&temp.FCMToken,
+		&temp.Role,
+		&temp.SuspendedAt,
 		&temp.LastLoggedIn,
 		&temp.CreatedAt,
 	)
@@ -734,12 +741,15 @@ func scanRowIntoUser(rows *sql.Rows) (*types.User, error) {
 		Provider:          temp.Provider,
 		ProfilePictureURL: temp.ProfilePictureURL,
 		FCMToken:          temp.FCMToken.String,
+		Role:              temp.Role,
+		SuspendedAt:       temp.SuspendedAt,
 		LastLoggedIn:      temp.LastLoggedIn,
 		CreatedAt:         temp.CreatedAt,
 	}
 
 	user.CreatedAt = user.CreatedAt.Local()
 	user.LastLoggedIn = user.LastLoggedIn.Local()
+	user.SuspendedAt.Time = user.SuspendedAt.Time.Local()
 
 	return user, nil
 }
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"time"
+)
+
+type AuditLogStore interface {
+	CreateAuditLog(auditLog AuditLog) error
+	GetAuditLogs(targetType int, targetId int, limit int, offset int) ([]AuditLog, error)
+	GetAuditLogCount(targetType int, targetId int) (int, error)
+}
+
+// one action an admin or support staff took, with the user, listing, order or
+// outbox message it was taken on
+type AuditLog struct {
+	ID         int       `json:"id"`
+	AdminID    int       `json:"adminId"`
+	AdminName  string    `json:"adminName"`
+	Action     int       `json:"action"`
+	TargetType int       `json:"targetType"`
+	TargetID   int       `json:"targetId"`
+	Reason     string    `json:"reason"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type AdminReasonPayload struct {
+	Reason string `json:"reason" validate:"required"`
+}
+
+type AdminOrderStatusPayload struct {
+	OrderStatus string `json:"orderStatus" validate:"required"`
+	Reason      string `json:"reason" validate:"required"`
+}
+
+type AdminUserReturnPayload struct {
+	ID           int       `json:"id"`
+	Name         string    `json:"name"`
+	Email        string    `json:"email"`
+	PhoneNumber  string    `json:"phoneNumber"`
+	Provider     string    `json:"provider"`
+	Role         string    `json:"role"`
+	Suspended    bool      `json:"suspended"`
+	SuspendedAt  time.Time `json:"suspendedAt"`
+	LastLoggedIn time.Time `json:"lastLoggedIn"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+type AdminUsersReturnPayload struct {
+	Total    int                      `json:"total"`
+	Page     int                      `json:"page"`
+	PageSize int                      `json:"pageSize"`
+	Users    []AdminUserReturnPayload `json:"users"`
+}
+
+type AuditLogReturnPayload struct {
+	ID         int       `json:"id"`
+	AdminID    int       `json:"adminId"`
+	AdminName  string    `json:"adminName"`
+	Action     string    `json:"action"`
+	TargetType string    `json:"targetType"`
+	TargetID   int       `json:"targetId"`
+	Reason     string    `json:"reason"`
+	IPAddress  string    `json:"ipAddress"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type AuditLogsReturnPayload struct {
+	Total     int                     `json:"total"`
+	Page      int                     `json:"page"`
+	PageSize  int                     `json:"pageSize"`
+	AuditLogs []AuditLogReturnPayload `json:"auditLogs"`
+}
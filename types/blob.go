@@ -0,0 +1,47 @@
+package types
+
+import (
+	"io"
+	"time"
+)
+
+// BlobStore keeps the uploaded files under a key such as "package/<name>.jpg",
+// the key is what the tables store
+type BlobStore interface {
+	Put(key string, data []byte, contentType string) error
+	Get(key string) (io.ReadCloser, error)
+	Delete(key string) error
+	Stat(key string) (*BlobInfo, error)
+	List(prefix string) ([]BlobInfo, error)
+}
+
+type BlobInfo struct {
+	Key         string    `json:"key"`
+	Size        int64     `json:"size"`
+	ContentType string    `json:"contentType"`
+	ModifiedAt  time.Time `json:"modifiedAt"`
+}
+
+type BlobReferenceStore interface {
+	// every blob key a row still points to
+	GetReferencedBlobKeys() (map[string]bool, error)
+}
+
+// BlobGarbageCollector finds the uploads nothing points to anymore and deletes
+// the ones older than the grace period, unless it is a dry run
+type BlobGarbageCollector interface {
+	Collect(dryRun bool) (*BlobGCReport, error)
+}
+
+type OrphanedBlob struct {
+	BlobInfo
+	InGracePeriod bool `json:"inGracePeriod"`
+	Deleted       bool `json:"deleted"`
+}
+
+type BlobGCReport struct {
+	DryRun       bool           `json:"dryRun"`
+	Scanned      int            `json:"scanned"`
+	DeletedCount int            `json:"deletedCount"`
+	Orphans      []OrphanedBlob `json:"orphans"`
+}
@@ -0,0 +1,16 @@
+package types
+
+// fields encrypted under one data key, with the data key wrapped by the master key KeyID
+type SealedFields struct {
+	KeyID      string
+	WrappedKey string
+	Values     []string
+}
+
+type FieldSealer interface {
+	CurrentKeyID() string
+	Seal(plaintexts ...string) (*SealedFields, error)
+	Open(sealed *SealedFields) ([]string, error)
+	// wraps the data key again with the current master key, the values are left as they are
+	Rewrap(sealed *SealedFields) (*SealedFields, error)
+}
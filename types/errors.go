@@ -0,0 +1,19 @@
+package types
+
+import "errors"
+
+var ErrNotEnoughWeight = errors.New("weight available is not enough")
+var ErrIllegalTransition = errors.New("illegal order status transition")
+var ErrNotOrderParty = errors.New("you are not the giver or carrier of this order")
+var ErrPriceMismatch = errors.New("price does not match the quote")
+var ErrRefreshTokenReused = errors.New("refresh token has already been used")
+var ErrAccountSuspended = errors.New("account is suspended")
+var ErrRequestNotOpen = errors.New("delivery request is not open anymore")
+var ErrOfferNotPending = errors.New("offer is not pending anymore")
+var ErrDeliveryRequestNotFound = errors.New("delivery request not found")
+var ErrListingNotFound = errors.New("listing not found")
+var ErrOfferNotFound = errors.New("offer not found")
+var ErrBlobNotFound = errors.New("blob not found")
+var ErrUnsupportedImage = errors.New("unsupported image type")
+var ErrUploadTooLarge = errors.New("upload is too large")
+var ErrUnknownEncryptionKey = errors.New("unknown encryption key")
@@ -1,27 +1,59 @@
 package types
 
-import "time"
+import (
+	"database/sql"
+	"time"
+)
 
 type FCMHistoryStore interface {
-	CreateFCMHistory(fcmHistory FCMHistory) error
+	CreateFCMHistory(fcmHistory FCMHistory) (int, error)
+	UpdateFCMHistoryResponse(id int, toToken string, response string) error
+
+	GetFCMHistoriesByUserID(userId int, unreadOnly bool, limit int, offset int) ([]FCMHistory, error)
+	GetFCMHistoryCountByUserID(userId int, unreadOnly bool) (int, error)
+
+	MarkFCMHistoryRead(id int, userId int) (bool, error)
+	MarkAllFCMHistoriesRead(userId int) error
 }
 
 type FCMData struct {
-	TitleLoc string `json:"title_loc_key,omitempty"`
-	BodyLoc  string `json:"body_loc_key,omitempty"`
-	Type     string `json:"type,omitempty"`
-	OrderID  string `json:"order_id,omitempty"`
+	TitleLoc  string `json:"title_loc_key,omitempty"`
+	BodyLoc   string `json:"body_loc_key,omitempty"`
+	Type      string `json:"type,omitempty"`
+	OrderID   string `json:"order_id,omitempty"`
+	ListingID string `json:"listing_id,omitempty"`
 }
 
 type FCMHistory struct {
+	ID        int          `json:"id"`
+	ToUserID  int          `json:"toUserId"`
+	ToToken   string       `json:"toToken"`
+	Title     string       `json:"title"`
+	Body      string       `json:"body"`
+	ImageURL  string       `json:"image"`
+	Link      string       `json:"link"`
+	Data      FCMData      `json:"data"`
+	Response  string       `json:"response"`
+	ReadAt    sql.NullTime `json:"readAt"`
+	CreatedAt time.Time    `json:"createdAt"`
+}
+
+type NotificationReturnPayload struct {
 	ID        int       `json:"id"`
-	ToUserID  int       `json:"toUserId"`
-	ToToken   string    `json:"toToken"`
 	Title     string    `json:"title"`
 	Body      string    `json:"body"`
 	ImageURL  string    `json:"image"`
 	Link      string    `json:"link"`
 	Data      FCMData   `json:"data"`
-	Response  string    `json:"response"`
+	Read      bool      `json:"read"`
+	ReadAt    time.Time `json:"readAt"`
 	CreatedAt time.Time `json:"createdAt"`
 }
+
+type NotificationsReturnPayload struct {
+	Total         int                         `json:"total"`
+	UnreadCount   int                         `json:"unreadCount"`
+	Page          int                         `json:"page"`
+	PageSize      int                         `json:"pageSize"`
+	Notifications []NotificationReturnPayload `json:"notifications"`
+}
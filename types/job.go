@@ -0,0 +1,24 @@
+package types
+
+import (
+	"database/sql"
+	"time"
+)
+
+type JobRunStore interface {
+	CreateJobRun(jobRun JobRun) error
+
+	AcquireJobLock(jobName string) (*sql.Conn, bool, error)
+	ReleaseJobLock(conn *sql.Conn, jobName string) error
+}
+
+type JobRun struct {
+	ID             int       `json:"id"`
+	JobName        string    `json:"jobName"`
+	Instance       string    `json:"instance"`
+	Status         int       `json:"status"`
+	ProcessedCount int       `json:"processedCount"`
+	Error          string    `json:"error"`
+	StartedAt      time.Time `json:"startedAt"`
+	FinishedAt     time.Time `json:"finishedAt"`
+}
@@ -6,11 +6,15 @@ import (
 )
 
 type ListingStore interface {
-	CreateListing(Listing) error
+	CreateListing(Listing) (int, error)
+	CreateListingTx(tx *sql.Tx, listing Listing) (int, error)
 	GetAllListings(carrierId int) ([]ListingReturnFromDB, error)
+	SearchListings(carrierId int, filter ListingSearchFilter) ([]ListingReturnFromDB, error)
+	GetListingCountBySearch(carrierId int, filter ListingSearchFilter) (int, error)
 	GetListingsByCarrierID(carrierId int) ([]ListingReturnFromDB, error)
 
-	UpdateListingExpStatus() error
+	GetListingsToExpire() ([]ListingReturnFromDB, error)
+	ExpireListing(id int) (bool, error)
 	IsListingDuplicate(carrierId int, destination string, weightAvailable float64, departureDate time.Time) (bool, error)
 
 	GetListingByPayload(carrierName string, destination string, weightAvailable float64, pricePerKg float64, departureDate time.Time) (*ListingReturnFromDB, error)
@@ -18,14 +22,53 @@ type ListingStore interface {
 
 	DeleteListing(id int) error
 
+	GetListingStopsByListingIDs(listingIds []int) (map[int][]ListingStop, error)
+
 	ModifyListing(int, Listing) error
 
 	SubtractWeightAvailable(listindId int, minusValue float64) error
 	AddWeightAvailable(listindId int, addValue float64) error
+
+	SubtractWeightAvailableTx(tx *sql.Tx, listingId int, minusValue float64) error
+	AddWeightAvailableTx(tx *sql.Tx, listingId int, addValue float64) error
+}
+
+// narrows down the available listings, zero values are not filtered on
+type ListingSearchFilter struct {
+	Destination            string
+	OriginCity             string
+	OriginCountryCode      string
+	DestinationCity        string
+	DestinationCountryCode string
+	ViaCity                string // the origin or one of the stops
+	DepartureFrom          time.Time
+	DepartureTo            time.Time
+	MinWeightAvailable     float64
+	MaxPricePerKg          float64
+	Currency               string
+	MinCarrierRating       float64
+	Sort                   string
+	Limit                  int
+	Offset                 int
+}
+
+// where the carrier departs from, passes through and arrives
+type ListingRoutePayload struct {
+	OriginCity             string               `json:"originCity" validate:"required,max=255"`
+	OriginCountryCode      string               `json:"originCountryCode" validate:"required,iso3166_1_alpha2"`
+	DestinationCity        string               `json:"destinationCity" validate:"required,max=255"`
+	DestinationCountryCode string               `json:"destinationCountryCode" validate:"required,iso3166_1_alpha2"`
+	Stops                  []ListingStopPayload `json:"stops" validate:"max=10,dive"`
+}
+
+type ListingStopPayload struct {
+	City        string `json:"city" validate:"required,max=255"`
+	CountryCode string `json:"countryCode" validate:"required,iso3166_1_alpha2"`
 }
 
 type PostListingPayload struct {
-	Destination      string  `json:"destination" validate:"required"`
+	ListingRoutePayload
+	Destination      string  `json:"destination"`
 	WeightAvailable  float64 `json:"weightAvailable" validate:"required"`
 	PricePerKg       float64 `json:"pricePerKg" validate:"required"`
 	Currency         string  `json:"currency" validate:"required"`
@@ -43,11 +86,13 @@ type DeleteListingPayload GetListingDetailPayload
 type UpdateBulkPackageLocationPayload struct {
 	ID              int    `json:"id" validate:"required"`
 	PackageLocation string `json:"packageLocation" validate:"required"`
+	Note            string `json:"note"`
 }
 
 type ModifyListingPayload struct {
+	ListingRoutePayload
 	ID               int     `json:"id" validate:"required"`
-	Destination      string  `json:"destination" validate:"required"`
+	Destination      string  `json:"destination"`
 	WeightAvailable  float64 `json:"weightAvailable" validate:"required"`
 	PricePerKg       float64 `json:"pricePerKg" validate:"required"`
 	Currency         string  `json:"currency" validate:"required"`
@@ -57,53 +102,116 @@ type ModifyListingPayload struct {
 }
 
 type ListingReturnPayload struct {
-	ID                    int              `json:"id"`
-	CarrierID             int              `json:"carrierId"`
-	CarrierName           string           `json:"carrierName"`
-	CarrierEmail          string           `json:"carrierEmail"`
-	CarrierProfilePicture []byte           `json:"carrierProfilePicture"`
-	Destination           string           `json:"destination"`
-	WeightAvailable       float64          `json:"weightAvailable"`
-	PricePerKg            float64          `json:"pricePerKg"`
-	Currency              string           `json:"currency"`
-	DepartureDate         time.Time        `json:"departureDate"`
-	LastReceivedDate      time.Time        `json:"lastReceivedDate"`
-	ExpStatus             string           `json:"expStatus"`
-	Description           string           `json:"description"`
-	CarrierRating         float64          `json:"carrierRating"`
-	LastModifiedAt        time.Time        `json:"lastModifiedAt"`
-	BankDetail            BankDetailReturn `json:"bankDetail"`
+	ID                       int                  `json:"id"`
+	CarrierID                int                  `json:"carrierId"`
+	CarrierName              string               `json:"carrierName"`
+	CarrierProfilePictureURL string               `json:"carrierProfilePictureUrl"`
+	Destination              string               `json:"destination"`
+	OriginCity               string               `json:"originCity"`
+	OriginCountryCode        string               `json:"originCountryCode"`
+	DestinationCity          string               `json:"destinationCity"`
+	DestinationCountryCode   string               `json:"destinationCountryCode"`
+	Stops                    []ListingStopPayload `json:"stops"`
+	WeightAvailable          float64              `json:"weightAvailable"`
+	PricePerKg               float64              `json:"pricePerKg"`
+	Currency                 string               `json:"currency"`
+	DepartureDate            time.Time            `json:"departureDate"`
+	LastReceivedDate         time.Time            `json:"lastReceivedDate"`
+	ExpStatus                string               `json:"expStatus"`
+	Description              string               `json:"description"`
+	CarrierRating            float64              `json:"carrierRating"`
+	LastModifiedAt           time.Time            `json:"lastModifiedAt"`
+}
+
+// what any user can see of the carrier, the contact details are only filled in
+// for the carrier and for givers with a confirmed order on the listing
+type CarrierProfileReturnPayload struct {
+	ID                int       `json:"id"`
+	Name              string    `json:"name"`
+	ProfilePictureURL string    `json:"profilePictureUrl"`
+	Rating            float64   `json:"rating"`
+	ReviewCount       int       `json:"reviewCount"`
+	MemberSince       time.Time `json:"memberSince"`
+	Email             string    `json:"email,omitempty"`
+	PhoneNumber       string    `json:"phoneNumber,omitempty"`
+}
+
+type ListingDetailReturnPayload struct {
+	ID                     int                         `json:"id"`
+	Destination            string                      `json:"destination"`
+	OriginCity             string                      `json:"originCity"`
+	OriginCountryCode      string                      `json:"originCountryCode"`
+	DestinationCity        string                      `json:"destinationCity"`
+	DestinationCountryCode string                      `json:"destinationCountryCode"`
+	Stops                  []ListingStopPayload        `json:"stops"`
+	WeightAvailable        float64                     `json:"weightAvailable"`
+	PricePerKg             float64                     `json:"pricePerKg"`
+	Currency               string                      `json:"currency"`
+	DepartureDate          time.Time                   `json:"departureDate"`
+	LastReceivedDate       time.Time                   `json:"lastReceivedDate"`
+	ExpStatus              string                      `json:"expStatus"`
+	Description            string                      `json:"description"`
+	OrderCount             int                         `json:"orderCount"`
+	LastModifiedAt         time.Time                   `json:"lastModifiedAt"`
+	Carrier                CarrierProfileReturnPayload `json:"carrier"`
+	ContactRevealed        bool                        `json:"contactRevealed"`
+	PayoutMethod           *PayoutMethodReturnPayload  `json:"payoutMethod,omitempty"`
+}
+
+type ListingsReturnPayload struct {
+	Total    int                    `json:"total"`
+	Page     int                    `json:"page"`
+	PageSize int                    `json:"pageSize"`
+	Listings []ListingReturnPayload `json:"listings"`
 }
 
 type ListingReturnFromDB struct {
-	ID               int            `json:"id"`
-	CarrierID        int            `json:"carrierId"`
-	CarrierName      string         `json:"carrierName"`
-	CarrierEmail     string         `json:"carrierEmail"`
-	Destination      string         `json:"destination"`
-	WeightAvailable  float64        `json:"weightAvailable"`
-	PricePerKg       float64        `json:"pricePerKg"`
-	Currency         string         `json:"currency"`
-	DepartureDate    time.Time      `json:"departureDate"`
-	LastReceivedDate time.Time      `json:"lastReceivedDate"`
-	ExpStatus        int            `json:"expStatus"`
-	Description      sql.NullString `json:"description"`
-	CarrierRating    float64        `json:"carrierRating"`
-	LastModifiedAt   time.Time      `json:"lastModifiedAt"`
+	ID                     int            `json:"id"`
+	CarrierID              int            `json:"carrierId"`
+	CarrierName            string         `json:"carrierName"`
+	CarrierEmail           string         `json:"carrierEmail"`
+	Destination            string         `json:"destination"`
+	OriginCity             string         `json:"originCity"`
+	OriginCountryCode      string         `json:"originCountryCode"`
+	DestinationCity        string         `json:"destinationCity"`
+	DestinationCountryCode string         `json:"destinationCountryCode"`
+	WeightAvailable        float64        `json:"weightAvailable"`
+	PricePerKg             float64        `json:"pricePerKg"`
+	Currency               string         `json:"currency"`
+	DepartureDate          time.Time      `json:"departureDate"`
+	LastReceivedDate       time.Time      `json:"lastReceivedDate"`
+	ExpStatus              int            `json:"expStatus"`
+	Description            sql.NullString `json:"description"`
+	CarrierRating          float64        `json:"carrierRating"`
+	LastModifiedAt         time.Time      `json:"lastModifiedAt"`
 }
 
 type Listing struct {
-	ID               int          `json:"id"`
-	CarrierID        int          `json:"carrierId"`
-	Destination      string       `json:"destination"`
-	WeightAvailable  float64      `json:"weightAvailable"`
-	PricePerKg       float64      `json:"pricePerKg"`
-	CurrencyID       int          `json:"currencyId"`
-	DepartureDate    time.Time    `json:"departureDate"`
-	LastReceivedDate time.Time    `json:"lastReceivedDate"`
-	ExpStatus        int          `json:"expStatus"`
-	Description      string       `json:"description"`
-	CreatedAt        time.Time    `json:"createdAt"`
-	LastModifiedAt   time.Time    `json:"lastModifiedAt"`
-	DeletedAt        sql.NullTime `json:"deletedAt"`
+	ID                     int           `json:"id"`
+	CarrierID              int           `json:"carrierId"`
+	Destination            string        `json:"destination"`
+	OriginCity             string        `json:"originCity"`
+	OriginCountryCode      string        `json:"originCountryCode"`
+	DestinationCity        string        `json:"destinationCity"`
+	DestinationCountryCode string        `json:"destinationCountryCode"`
+	Stops                  []ListingStop `json:"stops"`
+	WeightAvailable        float64       `json:"weightAvailable"`
+	PricePerKg             float64       `json:"pricePerKg"`
+	CurrencyID             int           `json:"currencyId"`
+	DepartureDate          time.Time     `json:"departureDate"`
+	LastReceivedDate       time.Time     `json:"lastReceivedDate"`
+	ExpStatus              int           `json:"expStatus"`
+	Description            string        `json:"description"`
+	CreatedAt              time.Time     `json:"createdAt"`
+	LastModifiedAt         time.Time     `json:"lastModifiedAt"`
+	DeletedAt              sql.NullTime  `json:"deletedAt"`
+}
+
+// a city the carrier passes through between the origin and the destination
+type ListingStop struct {
+	ID          int    `json:"id"`
+	ListingID   int    `json:"listingId"`
+	StopOrder   int    `json:"stopOrder"`
+	City        string `json:"city"`
+	CountryCode string `json:"countryCode"`
 }
@@ -0,0 +1,24 @@
+package types
+
+// Notifier delivers a message over one channel (email, push, ...)
+type Notifier interface {
+	Notify(message NotificationMessage) error
+}
+
+type NotificationMessage struct {
+	Channel  string `json:"channel"`
+	ToUserID int    `json:"toUserId"`
+
+	// the email address or the device token, depending on the channel.
+	// a push with ToUserID is sent to every device the user is logged in on
+	To string `json:"to"`
+
+	Title          string  `json:"title"`
+	Body           string  `json:"body"`
+	AttachmentURL  string  `json:"attachmentUrl"`
+	AttachmentName string  `json:"attachmentName"`
+	Data           FCMData `json:"data"`
+
+	// the inbox item a push belongs to, 0 if it has none
+	InboxID int `json:"inboxId"`
+}
@@ -23,27 +23,60 @@ type OrderStore interface {
 	UpdateOrderStatus(id int, orderStatus int, packageLocation string) error
 
 	IsOrderDuplicate(userId int, listingId int) (bool, error)
+	IsOrderConfirmed(giverId int, listingId int) (bool, error)
 	IsPaymentProofURLExist(string) bool
 	IsPackageImageURLExist(packageImgUrl string) bool
+	IsOrderImageVisibleTo(userId int, imageUrl string) (bool, error)
 
-	UpdateOrderStatusByDeadline() error
+	GetOrdersPastDeadline() ([]Order, error)
 
 	GetOrdersByListingID(listingId int) ([]OrderBulk, error)
 	GetOrderID(order Order) (int, error)
 
 	GetOrderCountByListingID(listingId int) (int, error)
+
+	GetOrderByIDForUpdate(tx *sql.Tx, id int) (*Order, error)
+	ModifyOrderTx(tx *sql.Tx, id int, order Order) error
+	UpdateOrderStatusTx(tx *sql.Tx, id int, orderStatus int, packageLocation string) error
+	UpdatePackageLocationTx(tx *sql.Tx, id int, packageLocation string) error
+	UpdatePaymentStatusTx(tx *sql.Tx, id int, paymentStatus int, paymentProofUrl string) error
+
+	CreateOrderTx(tx *sql.Tx, order Order) (int, error)
+	CreateOrderEventTx(tx *sql.Tx, event OrderEvent) error
+	GetOrderEventsByOrderID(orderId int) ([]OrderEvent, error)
+}
+
+// OrderUnitOfWork groups the order and listing updates that must be applied together
+type OrderUnitOfWork interface {
+	CreateOrder(order Order, actor int) (int, error)
+	TransitionOrder(orderId int, toStatus int, actor int, packageLocation string, note string) error
+
+	// the same inside the caller's transaction
+	CreateOrderTx(tx *sql.Tx, order Order, actor int, note string) (int, error)
+	TransitionOrderTx(tx *sql.Tx, orderId int, toStatus int, actor int, packageLocation string, note string) error
+
+	ModifyOrder(orderId int, actor int, order Order) error
+	UpdatePackageLocation(orderId int, actor int, packageLocation string, note string) error
+	UpdatePaymentStatus(orderId int, actor int, paymentStatus int, paymentProofUrl string) error
 }
 
 type RegisterOrderPayload struct {
 	ListingID      int     `json:"listingId" validate:"required"`
-	Weight         float64 `json:"weight" validate:"required"`
-	Price          float64 `json:"price" validate:"required"`
-	Currency       string  `json:"currency" validate:"required"`
+	Weight         float64 `json:"weight" validate:"required,gt=0"`
+	Price          float64 `json:"price"`
+	Currency       string  `json:"currency"`
+	Insurance      bool    `json:"insurance"`
 	PackageContent string  `json:"packageContent" validate:"required"`
 	PackageImage   []byte  `json:"packageImage" validate:"required"`
 	Notes          string  `json:"notes"`
 }
 
+type PriceQuotePayload struct {
+	ListingID int     `json:"listingId" validate:"required"`
+	Weight    float64 `json:"weight" validate:"required,gt=0"`
+	Insurance bool    `json:"insurance"`
+}
+
 type ViewOrderDetailPayload struct {
 	ID int `json:"id" validate:"required"`
 }
@@ -51,22 +84,22 @@ type ViewOrderDetailPayload struct {
 type DeleteOrderPayload ViewOrderDetailPayload
 
 type ModifyOrderPayload struct {
-	ID              int     `json:"id" validate:"required"`
-	ListingID       int     `json:"listingId" validate:"required"`
-	Weight          float64 `json:"weight" validate:"required"`
-	Price           float64 `json:"price" validate:"required"`
-	Currency        string  `json:"currency" validate:"required"`
-	PackageContent  string  `json:"packageContent" validate:"required"`
-	PackageImage    []byte  `json:"packageImage"`
-	PaymentStatus   string  `json:"paymentStatus" validate:"required"`
-	PackageLocation string  `json:"packageLocation" validate:"required"`
-	Notes           string  `json:"notes"`
+	ID             int     `json:"id" validate:"required"`
+	ListingID      int     `json:"listingId" validate:"required"`
+	Weight         float64 `json:"weight" validate:"required,gt=0"`
+	Price          float64 `json:"price"`
+	Currency       string  `json:"currency"`
+	Insurance      bool    `json:"insurance"`
+	PackageContent string  `json:"packageContent" validate:"required"`
+	PackageImage   []byte  `json:"packageImage"`
+	Notes          string  `json:"notes"`
 }
 
 type UpdatePackageLocationPayload struct {
 	ID              int    `json:"id" validate:"required"`
 	PackageLocation string `json:"packageLocation" validate:"required"`
 	OrderStatus     string `json:"orderStatus"`
+	Note            string `json:"note"`
 }
 
 type UpdatePaymentStatusPayload struct {
@@ -79,18 +112,15 @@ type UpdateOrderStatusPayload struct {
 	ID              int    `json:"id" validate:"required"`
 	OrderStatus     string `json:"orderStatus" validate:"required"`
 	PackageLocation string `json:"packageLocation"`
+	Note            string `json:"note"`
 }
 
 type GetPaymentProofImagePayload struct {
 	PaymentProofURL string `json:"paymentProofUrl" validate:"required"`
 }
 
-type ReturnPaymentProofImagePayload struct {
-	PaymentProof []byte `json:"paymentProof" validate:"required"`
-}
-
 type GetPaymentDetailsPayload struct {
-	CarrierID int `json:"carrierId" validate:"required"`
+	OrderID int `json:"orderId" validate:"required"`
 }
 
 type OrderGiverReturnFromDB struct {
@@ -124,7 +154,7 @@ type OrderGiverReturnPayload struct {
 	Price           float64   `json:"price"`
 	Currency        string    `json:"currency"`
 	PackageContent  string    `json:"packageContent"`
-	PackageImage    []byte    `json:"packageImage"`
+	PackageImageURL string    `json:"packageImageUrl"`
 	PaymentStatus   string    `json:"paymentStatus"`
 	PaidAt          time.Time `json:"paidAt"`
 	PaymentProofURL string    `json:"paymentProofUrl"`
@@ -184,7 +214,7 @@ type OrderCarrierReturnPayload struct {
 	Price            float64   `json:"price"`
 	Currency         string    `json:"currency"`
 	PackageContent   string    `json:"packageContent"`
-	PackageImage     []byte    `json:"packageImage"`
+	PackageImageURL  string    `json:"packageImageUrl"`
 	PaymentStatus    string    `json:"paymentStatus"`
 	PaidAt           time.Time `json:"paidAt"`
 	PaymentProofURL  string    `json:"paymentProofUrl"`
@@ -195,9 +225,25 @@ type OrderCarrierReturnPayload struct {
 	LastModifiedAt   time.Time `json:"lastModifiedAt"`
 }
 
+type PriceQuoteFee struct {
+	Name   string  `json:"name"`
+	Amount float64 `json:"amount"`
+}
+
+type PriceQuote struct {
+	ListingID  int             `json:"listingId"`
+	Weight     float64         `json:"weight"`
+	PricePerKg float64         `json:"pricePerKg"`
+	Currency   string          `json:"currency"`
+	BasePrice  float64         `json:"basePrice"`
+	Fees       []PriceQuoteFee `json:"fees"`
+	Total      float64         `json:"total"`
+}
+
 type OrderBulk struct {
-	ID         int    `json:"id"`
-	GiverEmail string `json:"giverEmail"`
+	ID          int    `json:"id"`
+	GiverEmail  string `json:"giverEmail"`
+	OrderStatus int    `json:"orderStatus"`
 }
 
 type Order struct {
@@ -220,3 +266,27 @@ type Order struct {
 	LastModifiedAt            time.Time    `json:"lastModifiedAt"`
 	DeletedAt                 sql.NullTime `json:"deletedAt"`
 }
+
+// one entry of the order timeline, holding the order state right after the event
+type OrderEvent struct {
+	ID              int       `json:"id"`
+	OrderID         int       `json:"orderId"`
+	EventType       int       `json:"eventType"`
+	Actor           int       `json:"actor"`
+	OrderStatus     int       `json:"orderStatus"`
+	PaymentStatus   int       `json:"paymentStatus"`
+	PackageLocation string    `json:"packageLocation"`
+	Note            string    `json:"note"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+type OrderEventReturnPayload struct {
+	ID              int       `json:"id"`
+	EventType       string    `json:"eventType"`
+	Actor           string    `json:"actor"`
+	OrderStatus     string    `json:"orderStatus"`
+	PaymentStatus   string    `json:"paymentStatus"`
+	PackageLocation string    `json:"packageLocation"`
+	Note            string    `json:"note"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
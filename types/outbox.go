@@ -0,0 +1,47 @@
+package types
+
+import (
+	"database/sql"
+	"time"
+)
+
+type OutboxStore interface {
+	CreateOutboxMessage(message NotificationMessage) error
+
+	ClaimDueOutboxMessages(limit int, lease time.Duration) ([]OutboxMessage, error)
+	MarkOutboxMessageSent(id int) error
+	MarkOutboxMessageFailed(id int, attempts int, lastError string, nextAttemptAt time.Time, dead bool) error
+
+	GetOutboxMessages(status int, limit int, offset int) ([]OutboxMessage, error)
+	GetOutboxMessageCount(status int) (int, error)
+	ReplayOutboxMessage(id int) (bool, error)
+}
+
+type OutboxMessage struct {
+	ID            int                 `json:"id"`
+	Message       NotificationMessage `json:"message"`
+	Status        int                 `json:"status"`
+	Attempts      int                 `json:"attempts"`
+	LastError     sql.NullString      `json:"lastError"`
+	NextAttemptAt time.Time           `json:"nextAttemptAt"`
+	SentAt        sql.NullTime        `json:"sentAt"`
+	CreatedAt     time.Time           `json:"createdAt"`
+}
+
+type OutboxMessageReturnPayload struct {
+	ID            int                 `json:"id"`
+	Message       NotificationMessage `json:"message"`
+	Status        string              `json:"status"`
+	Attempts      int                 `json:"attempts"`
+	LastError     string              `json:"lastError"`
+	NextAttemptAt time.Time           `json:"nextAttemptAt"`
+	SentAt        time.Time           `json:"sentAt"`
+	CreatedAt     time.Time           `json:"createdAt"`
+}
+
+type OutboxMessagesReturnPayload struct {
+	Total    int                          `json:"total"`
+	Page     int                          `json:"page"`
+	PageSize int                          `json:"pageSize"`
+	Messages []OutboxMessageReturnPayload `json:"messages"`
+}
@@ -0,0 +1,58 @@
+package types
+
+import "time"
+
+type PayoutMethodStore interface {
+	CreatePayoutMethod(payoutMethod PayoutMethod) error
+	ModifyPayoutMethod(id int, userId int, payoutMethod PayoutMethod) (bool, error)
+	SetDefaultPayoutMethod(id int, userId int) (bool, error)
+	DeletePayoutMethod(id int, userId int) (bool, error)
+	GetPayoutMethodsByUserID(userId int) ([]PayoutMethod, error)
+	GetPayoutMethodCountByUserID(userId int) (int, error)
+	GetPayoutMethodForCurrency(userId int, currency string) (*PayoutMethod, error)
+	RotateEncryptionKeys() (int, error)
+}
+
+// a bank account uses bankName, accountNumber and accountHolder,
+// an e-wallet provider and handle
+type PayoutMethodPayload struct {
+	MethodType    string `json:"methodType" validate:"required,oneof=bank-account e-wallet"`
+	Currency      string `json:"currency" validate:"required,max=10"`
+	BankName      string `json:"bankName" validate:"max=255"`
+	AccountNumber string `json:"accountNumber" validate:"max=64"`
+	AccountHolder string `json:"accountHolder" validate:"max=255"`
+	Provider      string `json:"provider" validate:"max=255"`
+	Handle        string `json:"handle" validate:"max=64"`
+	IsDefault     bool   `json:"isDefault"`
+}
+
+type PayoutMethodReturnPayload struct {
+	ID             int       `json:"id"`
+	MethodType     string    `json:"methodType"`
+	Currency       string    `json:"currency"`
+	BankName       string    `json:"bankName,omitempty"`
+	AccountNumber  string    `json:"accountNumber,omitempty"`
+	AccountHolder  string    `json:"accountHolder,omitempty"`
+	Provider       string    `json:"provider,omitempty"`
+	Handle         string    `json:"handle,omitempty"`
+	IsDefault      bool      `json:"isDefault"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
+
+// Provider is the bank name or the e-wallet provider and AccountNumber the
+// account number or the e-wallet handle, an e-wallet has no AccountHolder.
+// an empty Currency is a bank account from before the payout methods that
+// the carrier hasn't tagged yet
+type PayoutMethod struct {
+	ID             int       `json:"id"`
+	UserID         int       `json:"userId"`
+	MethodType     int       `json:"methodType"`
+	CurrencyID     int       `json:"currencyId"`
+	Currency       string    `json:"currency"`
+	Provider       string    `json:"provider"`
+	AccountNumber  string    `json:"accountNumber"`
+	AccountHolder  string    `json:"accountHolder"`
+	IsDefault      bool      `json:"isDefault"`
+	CreatedAt      time.Time `json:"createdAt"`
+	LastModifiedAt time.Time `json:"lastModifiedAt"`
+}
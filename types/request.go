@@ -0,0 +1,149 @@
+package types
+
+import (
+	"database/sql"
+	"time"
+)
+
+type DeliveryRequestStore interface {
+	CreateDeliveryRequest(request DeliveryRequest) (int, error)
+	GetDeliveryRequestByID(id int) (*DeliveryRequest, error)
+	GetDeliveryRequestsByGiverID(giverId int) ([]DeliveryRequest, error)
+	GetOpenDeliveryRequests(giverId int, filter DeliveryRequestFilter) ([]DeliveryRequest, error)
+	GetOpenDeliveryRequestCount(giverId int, filter DeliveryRequestFilter) (int, error)
+	IsPackageImageURLExist(packageImgUrl string) bool
+	IsPackageImageVisibleTo(userId int, packageImgUrl string) (bool, error)
+
+	CreateOffer(offer RequestOffer) (int, error)
+	GetOfferByID(id int) (*RequestOffer, error)
+	GetOffersByRequestID(requestId int) ([]RequestOffer, error)
+	IsOfferPending(requestId int, carrierId int) (bool, error)
+	WithdrawOffer(id int, carrierId int) (bool, error)
+
+	GetDeliveryRequestByIDForUpdate(tx *sql.Tx, id int) (*DeliveryRequest, error)
+	GetOfferByIDForUpdate(tx *sql.Tx, id int) (*RequestOffer, error)
+	AcceptOfferTx(tx *sql.Tx, requestId int, offerId int, orderId int) error
+	CancelDeliveryRequestTx(tx *sql.Tx, id int) error
+}
+
+// DeliveryRequestUnitOfWork turns an accepted offer into a listing and an order,
+// and closes a cancelled request together with its offers
+type DeliveryRequestUnitOfWork interface {
+	AcceptOffer(requestId int, offerId int, giverId int) (int, error)
+	CancelRequest(requestId int, giverId int) error
+}
+
+// narrows down the open requests, zero values are not filtered on
+type DeliveryRequestFilter struct {
+	OriginCity             string
+	OriginCountryCode      string
+	DestinationCity        string
+	DestinationCountryCode string
+	MaxWeight              float64
+	MatchingCarrierID      int // only requests one of the carrier's available listings can take
+	Limit                  int
+	Offset                 int
+}
+
+type PostDeliveryRequestPayload struct {
+	OriginCity             string  `json:"originCity" validate:"required,max=255"`
+	OriginCountryCode      string  `json:"originCountryCode" validate:"required,iso3166_1_alpha2"`
+	DestinationCity        string  `json:"destinationCity" validate:"required,max=255"`
+	DestinationCountryCode string  `json:"destinationCountryCode" validate:"required,iso3166_1_alpha2"`
+	Weight                 float64 `json:"weight" validate:"required,gt=0"`
+	Budget                 float64 `json:"budget" validate:"required,gt=0"`
+	Currency               string  `json:"currency" validate:"required"`
+	Deadline               string  `json:"deadline" validate:"required"`
+	PackageContent         string  `json:"packageContent" validate:"required,max=255"`
+	PackageImage           []byte  `json:"packageImage"`
+	Notes                  string  `json:"notes"`
+}
+
+type PostRequestOfferPayload struct {
+	Price            float64 `json:"price" validate:"required,gt=0"`
+	DepartureDate    string  `json:"departureDate" validate:"required"`
+	LastReceivedDate string  `json:"lastReceivedDate" validate:"required"`
+	Message          string  `json:"message"`
+}
+
+// a giver asking for a carrier to take the package from the origin to the destination
+// before the deadline, for at most the budget
+type DeliveryRequest struct {
+	ID                     int       `json:"id"`
+	GiverID                int       `json:"giverId"`
+	GiverName              string    `json:"giverName"`
+	OriginCity             string    `json:"originCity"`
+	OriginCountryCode      string    `json:"originCountryCode"`
+	DestinationCity        string    `json:"destinationCity"`
+	DestinationCountryCode string    `json:"destinationCountryCode"`
+	Weight                 float64   `json:"weight"`
+	Budget                 float64   `json:"budget"`
+	CurrencyID             int       `json:"currencyId"`
+	Currency               string    `json:"currency"`
+	Deadline               time.Time `json:"deadline"`
+	PackageContent         string    `json:"packageContent"`
+	PackageImageURL        string    `json:"packageImageUrl"`
+	Notes                  string    `json:"notes"`
+	Status                 int       `json:"status"`
+	OrderID                int       `json:"orderId"`
+	OfferCount             int       `json:"offerCount"`
+	CreatedAt              time.Time `json:"createdAt"`
+	LastModifiedAt         time.Time `json:"lastModifiedAt"`
+}
+
+// a carrier's price to take a delivery request
+type RequestOffer struct {
+	ID               int       `json:"id"`
+	RequestID        int       `json:"requestId"`
+	CarrierID        int       `json:"carrierId"`
+	CarrierName      string    `json:"carrierName"`
+	CarrierEmail     string    `json:"carrierEmail"`
+	Price            float64   `json:"price"`
+	DepartureDate    time.Time `json:"departureDate"`
+	LastReceivedDate time.Time `json:"lastReceivedDate"`
+	Message          string    `json:"message"`
+	Status           int       `json:"status"`
+	CreatedAt        time.Time `json:"createdAt"`
+	LastModifiedAt   time.Time `json:"lastModifiedAt"`
+}
+
+type DeliveryRequestReturnPayload struct {
+	ID                     int       `json:"id"`
+	GiverName              string    `json:"giverName"`
+	OriginCity             string    `json:"originCity"`
+	OriginCountryCode      string    `json:"originCountryCode"`
+	DestinationCity        string    `json:"destinationCity"`
+	DestinationCountryCode string    `json:"destinationCountryCode"`
+	Weight                 float64   `json:"weight"`
+	Budget                 float64   `json:"budget"`
+	Currency               string    `json:"currency"`
+	Deadline               time.Time `json:"deadline"`
+	PackageContent         string    `json:"packageContent"`
+	PackageImageURL        string    `json:"packageImageUrl"`
+	Notes                  string    `json:"notes"`
+	Status                 string    `json:"status"`
+	OrderID                int       `json:"orderId"`
+	OfferCount             int       `json:"offerCount"`
+	CreatedAt              time.Time `json:"createdAt"`
+}
+
+type DeliveryRequestsReturnPayload struct {
+	Total    int                            `json:"total"`
+	Page     int                            `json:"page"`
+	PageSize int                            `json:"pageSize"`
+	Requests []DeliveryRequestReturnPayload `json:"requests"`
+}
+
+type RequestOfferReturnPayload struct {
+	ID               int        `json:"id"`
+	RequestID        int        `json:"requestId"`
+	CarrierID        int        `json:"carrierId"`
+	CarrierName      string     `json:"carrierName"`
+	Price            float64    `json:"price"`
+	Quote            PriceQuote `json:"quote"`
+	DepartureDate    time.Time  `json:"departureDate"`
+	LastReceivedDate time.Time  `json:"lastReceivedDate"`
+	Message          string     `json:"message"`
+	Status           string     `json:"status"`
+	CreatedAt        time.Time  `json:"createdAt"`
+}
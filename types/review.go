@@ -19,6 +19,7 @@ type ReviewStore interface {
 	IsReviewDuplicate(reviewerId, revieweeId, orderId int) (bool, error)
 
 	GetAverageRating(userId int, reviewType int) (float64, error)
+	GetReviewCount(userId int, reviewType int) (int, error)
 }
 
 type RegisterReviewPayload struct {
@@ -0,0 +1,71 @@
+package types
+
+import (
+	"time"
+)
+
+type SavedSearchStore interface {
+	CreateSavedSearch(savedSearch SavedSearch) error
+	GetSavedSearchesByUserID(userId int) ([]SavedSearch, error)
+	GetSavedSearchCountByUserID(userId int) (int, error)
+	SetAlertsEnabled(id int, userId int, alertsEnabled bool) (bool, error)
+	DeleteSavedSearch(id int, userId int) (bool, error)
+	UnsubscribeByToken(token string) (bool, error)
+	IsUnsubscribeTokenExist(token string) bool
+
+	GetSavedSearchesMatchingListing(listingId int) ([]SavedSearch, error)
+	ClaimAlertSlot(userId int, interval time.Duration) (bool, error)
+	UpdateLastAlertedAt(id int) error
+}
+
+// SavedSearchAlerter tells the owners of the matching saved searches about a new listing
+type SavedSearchAlerter interface {
+	AlertNewListing(listingId int)
+}
+
+type SavedSearchPayload struct {
+	Name          string  `json:"name" validate:"required,max=255"`
+	Destination   string  `json:"destination" validate:"max=255"`
+	DepartureFrom string  `json:"departureFrom"`
+	DepartureTo   string  `json:"departureTo"`
+	MaxPricePerKg float64 `json:"maxPricePerKg" validate:"gte=0"`
+	Currency      string  `json:"currency" validate:"max=10"`
+	MinWeight     float64 `json:"minWeight" validate:"gte=0"`
+}
+
+type SavedSearchAlertsPayload struct {
+	AlertsEnabled *bool `json:"alertsEnabled" validate:"required"`
+}
+
+// listing criteria a user wants to be alerted about, zero values match everything
+type SavedSearch struct {
+	ID               int       `json:"id"`
+	UserID           int       `json:"userId"`
+	UserName         string    `json:"userName"`
+	UserEmail        string    `json:"userEmail"`
+	Name             string    `json:"name"`
+	Destination      string    `json:"destination"`
+	DepartureFrom    time.Time `json:"departureFrom"`
+	DepartureTo      time.Time `json:"departureTo"`
+	MaxPricePerKg    float64   `json:"maxPricePerKg"`
+	Currency         string    `json:"currency"`
+	MinWeight        float64   `json:"minWeight"`
+	AlertsEnabled    bool      `json:"alertsEnabled"`
+	UnsubscribeToken string    `json:"unsubscribeToken"`
+	LastAlertedAt    time.Time `json:"lastAlertedAt"`
+	CreatedAt        time.Time `json:"createdAt"`
+}
+
+type SavedSearchReturnPayload struct {
+	ID            int       `json:"id"`
+	Name          string    `json:"name"`
+	Destination   string    `json:"destination"`
+	DepartureFrom time.Time `json:"departureFrom"`
+	DepartureTo   time.Time `json:"departureTo"`
+	MaxPricePerKg float64   `json:"maxPricePerKg"`
+	Currency      string    `json:"currency"`
+	MinWeight     float64   `json:"minWeight"`
+	AlertsEnabled bool      `json:"alertsEnabled"`
+	LastAlertedAt time.Time `json:"lastAlertedAt"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
@@ -0,0 +1,73 @@
+package types
+
+import (
+	"database/sql"
+	"time"
+)
+
+type SessionStore interface {
+	CreateSession(userId int, deviceName string, fcmToken string) (int, error)
+	GetActiveSessionsByUserID(userId int) ([]Session, error)
+	GetActiveFCMTokensByUserID(userId int) ([]string, error)
+
+	UpdateSessionFCMToken(id int, fcmToken string) error
+
+	RevokeSession(id int, userId int) (bool, error)
+	RevokeOtherSessions(userId int, currentSessionId int) ([]int, error)
+
+	RotateSessionTokens(event SessionEvent, accessTokenDetails *TokenDetails, refreshTokenDetails *TokenDetails) (bool, error)
+	IsRotatedRefreshToken(sessionId int, tokenUuid string) (bool, error)
+
+	CreateSessionEvent(event SessionEvent) error
+	GetSessionEventsByUserID(userId int, limit int, offset int) ([]SessionEvent, error)
+	GetSessionEventCountByUserID(userId int) (int, error)
+}
+
+// one logged in device of a user
+type Session struct {
+	ID         int          `json:"id"`
+	UserID     int          `json:"userId"`
+	DeviceName string       `json:"deviceName"`
+	FCMToken   string       `json:"fcmToken"`
+	LastUsedAt time.Time    `json:"lastUsedAt"`
+	RevokedAt  sql.NullTime `json:"revokedAt"`
+	CreatedAt  time.Time    `json:"createdAt"`
+}
+
+type SessionReturnPayload struct {
+	ID         int       `json:"id"`
+	DeviceName string    `json:"deviceName"`
+	Current    bool      `json:"current"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// something that happened to a session, e.g. a refresh or a detected token reuse
+type SessionEvent struct {
+	ID         int       `json:"id"`
+	SessionID  int       `json:"sessionId"`
+	UserID     int       `json:"userId"`
+	EventType  int       `json:"eventType"`
+	TokenUUID  string    `json:"tokenUuid"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	DeviceName string    `json:"deviceName"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type SessionEventReturnPayload struct {
+	ID         int       `json:"id"`
+	SessionID  int       `json:"sessionId"`
+	DeviceName string    `json:"deviceName"`
+	EventType  string    `json:"eventType"`
+	IPAddress  string    `json:"ipAddress"`
+	UserAgent  string    `json:"userAgent"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+type SessionEventsReturnPayload struct {
+	Total    int                         `json:"total"`
+	Page     int                         `json:"page"`
+	PageSize int                         `json:"pageSize"`
+	Events   []SessionEventReturnPayload `json:"events"`
+}
@@ -7,6 +7,8 @@ type TokenDetails struct {
 }
 
 type AccessDetails struct {
-	UUID   string
-	UserID int
+	UUID      string
+	UserID    int
+	SessionID int
+	Role      int
 }
@@ -1,7 +1,7 @@
 package types
 
 import (
-	"net/http"
+	"database/sql"
 	"time"
 )
 
@@ -23,12 +23,10 @@ type UserStore interface {
 	UpdatePassword(int, string) error
 	UpdateProfilePicture(id int, profPicUrl string) error
 
-	SaveToken(userId int, accessTokenDetails *TokenDetails, refreshTokenDetails *TokenDetails) error
+	SaveToken(userId int, sessionId int, accessTokenDetails *TokenDetails, refreshTokenDetails *TokenDetails) error
 	DeleteToken(int) error
-	ValidateUserAccessToken(http.ResponseWriter, *http.Request) (*User, error)
-	ValidateUserRefreshToken(refreshToken string) (*User, error)
-	UpdateAccessToken(userId int, accessTokenDetails *TokenDetails) error
-	IsAccessTokenExist(userId int) (bool, error)
+	GetUserByAccessDetails(accessDetails *AccessDetails) (*User, error)
+	ValidateUserRefreshToken(refreshToken string) (*User, *AccessDetails, error)
 
 	DelayCodeWithinTime(email string, minutes int) (bool, error)
 	SaveVerificationCode(email, code string, requestType int) error
@@ -41,6 +39,14 @@ type UserStore interface {
 
 	UpdateFCMToken(id int, fcmToken string) error
 
+	UpdateUserRole(id int, role int) error
+	GetUserCountByRole(role int) (int, error)
+
+	GetUsers(limit int, offset int) ([]User, error)
+	GetUserCount() (int, error)
+	SuspendUser(id int) (bool, error)
+	ReactivateUser(id int) (bool, error)
+
 	IsDeleteUserAllowed(id int) (bool, error)
 }
 
@@ -59,6 +65,7 @@ type RegisterGooglePayload struct {
 	IDToken           string `json:"idToken" validate:"required"`
 	ServerAuthCode    string `json:"serverAuthCode" validate:"required"`
 	FCMToken          string `json:"fcmToken"`
+	DeviceName        string `json:"deviceName"`
 	Name              string `json:"name" validate:"required"`
 	PhoneNumber       string `json:"phoneNumber" validate:"required"`
 	ProfilePictureURL string `json:"profilePictureUrl"`
@@ -80,12 +87,14 @@ type LoginUserPayload struct {
 	Email      string `json:"email" validate:"required,email"`
 	Password   string `json:"password" validate:"required"`
 	FCMToken   string `json:"fcmToken"`
+	DeviceName string `json:"deviceName"`
 }
 
 type LoginGooglePayload struct {
 	IDToken        string `json:"idToken" validate:"required"`
 	ServerAuthCode string `json:"serverAuthCode" validate:"required"`
 	FCMToken       string `json:"fcmToken"`
+	DeviceName     string `json:"deviceName"`
 }	
 
 // request verification code payload
@@ -122,27 +131,30 @@ type AutoLoginPayload struct {
 }
 
 type ReturnUserPayload struct {
-	ID             int       `json:"id"`
-	Name           string    `json:"name"`
-	Email          string    `json:"email"`
-	PhoneNumber    string    `json:"phoneNumber"`
-	Provider       string    `json:"provider"`
-	ProfilePicture []byte    `json:"profilePicture"`
-	FCMToken       string    `json:"fcmToken"`
-	LastLoggedIn   time.Time `json:"lastLoggedIn"`
-	CreatedAt      time.Time `json:"createdAt"`
-}
-
-// basic user data info
-type User struct {
 	ID                int       `json:"id"`
 	Name              string    `json:"name"`
 	Email             string    `json:"email"`
-	Password          string    `json:"password"`
 	PhoneNumber       string    `json:"phoneNumber"`
 	Provider          string    `json:"provider"`
-	ProfilePictureURL string    `json:"profilePictureURL"`
-	FCMToken          string    `json:"fcmToken"` // Firebase Cloud Messaging for notification
+	ProfilePictureURL string    `json:"profilePictureUrl"`
+	FCMToken          string    `json:"fcmToken"`
+	Role              string    `json:"role"`
 	LastLoggedIn      time.Time `json:"lastLoggedIn"`
 	CreatedAt         time.Time `json:"createdAt"`
 }
+
+// basic user data info
+type User struct {
+	ID                int          `json:"id"`
+	Name              string       `json:"name"`
+	Email             string       `json:"email"`
+	Password          string       `json:"password"`
+	PhoneNumber       string       `json:"phoneNumber"`
+	Provider          string       `json:"provider"`
+	ProfilePictureURL string       `json:"profilePictureURL"`
+	FCMToken          string       `json:"fcmToken"` // Firebase Cloud Messaging for notification
+	Role              int          `json:"role"`
+	SuspendedAt       sql.NullTime `json:"suspendedAt"`
+	LastLoggedIn      time.Time    `json:"lastLoggedIn"`
+	CreatedAt         time.Time    `json:"createdAt"`
+}
@@ -1,14 +0,0 @@
-package utils
-
-import (
-	"os"
-)
-
-func GetImage(imageURL string) ([]byte, error) {
-	imageBytes, err := os.ReadFile(imageURL)
-	if err != nil {
-		return nil, err
-	}
-
-	return imageBytes, nil
-}
\ No newline at end of file
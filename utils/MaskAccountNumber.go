@@ -0,0 +1,14 @@
+package utils
+
+import "strings"
+
+// keeps the last 4 characters, a number that short is masked whole
+func MaskAccountNumber(accountNumber string) string {
+	characters := []rune(accountNumber)
+
+	if len(characters) <= 4 {
+		return strings.Repeat("*", len(characters))
+	}
+
+	return strings.Repeat("*", len(characters)-4) + string(characters[len(characters)-4:])
+}
@@ -81,7 +81,233 @@ func ExpStatusIntToString(expStatus int) string {
 		expStatusStr = constants.AVAILABLE_STATUS_STR
 	case constants.EXP_STATUS_EXPIRED:
 		expStatusStr = constants.EXPIRED_STATUS_STR
+	case constants.EXP_STATUS_RESERVED:
+		expStatusStr = constants.RESERVED_STATUS_STR
 	}
 
 	return expStatusStr
-}
\ No newline at end of file
+}
+
+func ActorIntToString(actor int) string {
+	var actorStr string
+	switch actor {
+	case constants.ACTOR_GIVER:
+		actorStr = constants.GIVER_ACTOR_STR
+	case constants.ACTOR_CARRIER:
+		actorStr = constants.CARRIER_ACTOR_STR
+	case constants.ACTOR_SYSTEM:
+		actorStr = constants.SYSTEM_ACTOR_STR
+	case constants.ACTOR_ADMIN:
+		actorStr = constants.ADMIN_ACTOR_STR
+	}
+
+	return actorStr
+}
+
+func OrderEventIntToString(eventType int) string {
+	var eventStr string
+	switch eventType {
+	case constants.ORDER_EVENT_STATUS_CHANGED:
+		eventStr = constants.STATUS_CHANGED_EVENT_STR
+	case constants.ORDER_EVENT_LOCATION_UPDATED:
+		eventStr = constants.LOCATION_UPDATED_EVENT_STR
+	case constants.ORDER_EVENT_PAYMENT_UPDATED:
+		eventStr = constants.PAYMENT_UPDATED_EVENT_STR
+	case constants.ORDER_EVENT_MODIFIED:
+		eventStr = constants.MODIFIED_EVENT_STR
+	case constants.ORDER_EVENT_CREATED:
+		eventStr = constants.CREATED_EVENT_STR
+	}
+
+	return eventStr
+}
+
+func OutboxStatusStringToInt(outboxStr string) int {
+	var outboxStatus int
+	switch outboxStr {
+	case constants.PENDING_STATUS_STR:
+		outboxStatus = constants.OUTBOX_STATUS_PENDING
+	case constants.SENT_STATUS_STR:
+		outboxStatus = constants.OUTBOX_STATUS_SENT
+	case constants.DEAD_STATUS_STR:
+		outboxStatus = constants.OUTBOX_STATUS_DEAD
+	default:
+		outboxStatus = -1
+	}
+
+	return outboxStatus
+}
+
+func OutboxStatusIntToString(outboxStatus int) string {
+	var outboxStr string
+	switch outboxStatus {
+	case constants.OUTBOX_STATUS_PENDING:
+		outboxStr = constants.PENDING_STATUS_STR
+	case constants.OUTBOX_STATUS_SENT:
+		outboxStr = constants.SENT_STATUS_STR
+	case constants.OUTBOX_STATUS_DEAD:
+		outboxStr = constants.DEAD_STATUS_STR
+	}
+
+	return outboxStr
+}
+
+func SessionEventIntToString(eventType int) string {
+	var eventStr string
+	switch eventType {
+	case constants.SESSION_EVENT_LOGIN:
+		eventStr = constants.LOGIN_EVENT_STR
+	case constants.SESSION_EVENT_REFRESHED:
+		eventStr = constants.REFRESHED_EVENT_STR
+	case constants.SESSION_EVENT_REUSE_DETECTED:
+		eventStr = constants.REUSE_DETECTED_EVENT_STR
+	case constants.SESSION_EVENT_REVOKED:
+		eventStr = constants.REVOKED_EVENT_STR
+	}
+
+	return eventStr
+}
+
+func RoleStringToInt(roleStr string) int {
+	var role int
+	switch roleStr {
+	case constants.USER_ROLE_STR:
+		role = constants.ROLE_USER
+	case constants.SUPPORT_ROLE_STR:
+		role = constants.ROLE_SUPPORT
+	case constants.ADMIN_ROLE_STR:
+		role = constants.ROLE_ADMIN
+	default:
+		role = -1
+	}
+
+	return role
+}
+
+func RoleIntToString(role int) string {
+	var roleStr string
+	switch role {
+	case constants.ROLE_USER:
+		roleStr = constants.USER_ROLE_STR
+	case constants.ROLE_SUPPORT:
+		roleStr = constants.SUPPORT_ROLE_STR
+	case constants.ROLE_ADMIN:
+		roleStr = constants.ADMIN_ROLE_STR
+	}
+
+	return roleStr
+}
+
+func AuditActionIntToString(action int) string {
+	var actionStr string
+	switch action {
+	case constants.AUDIT_ACTION_SUSPEND_USER:
+		actionStr = constants.SUSPEND_USER_ACTION_STR
+	case constants.AUDIT_ACTION_REACTIVATE_USER:
+		actionStr = constants.REACTIVATE_USER_ACTION_STR
+	case constants.AUDIT_ACTION_EXPIRE_LISTING:
+		actionStr = constants.EXPIRE_LISTING_ACTION_STR
+	case constants.AUDIT_ACTION_DELETE_LISTING:
+		actionStr = constants.DELETE_LISTING_ACTION_STR
+	case constants.AUDIT_ACTION_FORCE_ORDER_STATUS:
+		actionStr = constants.FORCE_ORDER_STATUS_ACTION_STR
+	case constants.AUDIT_ACTION_VIEW_PAYMENT_PROOF:
+		actionStr = constants.VIEW_PAYMENT_PROOF_ACTION_STR
+	case constants.AUDIT_ACTION_REPLAY_OUTBOX:
+		actionStr = constants.REPLAY_OUTBOX_ACTION_STR
+	}
+
+	return actionStr
+}
+
+func AuditTargetStringToInt(targetStr string) int {
+	var target int
+	switch targetStr {
+	case constants.USER_TARGET_STR:
+		target = constants.AUDIT_TARGET_USER
+	case constants.LISTING_TARGET_STR:
+		target = constants.AUDIT_TARGET_LISTING
+	case constants.ORDER_TARGET_STR:
+		target = constants.AUDIT_TARGET_ORDER
+	case constants.OUTBOX_TARGET_STR:
+		target = constants.AUDIT_TARGET_OUTBOX
+	default:
+		target = -1
+	}
+
+	return target
+}
+
+func AuditTargetIntToString(target int) string {
+	var targetStr string
+	switch target {
+	case constants.AUDIT_TARGET_USER:
+		targetStr = constants.USER_TARGET_STR
+	case constants.AUDIT_TARGET_LISTING:
+		targetStr = constants.LISTING_TARGET_STR
+	case constants.AUDIT_TARGET_ORDER:
+		targetStr = constants.ORDER_TARGET_STR
+	case constants.AUDIT_TARGET_OUTBOX:
+		targetStr = constants.OUTBOX_TARGET_STR
+	}
+
+	return targetStr
+}
+
+func RequestStatusIntToString(requestStatus int) string {
+	var requestStr string
+	switch requestStatus {
+	case constants.REQUEST_STATUS_OPEN:
+		requestStr = constants.OPEN_STATUS_STR
+	case constants.REQUEST_STATUS_ACCEPTED:
+		requestStr = constants.ACCEPTED_STATUS_STR
+	case constants.REQUEST_STATUS_CANCELLED:
+		requestStr = constants.CANCELLED_STATUS_STR
+	}
+
+	return requestStr
+}
+
+func OfferStatusIntToString(offerStatus int) string {
+	var offerStr string
+	switch offerStatus {
+	case constants.OFFER_STATUS_PENDING:
+		offerStr = constants.PENDING_STATUS_STR
+	case constants.OFFER_STATUS_ACCEPTED:
+		offerStr = constants.ACCEPTED_STATUS_STR
+	case constants.OFFER_STATUS_REJECTED:
+		offerStr = constants.REJECTED_STATUS_STR
+	case constants.OFFER_STATUS_WITHDRAWN:
+		offerStr = constants.WITHDRAWN_STATUS_STR
+	}
+
+	return offerStr
+}
+
+// to set the payout method type from string into int
+func PayoutMethodTypeStringToInt(methodTypeStr string) int {
+	var methodType int
+	switch methodTypeStr {
+	case constants.BANK_ACCOUNT_TYPE_STR:
+		methodType = constants.PAYOUT_METHOD_BANK_ACCOUNT
+	case constants.E_WALLET_TYPE_STR:
+		methodType = constants.PAYOUT_METHOD_E_WALLET
+	default:
+		methodType = -1
+	}
+
+	return methodType
+}
+
+// to get the payout method type string from int
+func PayoutMethodTypeIntToString(methodType int) string {
+	var methodTypeStr string
+	switch methodType {
+	case constants.PAYOUT_METHOD_BANK_ACCOUNT:
+		methodTypeStr = constants.BANK_ACCOUNT_TYPE_STR
+	case constants.PAYOUT_METHOD_E_WALLET:
+		methodTypeStr = constants.E_WALLET_TYPE_STR
+	}
+
+	return methodTypeStr
+}
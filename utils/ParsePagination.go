@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+)
+
+const defaultPageSize = 20
+const maxPageSize = 100
+
+// reads the page and pageSize query params, page starts from 1
+func ParsePagination(r *http.Request) (int, int, error) {
+	page := 1
+	pageSize := defaultPageSize
+
+	if pageStr := r.URL.Query().Get("page"); pageStr != "" {
+		p, err := strconv.Atoi(pageStr)
+		if err != nil || p < 1 {
+			return 0, 0, fmt.Errorf("invalid page")
+		}
+
+		page = p
+	}
+
+	if pageSizeStr := r.URL.Query().Get("pageSize"); pageSizeStr != "" {
+		p, err := strconv.Atoi(pageSizeStr)
+		if err != nil || p < 1 || p > maxPageSize {
+			return 0, 0, fmt.Errorf("invalid page size, must be between 1 and %d", maxPageSize)
+		}
+
+		pageSize = p
+	}
+
+	return page, pageSize, nil
+}
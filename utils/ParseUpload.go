@@ -0,0 +1,88 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// reads a request carrying one uploaded file, either as multipart/form-data or as
+// the old JSON body with the file base64 encoded in it.
+// in a multipart body the other fields are the JSON of the "payload" part and the
+// file is the part named fileField. the whole body is capped with http.MaxBytesReader
+// and the file is read into memory, up to maxFileBytes, since ProcessImage decodes
+// it from there anyway.
+// the file is returned for a multipart body, for a JSON one it is already in the payload
+func ParseUpload(w http.ResponseWriter, r *http.Request, payload any, fileField string, maxFileBytes int64) ([]byte, error) {
+	if r.Body == nil {
+		return nil, fmt.Errorf("missing request body")
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		// base64 takes 4 bytes for every 3
+		r.Body = http.MaxBytesReader(w, r.Body, (maxFileBytes+2)/3*4+constants.UPLOAD_FIELDS_MAX_BYTES)
+
+		err := ParseJSON(r, payload)
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return nil, types.ErrUploadTooLarge
+		}
+
+		return nil, err
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, maxFileBytes+constants.UPLOAD_FIELDS_MAX_BYTES)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+
+	var fileData []byte
+	hasPayload := false
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return nil, types.ErrUploadTooLarge
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		switch part.FormName() {
+		case "payload":
+			hasPayload = true
+			err = json.NewDecoder(io.LimitReader(part, constants.UPLOAD_FIELDS_MAX_BYTES)).Decode(payload)
+		case fileField:
+			// one byte more than allowed tells a file that is too big from one that just fits
+			fileData, err = io.ReadAll(io.LimitReader(part, maxFileBytes+1))
+			if err == nil && int64(len(fileData)) > maxFileBytes {
+				err = types.ErrUploadTooLarge
+			}
+		}
+		part.Close()
+
+		if errors.As(err, new(*http.MaxBytesError)) {
+			return nil, types.ErrUploadTooLarge
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !hasPayload {
+		return nil, fmt.Errorf("missing payload part")
+	}
+
+	return fileData, nil
+}
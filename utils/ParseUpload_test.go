@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+type uploadPayload struct {
+	Name  string `json:"name"`
+	Image []byte `json:"image"`
+}
+
+// a multipart body with the given parts, a nil value leaves the part out
+func multipartRequest(t *testing.T, payload []byte, file []byte) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+
+	if payload != nil {
+		if err := writer.WriteField("payload", string(payload)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if file != nil {
+		part, err := writer.CreateFormFile("image", "image.jpg")
+		if err != nil {
+			t.Fatal(err)
+		}
+		part.Write(file)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest(http.MethodPost, "/", &body)
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return r
+}
+
+func TestParseUpload(t *testing.T) {
+	const maxFileBytes = 16
+
+	fitting := bytes.Repeat([]byte{'a'}, maxFileBytes)
+	tooLarge := bytes.Repeat([]byte{'a'}, maxFileBytes+1)
+
+	jsonRequest := func(image []byte) *http.Request {
+		body := `{"name":"box","image":"` + base64.StdEncoding.EncodeToString(image) + `"}`
+		r := httptest.NewRequest(http.MethodPost, "/", bytes.NewBufferString(body))
+		r.Header.Set("Content-Type", "application/json")
+		return r
+	}
+
+	tests := []struct {
+		name      string
+		request   *http.Request
+		wantFile  []byte
+		wantImage []byte
+		wantName  string
+		wantErr   error
+		anyErr    bool
+	}{
+		{
+			name:     "multipart file at the limit",
+			request:  multipartRequest(t, []byte(`{"name":"box"}`), fitting),
+			wantFile: fitting,
+			wantName: "box",
+		},
+		{
+			name:    "multipart file one byte over the limit",
+			request: multipartRequest(t, []byte(`{"name":"box"}`), tooLarge),
+			wantErr: types.ErrUploadTooLarge,
+		},
+		{
+			name:     "multipart without a file",
+			request:  multipartRequest(t, []byte(`{"name":"box"}`), nil),
+			wantName: "box",
+		},
+		{
+			name:    "multipart without the payload part",
+			request: multipartRequest(t, nil, fitting),
+			anyErr:  true,
+		},
+		{
+			name:      "JSON fallback",
+			request:   jsonRequest(fitting),
+			wantImage: fitting,
+			wantName:  "box",
+		},
+		{
+			name:    "JSON body over the limit",
+			request: jsonRequest(bytes.Repeat([]byte{'a'}, 2<<20)),
+			wantErr: types.ErrUploadTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payload uploadPayload
+
+			file, err := ParseUpload(httptest.NewRecorder(), tt.request, &payload, "image", maxFileBytes)
+
+			if tt.wantErr != nil || tt.anyErr {
+				if err == nil || (tt.wantErr != nil && !errors.Is(err, tt.wantErr)) {
+					t.Fatalf("ParseUpload() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParseUpload() error = %v", err)
+			}
+
+			if !bytes.Equal(file, tt.wantFile) {
+				t.Errorf("file = %d bytes, want %d", len(file), len(tt.wantFile))
+			}
+			if !bytes.Equal(payload.Image, tt.wantImage) {
+				t.Errorf("payload image = %d bytes, want %d", len(payload.Image), len(tt.wantImage))
+			}
+			if payload.Name != tt.wantName {
+				t.Errorf("payload name = %q, want %q", payload.Name, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+// the account number or e-wallet handle is masked unless revealed
+func PayoutMethodPayload(payoutMethod types.PayoutMethod, revealed bool) types.PayoutMethodReturnPayload {
+	accountNumber := payoutMethod.AccountNumber
+	if !revealed {
+		accountNumber = MaskAccountNumber(accountNumber)
+	}
+
+	payload := types.PayoutMethodReturnPayload{
+		ID:             payoutMethod.ID,
+		MethodType:     PayoutMethodTypeIntToString(payoutMethod.MethodType),
+		Currency:       payoutMethod.Currency,
+		IsDefault:      payoutMethod.IsDefault,
+		LastModifiedAt: payoutMethod.LastModifiedAt,
+	}
+
+	if payoutMethod.MethodType == constants.PAYOUT_METHOD_E_WALLET {
+		payload.Provider = payoutMethod.Provider
+		payload.Handle = accountNumber
+	} else {
+		payload.BankName = payoutMethod.Provider
+		payload.AccountNumber = accountNumber
+		payload.AccountHolder = payoutMethod.AccountHolder
+	}
+
+	return payload
+}
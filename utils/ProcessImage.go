@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gen2brain/heic"
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+	"golang.org/x/image/draw"
+	"golang.org/x/image/webp"
+)
+
+// an upload after ProcessImage. the original and the variants share one format,
+// png stays png and everything else becomes jpeg
+type ProcessedImage struct {
+	Extension   string
+	ContentType string
+	Original    []byte
+	Variants    map[string][]byte
+}
+
+// the HEIC decoder runs on a single wasm instance that can't be called concurrently
+var heicMutex sync.Mutex
+
+var imageVariantDimensions = map[string]int{
+	constants.IMAGE_VARIANT_MEDIUM_STR:    constants.IMG_MEDIUM_DIMENSION,
+	constants.IMAGE_VARIANT_THUMBNAIL_STR: constants.IMG_THUMBNAIL_DIMENSION,
+}
+
+// decodes the upload and encodes it again, which drops the EXIF (GPS included) and
+// every other metadata. the orientation is applied to the pixels first, then the
+// original is scaled down to IMG_MAX_DIMENSION and the variants are made from it
+func ProcessImage(imageData []byte) (*ProcessedImage, error) {
+	format, err := detectImageFormat(imageData)
+	if err != nil {
+		return nil, err
+	}
+
+	config, err := decodeImageConfig(format, imageData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %v", err)
+	}
+
+	if config.Width*config.Height > constants.IMG_MAX_PIXELS {
+		return nil, fmt.Errorf("image dimensions are too large")
+	}
+
+	decoded, err := decodeImage(format, imageData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image: %v", err)
+	}
+
+	if format == "jpeg" {
+		decoded = applyOrientation(decoded, jpegOrientation(imageData))
+	}
+
+	encodePNG := (format == "png")
+
+	processed := &ProcessedImage{
+		Extension:   ".jpg",
+		ContentType: "image/jpeg",
+		Variants:    make(map[string][]byte),
+	}
+	if encodePNG {
+		processed.Extension = ".png"
+		processed.ContentType = "image/png"
+	}
+
+	original := fitImage(decoded, constants.IMG_MAX_DIMENSION)
+
+	processed.Original, err = encodeImage(original, encodePNG)
+	if err != nil {
+		return nil, err
+	}
+
+	// the smaller variant is scaled from the bigger one, it is much cheaper
+	medium := fitImage(original, imageVariantDimensions[constants.IMAGE_VARIANT_MEDIUM_STR])
+	thumbnail := fitImage(medium, imageVariantDimensions[constants.IMAGE_VARIANT_THUMBNAIL_STR])
+
+	processed.Variants[constants.IMAGE_VARIANT_MEDIUM_STR], err = encodeImage(medium, encodePNG)
+	if err != nil {
+		return nil, err
+	}
+
+	processed.Variants[constants.IMAGE_VARIANT_THUMBNAIL_STR], err = encodeImage(thumbnail, encodePNG)
+	if err != nil {
+		return nil, err
+	}
+
+	return processed, nil
+}
+
+func IsImageVariant(variant string) bool {
+	_, ok := imageVariantDimensions[variant]
+	return ok
+}
+
+// "package/abc.jpg" and "thumb" give "package/abc_thumb.jpg"
+func ImageVariantKey(imageKey string, variant string) string {
+	extension := path.Ext(imageKey)
+	return strings.TrimSuffix(imageKey, extension) + "_" + variant + extension
+}
+
+// the reverse of ImageVariantKey, a key that isn't a variant is returned as it is
+func ImageOriginalKey(imageKey string) string {
+	extension := path.Ext(imageKey)
+	base := strings.TrimSuffix(imageKey, extension)
+
+	for variant := range imageVariantDimensions {
+		if strings.HasSuffix(base, "_"+variant) {
+			return strings.TrimSuffix(base, "_"+variant) + extension
+		}
+	}
+
+	return imageKey
+}
+
+// jpeg, png and webp are sniffed by http.DetectContentType, HEIC is told by the brand of its ftyp box
+func detectImageFormat(imageData []byte) (string, error) {
+	switch http.DetectContentType(imageData) {
+	case "image/jpeg":
+		return "jpeg", nil
+	case "image/png":
+		return "png", nil
+	case "image/webp":
+		return "webp", nil
+	}
+
+	if len(imageData) >= 12 && string(imageData[4:8]) == "ftyp" {
+		switch string(imageData[8:12]) {
+		case "heic", "heix", "hevc", "hevx", "heim", "heis", "mif1", "msf1":
+			return "heic", nil
+		}
+	}
+
+	return "", types.ErrUnsupportedImage
+}
+
+func decodeImageConfig(format string, imageData []byte) (image.Config, error) {
+	reader := bytes.NewReader(imageData)
+
+	switch format {
+	case "jpeg":
+		return jpeg.DecodeConfig(reader)
+	case "png":
+		return png.DecodeConfig(reader)
+	case "webp":
+		return webp.DecodeConfig(reader)
+	case "heic":
+		heicMutex.Lock()
+		defer heicMutex.Unlock()
+
+		return heic.DecodeConfig(reader)
+	}
+
+	return image.Config{}, types.ErrUnsupportedImage
+}
+
+func decodeImage(format string, imageData []byte) (image.Image, error) {
+	reader := bytes.NewReader(imageData)
+
+	switch format {
+	case "jpeg":
+		return jpeg.Decode(reader)
+	case "png":
+		return png.Decode(reader)
+	case "webp":
+		return webp.Decode(reader)
+	case "heic":
+		heicMutex.Lock()
+		defer heicMutex.Unlock()
+
+		return heic.Decode(reader)
+	}
+
+	return nil, types.ErrUnsupportedImage
+}
+
+// jpeg has no alpha, transparent pixels end up white instead of black
+func encodeImage(img image.Image, encodePNG bool) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if encodePNG {
+		err := png.Encode(&buffer, img)
+		if err != nil {
+			return nil, err
+		}
+
+		return buffer.Bytes(), nil
+	}
+
+	opaque := image.NewRGBA(img.Bounds())
+	draw.Draw(opaque, opaque.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaque, opaque.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	err := jpeg.Encode(&buffer, opaque, &jpeg.Options{Quality: constants.IMG_JPEG_QUALITY})
+	if err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// scales the image down so its longer side is at most maxDimension, smaller images are kept as they are
+func fitImage(img image.Image, maxDimension int) image.Image {
+	width := img.Bounds().Dx()
+	height := img.Bounds().Dy()
+
+	if width <= maxDimension && height <= maxDimension {
+		return img
+	}
+
+	if width >= height {
+		height = max(1, height*maxDimension/width)
+		width = maxDimension
+	} else {
+		width = max(1, width*maxDimension/height)
+		height = maxDimension
+	}
+
+	scaled := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(scaled, scaled.Bounds(), img, img.Bounds(), draw.Src, nil)
+
+	return scaled
+}
+
+// the EXIF orientation tag (0x0112) of the APP1 segment, 1 (as stored) when there is none
+func jpegOrientation(imageData []byte) int {
+	offset := 2 // after the SOI marker
+
+	for offset+4 <= len(imageData) {
+		if imageData[offset] != 0xFF {
+			return 1
+		}
+
+		marker := imageData[offset+1]
+		length := int(binary.BigEndian.Uint16(imageData[offset+2 : offset+4]))
+
+		// the image data starts at SOS, there is no metadata after it
+		if marker == 0xDA || length < 2 || offset+2+length > len(imageData) {
+			return 1
+		}
+
+		segment := imageData[offset+4 : offset+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return exifOrientation(segment[6:])
+		}
+
+		offset += 2 + length
+	}
+
+	return 1
+}
+
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var byteOrder binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		byteOrder = binary.LittleEndian
+	case "MM":
+		byteOrder = binary.BigEndian
+	default:
+		return 1
+	}
+
+	ifdOffset := int(byteOrder.Uint32(tiff[4:8]))
+	if ifdOffset+2 > len(tiff) {
+		return 1
+	}
+
+	entryCount := int(byteOrder.Uint16(tiff[ifdOffset : ifdOffset+2]))
+
+	for i := 0; i < entryCount; i++ {
+		entry := ifdOffset + 2 + i*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+
+		if byteOrder.Uint16(tiff[entry:entry+2]) == 0x0112 {
+			orientation := int(byteOrder.Uint16(tiff[entry+8 : entry+10]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// turns the pixels so the image looks right without the orientation tag
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+
+	src := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Src)
+
+	dstWidth, dstHeight := width, height
+	if orientation >= 5 {
+		dstWidth, dstHeight = height, width
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, dstWidth, dstHeight))
+
+	for y := 0; y < dstHeight; y++ {
+		for x := 0; x < dstWidth; x++ {
+			var srcX, srcY int
+
+			switch orientation {
+			case 2: // mirrored
+				srcX, srcY = width-1-x, y
+			case 3: // rotated 180
+				srcX, srcY = width-1-x, height-1-y
+			case 4: // mirrored vertically
+				srcX, srcY = x, height-1-y
+			case 5: // transposed
+				srcX, srcY = y, x
+			case 6: // needs 90 clockwise
+				srcX, srcY = y, height-1-x
+			case 7: // transversed
+				srcX, srcY = width-1-y, height-1-x
+			case 8: // needs 90 counterclockwise
+				srcX, srcY = width-1-y, x
+			}
+
+			copy(dst.Pix[dst.PixOffset(x, y):dst.PixOffset(x, y)+4], src.Pix[src.PixOffset(srcX, srcY):src.PixOffset(srcX, srcY)+4])
+		}
+	}
+
+	return dst
+}
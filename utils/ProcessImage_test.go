@@ -0,0 +1,204 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+)
+
+var (
+	red  = color.RGBA{R: 255, A: 255}
+	blue = color.RGBA{B: 255, A: 255}
+)
+
+// a 32x16 jpeg, red on the left half and blue on the right
+func testJPEG(t *testing.T) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, 32, 16))
+	for y := 0; y < 16; y++ {
+		for x := 0; x < 32; x++ {
+			if x < 16 {
+				img.Set(x, y, red)
+			} else {
+				img.Set(x, y, blue)
+			}
+		}
+	}
+
+	var buffer bytes.Buffer
+	if err := jpeg.Encode(&buffer, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+// puts an APP1 segment with the orientation tag right after the SOI marker
+func withExifOrientation(jpegData []byte, orientation uint16) []byte {
+	var tiff bytes.Buffer
+	tiff.WriteString("MM\x00\x2a")
+	binary.Write(&tiff, binary.BigEndian, uint32(8)) // offset of the IFD
+	binary.Write(&tiff, binary.BigEndian, uint16(1)) // entry count
+	binary.Write(&tiff, binary.BigEndian, uint16(0x0112))
+	binary.Write(&tiff, binary.BigEndian, uint16(3)) // SHORT
+	binary.Write(&tiff, binary.BigEndian, uint32(1))
+	binary.Write(&tiff, binary.BigEndian, orientation)
+	binary.Write(&tiff, binary.BigEndian, uint16(0))
+	binary.Write(&tiff, binary.BigEndian, uint32(0)) // no next IFD
+
+	payload := append([]byte("Exif\x00\x00"), tiff.Bytes()...)
+
+	var out bytes.Buffer
+	out.Write(jpegData[:2])
+	out.Write([]byte{0xFF, 0xE1})
+	binary.Write(&out, binary.BigEndian, uint16(len(payload)+2))
+	out.Write(payload)
+	out.Write(jpegData[2:])
+
+	return out.Bytes()
+}
+
+// the markers of the segments before the image data
+func jpegMarkers(jpegData []byte) []byte {
+	markers := make([]byte, 0)
+
+	offset := 2
+	for offset+4 <= len(jpegData) && jpegData[offset] == 0xFF {
+		marker := jpegData[offset+1]
+		markers = append(markers, marker)
+
+		if marker == 0xDA {
+			break
+		}
+
+		offset += 2 + int(binary.BigEndian.Uint16(jpegData[offset+2:offset+4]))
+	}
+
+	return markers
+}
+
+func isRed(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return r > 0xC000 && g < 0x4000 && b < 0x4000
+}
+
+func isBlue(c color.Color) bool {
+	r, g, b, _ := c.RGBA()
+	return b > 0xC000 && r < 0x4000 && g < 0x4000
+}
+
+func TestProcessImageOrientation(t *testing.T) {
+	tests := []struct {
+		name        string
+		orientation uint16
+		wantWidth   int
+		wantHeight  int
+		// where the red half of the source ends up
+		redOnTop  bool
+		redOnLeft bool
+	}{
+		{name: "as stored", orientation: 1, wantWidth: 32, wantHeight: 16, redOnLeft: true},
+		{name: "90 clockwise", orientation: 6, wantWidth: 16, wantHeight: 32, redOnTop: true},
+		{name: "90 counterclockwise", orientation: 8, wantWidth: 16, wantHeight: 32, redOnTop: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processed, err := ProcessImage(withExifOrientation(testJPEG(t), tt.orientation))
+			if err != nil {
+				t.Fatalf("ProcessImage() error = %v", err)
+			}
+
+			img, err := jpeg.Decode(bytes.NewReader(processed.Original))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			bounds := img.Bounds()
+			if bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+				t.Fatalf("size = %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+
+			var first, second color.Color
+			if tt.wantWidth > tt.wantHeight {
+				first, second = img.At(4, 8), img.At(28, 8)
+			} else {
+				first, second = img.At(8, 4), img.At(8, 28)
+			}
+
+			wantRedFirst := tt.redOnLeft || tt.redOnTop
+			if wantRedFirst && (!isRed(first) || !isBlue(second)) {
+				t.Errorf("want red then blue, got %v then %v", first, second)
+			}
+			if !wantRedFirst && (!isBlue(first) || !isRed(second)) {
+				t.Errorf("want blue then red, got %v then %v", first, second)
+			}
+		})
+	}
+}
+
+func TestProcessImageStripsExif(t *testing.T) {
+	processed, err := ProcessImage(withExifOrientation(testJPEG(t), 6))
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+
+	outputs := map[string][]byte{"original": processed.Original}
+	for variant, data := range processed.Variants {
+		outputs[variant] = data
+	}
+
+	for name, data := range outputs {
+		if bytes.IndexByte(jpegMarkers(data), 0xE1) >= 0 {
+			t.Errorf("%s has an APP1 segment", name)
+		}
+
+		if bytes.Contains(data, []byte("Exif\x00\x00")) {
+			t.Errorf("%s still holds the EXIF header", name)
+		}
+	}
+}
+
+func TestProcessImageRejectsOversize(t *testing.T) {
+	jpegData := testJPEG(t)
+
+	// only the SOF0 header is changed, the size check runs before the pixels are decoded
+	sof := bytes.Index(jpegData, []byte{0xFF, 0xC0})
+	if sof < 0 {
+		t.Fatal("no SOF0 marker in the test jpeg")
+	}
+	binary.BigEndian.PutUint16(jpegData[sof+5:], 10000) // height
+	binary.BigEndian.PutUint16(jpegData[sof+7:], 10000) // width
+
+	if 10000*10000 <= constants.IMG_MAX_PIXELS {
+		t.Fatal("the test image is not over IMG_MAX_PIXELS")
+	}
+
+	_, err := ProcessImage(jpegData)
+	if err == nil || err.Error() != "image dimensions are too large" {
+		t.Errorf("ProcessImage() error = %v, want image dimensions are too large", err)
+	}
+}
+
+func TestProcessImageKeepsPNG(t *testing.T) {
+	var buffer bytes.Buffer
+	if err := png.Encode(&buffer, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatal(err)
+	}
+
+	processed, err := ProcessImage(buffer.Bytes())
+	if err != nil {
+		t.Fatalf("ProcessImage() error = %v", err)
+	}
+
+	if processed.Extension != ".png" || processed.ContentType != "image/png" {
+		t.Errorf("got %s (%s), want .png (image/png)", processed.Extension, processed.ContentType)
+	}
+}
@@ -4,105 +4,64 @@ import (
 	"fmt"
 	"io"
 	"net/http"
-	"os"
-	"path/filepath"
-	"regexp"
 
 	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
 )
 
-func SaveProfilePicture(id int, imageData []byte, fileExtension string) (string, error) {
-	if err := os.MkdirAll(constants.PROFILE_IMG_DIR_PATH, 0744); err != nil {
-		return "", err
-	}
-
+// stores the new profile picture under a fresh name, then removes the previous one
+func SaveProfilePicture(blobStore types.BlobStore, id int, oldKey string, processed *ProcessedImage) (string, error) {
 	// set the image file name
 	randomNumber := GenerateRandomCodeNumbers(12)
-	fileName := fmt.Sprintf("%s-%d%s", randomNumber, id, fileExtension)
-	imagePath := constants.PROFILE_IMG_DIR_PATH + fileName
-
-	pattern := fmt.Sprintf(`-%d.(jpg|png)$`, id)
-	re := regexp.MustCompile(pattern)
+	fileName := fmt.Sprintf("%s-%d%s", randomNumber, id, processed.Extension)
+	imageKey := constants.PROFILE_IMG_KEY_PREFIX + fileName
 
-	// Read all files in the specified folder
-	files, err := os.ReadDir(constants.PROFILE_IMG_DIR_PATH)
+	err := saveProcessedImage(blobStore, processed, imageKey)
 	if err != nil {
 		return "", err
 	}
 
-	for _, file := range files {
-		if !file.IsDir() {
-			if re.MatchString(file.Name()) {
-				// Full path of the file
-				fullPath := filepath.Join(constants.PROFILE_IMG_DIR_PATH, file.Name())
-
-				// Delete the previous profile picture
-				err := os.Remove(fullPath)
-				if err != nil {
-					return "", fmt.Errorf("error delete old profile picture: %v", err)
-				}
-
-				break
-			}
+	// the default picture is shared by every user
+	if oldKey != "" && oldKey != constants.DEFAULT_PROFILE_IMG_KEY {
+		err = DeleteImage(blobStore, oldKey)
+		if err != nil {
+			return "", fmt.Errorf("error delete old profile picture: %v", err)
 		}
 	}
 
-	// create the empty file for the image
-	file, err := os.Create(imagePath)
-	if err != nil {
-		return "", err
-	}
-	defer file.Close()
-
-	// save the image data
-	_, err = file.Write(imageData)
-	if err != nil {
-		return "", err
-	}
-
-	return imagePath, nil
+	return imageKey, nil
 }
 
-func SavePaymentProof(imageData []byte, filePath string) error {
-	if err := os.MkdirAll(constants.PAYMENT_PROOF_DIR_PATH, 0744); err != nil {
-		return err
-	}
-
-	// create the empty file for the image
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	// save the image data
-	_, err = file.Write(imageData)
-	if err != nil {
-		return err
-	}
+func SavePaymentProof(blobStore types.BlobStore, processed *ProcessedImage, imageKey string) error {
+	return saveProcessedImage(blobStore, processed, imageKey)
+}
 
-	return nil
+func SavePackageImage(blobStore types.BlobStore, processed *ProcessedImage, imageKey string) error {
+	return saveProcessedImage(blobStore, processed, imageKey)
 }
 
-func SavePackageImage(imageData []byte, filePath string) error {
-	if err := os.MkdirAll(constants.PACKAGE_IMG_DIR_PATH, 0744); err != nil {
-		return err
+// removes the image together with its variants
+func DeleteImage(blobStore types.BlobStore, imageKey string) error {
+	for variant := range imageVariantDimensions {
+		err := blobStore.Delete(ImageVariantKey(imageKey, variant))
+		if err != nil {
+			return err
+		}
 	}
 
-	// create the empty file for the image
-	file, err := os.Create(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	return blobStore.Delete(imageKey)
+}
 
-	// save the image data
-	_, err = file.Write(imageData)
-	if err != nil {
-		return err
+// the variants go first, so once the original is there the whole image is
+func saveProcessedImage(blobStore types.BlobStore, processed *ProcessedImage, imageKey string) error {
+	for variant, imageData := range processed.Variants {
+		err := blobStore.Put(ImageVariantKey(imageKey, variant), imageData, processed.ContentType)
+		if err != nil {
+			return err
+		}
 	}
 
-	return nil
+	return blobStore.Put(imageKey, processed.Original, processed.ContentType)
 }
 
 func DownloadImage(srcURL string) ([]byte, string, error) {
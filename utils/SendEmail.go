@@ -9,10 +9,8 @@ import (
 
 	"mime/multipart"
 	"net/http"
-	"net/smtp"
 	"strings"
 
-	"github.com/nicolaics/jim-carrier-server/config"
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 )
@@ -85,38 +83,6 @@ func (m *Message) AttachFile(src string, attachedFileName string) error {
 	return nil
 }
 
-func SendEmail(to, subject, body, attachmentUrl, attachedFileName string) error {
-	from := config.Envs.CompanyEmail
-	password := config.Envs.CompanyEmailPassword
-
-	// set gmail smtp server
-	smtpHost := "smtp.gmail.com"
-	smtpPort := "587"
-
-	body += "<br><br><p>Copyright 2024. Jim Carrier International.</p>"
-
-	// email message
-	message := &Message{
-		To:          []string{to},
-		Subject:     subject,
-		Body:        body,
-		Attachments: make(map[string][]byte),
-	}
-
-	if attachmentUrl != "" {
-		err := message.AttachFile(attachmentUrl, attachedFileName)
-		if err != nil {
-			return err
-		}
-	}
-
-	// set the gmail authentification
-	auth := smtp.PlainAuth("", from, password, smtpHost)
-
-	// send the email
-	return smtp.SendMail(smtpHost+":"+smtpPort, auth, from, []string{to}, message.ToBytes())
-}
-
 func CreateEmailBodyOfOrder(subject, name, destination, currency, notes, packageContent string, weight, price float64) string {
 	var printer = message.NewPrinter(language.English)
 	var body string
@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/nicolaics/jim-carrier-server/constants"
+	"github.com/nicolaics/jim-carrier-server/types"
+)
+
+var imageKeyPrefixes = map[string]string{
+	constants.IMAGE_KIND_PROFILE_STR:       constants.PROFILE_IMG_KEY_PREFIX,
+	constants.IMAGE_KIND_PACKAGE_STR:       constants.PACKAGE_IMG_KEY_PREFIX,
+	constants.IMAGE_KIND_PAYMENT_PROOF_STR: constants.PAYMENT_PROOF_KEY_PREFIX,
+}
+
+// turns a stored image key into the URL it is served from, an empty key stays empty
+func ImageURL(imageKey string) string {
+	if imageKey == "" {
+		return ""
+	}
+
+	for kind, keyPrefix := range imageKeyPrefixes {
+		if strings.HasPrefix(imageKey, keyPrefix) {
+			return fmt.Sprintf("/api/v1/image/%s/%s", kind, path.Base(imageKey))
+		}
+	}
+
+	return ""
+}
+
+// the reverse of ImageURL, the name can't leave the prefix of its kind
+func ImageKey(kind string, name string) (string, error) {
+	keyPrefix, ok := imageKeyPrefixes[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown image kind")
+	}
+
+	if name == "" || name != path.Base(name) || strings.HasPrefix(name, ".") || strings.Contains(name, "\\") {
+		return "", fmt.Errorf("invalid image name")
+	}
+
+	return keyPrefix + name, nil
+}
+
+// writes the image with an ETag from its size and modification time.
+// a matching If-None-Match is answered with 304 Not Modified before the blob is fetched
+func ServeImage(w http.ResponseWriter, r *http.Request, blobStore types.BlobStore, imageKey string) error {
+	info, err := blobStore.Stat(imageKey)
+	if err != nil {
+		return err
+	}
+
+	etag := fmt.Sprintf("\"%x-%x\"", info.ModifiedAt.UnixNano(), info.Size)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", constants.IMAGE_CACHE_CONTROL)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
+	reader, err := blobStore.Get(imageKey)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	imageData, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	if info.ContentType != "" {
+		w.Header().Set("Content-Type", info.ContentType)
+	}
+
+	http.ServeContent(w, r, path.Base(imageKey), info.ModifiedAt, bytes.NewReader(imageData))
+
+	return nil
+}
@@ -1,9 +1,12 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math/rand"
+	"net"
 	"net/http"
 	"strings"
 	"time"
@@ -49,6 +52,18 @@ func GenerateRandomCodeAlphanumeric(length int) string {
 	return string(result)
 }
 
+// for tokens that must not be guessable, e.g. the ones in email links
+func GenerateSecureToken(byteLength int) (string, error) {
+	b := make([]byte, byteLength)
+
+	_, err := crand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
 func GeneratePictureFileName(fileExtension string) string {
 	// set the image file name
 	rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -84,3 +99,17 @@ func WrapText(text string, width int) []string {
 
 	return lines
 }
+
+// the first forwarded address when behind a proxy, the remote address otherwise
+func ClientIP(r *http.Request) string {
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		return strings.TrimSpace(strings.Split(forwarded, ",")[0])
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return host
+}